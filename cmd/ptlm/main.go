@@ -8,6 +8,11 @@ import (
 )
 
 func main() {
+	// 文档输出到标准输出时，界面信息改为输出到标准错误
+	if cli.StdoutRequested(os.Args[1:]) {
+		ui.SetOutput(os.Stderr)
+	}
+
 	// 显示 Miku 横幅
 	ui.PrintBanner()
 
@@ -11,28 +11,119 @@ import (
 //go:embed default.yaml prompts.yaml
 var embeddedFS embed.FS
 
+//go:embed tokenizers
+var tokenizerFS embed.FS
+
 type Config struct {
 	LanguageMap       map[string]string `yaml:"language_map"`
+	// FilenameMap 按文件名（可含通配符）识别语言，优先于扩展名
+	FilenameMap       map[string]string `yaml:"filename_map"`
 	DefaultIgnore     []string          `yaml:"default_ignore"`
 	BinaryExtensions  []string          `yaml:"binary_extensions"`
 	NonCodeExtensions []string          `yaml:"non_code_extensions"`
+	RespectGitignore  bool              `yaml:"respect_gitignore"`
+	// Jobs 读取与压缩文件的并发数，0 表示 CPU 核心数
+	Jobs              int               `yaml:"jobs"`
+	// Cache 按文件内容缓存压缩结果与 token 数，缓存位于用户缓存目录
+	Cache             bool              `yaml:"cache"`
+	// MaxFileSize 超过该字节数的文件不读取，列入跳过的文件；-1 表示不限制
+	MaxFileSize       int64             `yaml:"max_file_size"`
+	// MaxFileLines 超过该行数的文件按 Truncate 截断，0 表示不限制
+	MaxFileLines      int               `yaml:"max_file_lines"`
+	// Truncate 截断方式: head / head_tail / skeleton
+	Truncate          string            `yaml:"truncate"`
+	// SourceEncoding 按路径指定文件编码，未匹配的文件自动检测
+	SourceEncoding    []EncodingRule    `yaml:"source_encoding"`
 	CustomIgnore      CustomIgnore      `yaml:"custom_ignore"`
+	Include           Include           `yaml:"include"`
 	Output            Output            `yaml:"output"`
+	Redact            Redact            `yaml:"redact"`
 	Prompts           Prompts           `yaml:"prompts"`
+	Serve             Serve             `yaml:"serve"`
+	Ask               Ask               `yaml:"ask"`
 }
 
 type CustomIgnore struct {
 	Patterns []string `yaml:"patterns"`
 	Regex    []string `yaml:"regex"`
+	// Paths 相对项目根目录的文件或目录，只排除这个路径本身，ptlm pick 保存的排除项写在这里
+	Paths []string `yaml:"paths"`
+}
+
+// EncodingRule 匹配 Pattern 的文件按 Encoding 读取，后出现的规则优先
+type EncodingRule struct {
+	// Pattern 与 include.patterns 的写法相同
+	Pattern  string `yaml:"pattern"`
+	// Encoding 例如 gbk、gb18030、big5、shift_jis、utf-16le、utf-16be、utf-8
+	Encoding string `yaml:"encoding"`
+}
+
+// Include 只整理匹配的路径，与忽略规则同时生效（忽略优先）
+type Include struct {
+	// Patterns 支持 * ? [...] 与 **；含 / 的模式相对项目根目录，否则匹配任意层级的名称；
+	// 匹配目录时包含其下全部文件，以 ! 开头的模式再次排除
+	Patterns []string `yaml:"patterns"`
+	// Tree 目录树中未包含的部分: prune 不显示，collapse 折叠为 …
+	Tree string `yaml:"tree"`
 }
 
 type Output struct {
 	MaxChars      int    `yaml:"max_chars"`
+	MaxTokens     int    `yaml:"max_tokens"`
+	Tokenizer     string `yaml:"tokenizer"`
+	TokenizerFile string `yaml:"tokenizer_file"`
 	Compress      bool   `yaml:"compress"`
 	UltraCompress bool   `yaml:"ultra_compress"`
 	SplitMode     string `yaml:"split_mode"`
 	IncludeTree   bool   `yaml:"include_tree"`
 	OutputPrefix  string `yaml:"output_prefix"`
+	Format        string `yaml:"format"`
+	PartDelimiter string `yaml:"part_delimiter"`
+	DiffMode      string `yaml:"diff_mode"`
+	// Skeleton 代码文件只保留声明与签名，SkeletonKeep 中匹配的文件保留全文
+	Skeleton     bool     `yaml:"skeleton"`
+	SkeletonKeep []string `yaml:"skeleton_keep"`
+	// ReportExcluded 在文档末尾附上被排除的路径及原因
+	ReportExcluded bool `yaml:"report_excluded"`
+	// LineNumbers 每行开头加上原文件中的行号，压缩、节选与拆分后仍对应原文件
+	LineNumbers bool `yaml:"line_numbers"`
+}
+
+// Redact 敏感信息遮盖
+type Redact struct {
+	Enabled bool `yaml:"enabled"`
+	// FailOnSecrets 发现敏感信息时以错误退出，不生成文档，用于 CI
+	FailOnSecrets bool `yaml:"fail_on_secrets"`
+	// AllowPaths 不检查的文件，规则与忽略模式相同
+	AllowPaths []string `yaml:"allow_paths"`
+	// AllowValues 允许出现的值（正则），例如测试用的假密钥
+	AllowValues []string `yaml:"allow_values"`
+}
+
+// Serve ptlm serve 的设置
+type Serve struct {
+	// Addr 监听地址，默认只监听本机
+	Addr string `yaml:"addr"`
+	// Roots 允许通过接口访问的目录，为空时只允许启动时的当前目录
+	Roots []string `yaml:"roots"`
+}
+
+// Ask ptlm ask 使用的对话接口，环境变量 PTLM_ASK_* 优先
+type Ask struct {
+	// Provider 接口风格: openai（兼容 /chat/completions）或 anthropic（/messages）
+	Provider string `yaml:"provider"`
+	// BaseURL 接口地址，为空时使用对应服务的官方地址
+	BaseURL string `yaml:"base_url"`
+	Model   string `yaml:"model"`
+	// APIKeyEnv 读取密钥的环境变量，为空时按接口风格使用 OPENAI_API_KEY 或 ANTHROPIC_API_KEY
+	APIKeyEnv string `yaml:"api_key_env"`
+	// MaxTokens 回复的最大 token 数
+	MaxTokens int    `yaml:"max_tokens"`
+	System    string `yaml:"system"`
+	// TranscriptPrefix 对话记录的文件名前缀
+	TranscriptPrefix string `yaml:"transcript_prefix"`
+	// ContextWindow 模型的上下文窗口(token)，发送前检查分段、问题与回复是否放得下；-1 表示不检查
+	ContextWindow int `yaml:"context_window"`
 }
 
 type Prompts struct {
@@ -40,6 +131,8 @@ type Prompts struct {
 	SectionTree         string `yaml:"section_tree"`
 	SectionCode         string `yaml:"section_code"`
 	SectionStats        string `yaml:"section_stats"`
+	SectionSkipped      string `yaml:"section_skipped"`
+	SectionExcluded     string `yaml:"section_excluded"`
 	HeaderPrompt        string `yaml:"header_prompt"`
 	CompressNotice      string `yaml:"compress_notice"`
 	UltraCompressNotice string `yaml:"ultra_compress_notice"`
@@ -56,13 +149,19 @@ type Prompts struct {
 func LoadEmbedded() (*Config, error) {
 	cfg := &Config{
 		LanguageMap:       make(map[string]string),
+		FilenameMap:       make(map[string]string),
 		DefaultIgnore:     []string{},
 		BinaryExtensions:  []string{},
 		NonCodeExtensions: []string{},
+		RespectGitignore:  true,
+		Cache:             true,
+		MaxFileSize:       10 * 1024 * 1024,
+		Truncate:          "head_tail",
 		CustomIgnore: CustomIgnore{
 			Patterns: []string{},
 			Regex:    []string{},
 		},
+		Include: Include{Tree: "prune"},
 		Output: Output{
 			MaxChars:      50000,
 			Compress:      true,
@@ -70,8 +169,13 @@ func LoadEmbedded() (*Config, error) {
 			SplitMode:     "char",
 			IncludeTree:   true,
 			OutputPrefix:  "LLM_CODE",
+			Format:        "markdown",
+			PartDelimiter: "\n",
 		},
+		Redact:  Redact{Enabled: true},
 		Prompts: Prompts{},
+		Serve:   Serve{Addr: "127.0.0.1:7878"},
+		Ask:     Ask{Provider: "openai", MaxTokens: 4096, TranscriptPrefix: "LLM_ASK", ContextWindow: 128000},
 	}
 
 	defaultData, err := embeddedFS.ReadFile("default.yaml")
@@ -124,4 +228,9 @@ func HasEmbedded() bool {
 
 func GetEmbeddedRaw(filename string) ([]byte, error) {
 	return embeddedFS.ReadFile(filename)
-}
\ No newline at end of file
+}
+
+// TokenizerTable 读取编译时内嵌的 tiktoken 词表
+func TokenizerTable(name string) ([]byte, error) {
+	return tokenizerFS.ReadFile("tokenizers/" + name + ".tiktoken")
+}
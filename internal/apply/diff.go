@@ -0,0 +1,199 @@
+package apply
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type diffOp struct {
+	kind opKind
+	a, b int // 在旧/新内容中的行下标
+}
+
+// diffLines 使用 Myers 算法计算逐行差异
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	// trace[d] 保存第 d 步开始前 k ∈ [-d, d] 的状态，用于回溯
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, d, n, m)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, d, n, m int) []diffOp {
+	var ops []diffOp
+	x, y := n, m
+
+	for ; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: opEqual, a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: opInsert, a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: opDelete, a: x, b: y})
+		}
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{kind: opEqual, a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// UnifiedDiff 生成 unified diff 文本，内容相同时返回空字符串
+func UnifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	ops := diffLines(a, b)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	var changed []int
+	for i, op := range ops {
+		if op.kind != opEqual {
+			changed = append(changed, i)
+		}
+	}
+
+	// 相邻改动之间的相同行不超过 2*context 时合并为一个 hunk
+	for ci := 0; ci < len(changed); {
+		cj := ci
+		for cj+1 < len(changed) && changed[cj+1]-changed[cj]-1 <= 2*context {
+			cj++
+		}
+
+		start := changed[ci] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changed[cj] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&builder, ops[start:end], a, b)
+		ci = cj + 1
+	}
+
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, ops []diffOp, a, b []string) {
+	oldStart, newStart := -1, -1
+	oldCount, newCount := 0, 0
+	var body strings.Builder
+
+	for _, op := range ops {
+		if oldStart < 0 {
+			oldStart, newStart = op.a, op.b
+		}
+		switch op.kind {
+		case opEqual:
+			body.WriteString(" " + a[op.a] + "\n")
+			oldCount++
+			newCount++
+		case opDelete:
+			body.WriteString("-" + a[op.a] + "\n")
+			oldCount++
+		case opInsert:
+			body.WriteString("+" + b[op.b] + "\n")
+			newCount++
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount)))
+	builder.WriteString(body.String())
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines 按行切分，末尾换行不产生额外的空行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffStat 统计新增与删除的行数
+func diffStat(oldText, newText string) (int, int) {
+	added, removed := 0, 0
+	for _, op := range diffLines(splitLines(oldText), splitLines(newText)) {
+		switch op.kind {
+		case opInsert:
+			added++
+		case opDelete:
+			removed++
+		}
+	}
+	return added, removed
+}
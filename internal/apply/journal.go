@@ -0,0 +1,270 @@
+package apply
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"printcode2llm/internal/unpack"
+)
+
+const journalFile = "journal.json"
+
+// Journal 一次 apply 的撤销记录，保存在用户缓存目录中，不会污染工作区
+type Journal struct {
+	Root    string         `json:"root"`
+	Time    time.Time      `json:"time"`
+	Source  string         `json:"source"`
+	Entries []JournalEntry `json:"entries"`
+
+	dir string
+}
+
+// JournalEntry 单个文件的撤销信息
+type JournalEntry struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Backup  string      `json:"backup,omitempty"`
+	Written string      `json:"written,omitempty"` // 写入内容的 sha256，撤销前用于检查文件是否被再次修改
+}
+
+// JournalDir root 对应的撤销记录目录
+func JournalDir(root string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(absRoot))
+	return filepath.Join(cacheDir, "ptlm", "apply", hex.EncodeToString(sum[:8])), nil
+}
+
+// Write 备份原文件并写入变更，任何一步失败都会回滚已写入的文件
+func Write(root, source string, changes []*FileChange) (*Journal, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	base, err := JournalDir(absRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{Root: absRoot, Time: time.Now(), Source: source}
+	journal.dir = filepath.Join(base, journal.Time.Format("20060102-150405.000000000"))
+	if err := os.MkdirAll(filepath.Join(journal.dir, "files"), 0755); err != nil {
+		return nil, fmt.Errorf("创建撤销记录失败: %w", err)
+	}
+
+	// 先完成全部备份并保存记录，再修改工作区
+	for i, change := range changes {
+		target, err := unpack.SafeJoin(absRoot, change.Path)
+		if err != nil {
+			os.RemoveAll(journal.dir)
+			return nil, err
+		}
+
+		entry := JournalEntry{Path: change.Path, Existed: change.Existed}
+		if !change.Delete {
+			entry.Written = hashContent([]byte(change.New))
+		}
+		if change.Existed {
+			info, err := os.Stat(target)
+			if err != nil {
+				os.RemoveAll(journal.dir)
+				return nil, err
+			}
+			entry.Mode = info.Mode().Perm()
+			entry.Backup = filepath.Join("files", fmt.Sprintf("%d", i))
+			if err := os.WriteFile(filepath.Join(journal.dir, entry.Backup), []byte(change.Old), 0600); err != nil {
+				os.RemoveAll(journal.dir)
+				return nil, fmt.Errorf("备份 %s 失败: %w", change.Path, err)
+			}
+		}
+		journal.Entries = append(journal.Entries, entry)
+	}
+	if err := journal.save(); err != nil {
+		os.RemoveAll(journal.dir)
+		return nil, err
+	}
+
+	for i, change := range changes {
+		if err := writeChange(absRoot, change, journal.Entries[i].Mode); err != nil {
+			if _, rollbackErr := journal.restore(i+1, true); rollbackErr != nil {
+				return nil, fmt.Errorf("写入 %s 失败: %v；回滚失败: %v", change.Path, err, rollbackErr)
+			}
+			os.RemoveAll(journal.dir)
+			return nil, fmt.Errorf("写入 %s 失败，已回滚: %w", change.Path, err)
+		}
+	}
+
+	return journal, nil
+}
+
+func writeChange(root string, change *FileChange, mode os.FileMode) error {
+	target, err := unpack.SafeJoin(root, change.Path)
+	if err != nil {
+		return err
+	}
+
+	if change.Delete {
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// 父目录此时已存在，再次解析其中的符号链接，确认写入位置仍在 root 内
+	if _, err := unpack.SafeJoin(root, change.Path); err != nil {
+		return err
+	}
+	return os.WriteFile(target, []byte(change.New), mode)
+}
+
+// Latest 读取 root 最近一次 apply 的撤销记录，没有记录时返回 nil
+func Latest(root string) (*Journal, error) {
+	base, err := JournalDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	dir := filepath.Join(base, names[len(names)-1])
+	data, err := os.ReadFile(filepath.Join(dir, journalFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取撤销记录失败: %w", err)
+	}
+	journal := &Journal{dir: dir}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("解析撤销记录失败: %w", err)
+	}
+	return journal, nil
+}
+
+// Undo 恢复记录中的全部文件并删除记录；force 为 false 时，
+// 若文件在 apply 之后又被修改过则拒绝撤销，返回被修改的文件
+func (j *Journal) Undo(force bool) ([]string, error) {
+	modified, err := j.restore(len(j.Entries), force)
+	if err != nil || len(modified) > 0 {
+		return modified, err
+	}
+	return nil, os.RemoveAll(j.dir)
+}
+
+// restore 恢复前 n 个文件
+func (j *Journal) restore(n int, force bool) ([]string, error) {
+	if !force {
+		var modified []string
+		for _, entry := range j.Entries[:n] {
+			target, err := unpack.SafeJoin(j.Root, entry.Path)
+			if err != nil {
+				return nil, err
+			}
+			data, err := os.ReadFile(target)
+			switch {
+			case os.IsNotExist(err):
+				if entry.Written != "" {
+					modified = append(modified, entry.Path)
+				}
+			case err != nil:
+				return nil, err
+			case hashContent(data) != entry.Written:
+				modified = append(modified, entry.Path)
+			}
+		}
+		if len(modified) > 0 {
+			return modified, nil
+		}
+	}
+
+	for _, entry := range j.Entries[:n] {
+		target, err := unpack.SafeJoin(j.Root, entry.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !entry.Existed {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			removeEmptyParents(j.Root, filepath.Dir(target))
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(j.dir, entry.Backup))
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 的备份失败: %w", entry.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, entry.Mode); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// removeEmptyParents 删除 apply 时为新文件创建、现已为空的目录
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Dir 撤销记录所在目录
+func (j *Journal) Dir() string {
+	return j.dir
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(j.dir, journalFile), data, 0600)
+}
+
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// isolateCache 让撤销记录写入临时目录
+func isolateCache(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func writeTestChanges(t *testing.T) (string, *Journal) {
+	t.Helper()
+	isolateCache(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "old\n", "b.go": "b\n"})
+
+	changes, err := Plan(root, []*Edit{
+		{Path: "a.go", Content: "new", Source: "reply.md:1"},
+		{Path: "dir/c.go", Content: "c", Source: "reply.md:2"},
+		{Path: "b.go", Patch: &FilePatch{OldPath: "b.go", NewPath: devNull}, Source: "reply.md:3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal, err := Write(root, "reply.md", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, journal
+}
+
+func TestWriteAndUndo(t *testing.T) {
+	root, _ := writeTestChanges(t)
+
+	if got := readFile(t, filepath.Join(root, "a.go")); got != "new\n" {
+		t.Errorf("a.go = %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, "dir", "c.go")); got != "c\n" {
+		t.Errorf("dir/c.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.go")); !os.IsNotExist(err) {
+		t.Errorf("b.go 应被删除: %v", err)
+	}
+
+	journal, err := Latest(root)
+	if err != nil || journal == nil {
+		t.Fatalf("Latest = %v, %v", journal, err)
+	}
+	modified, err := journal.Undo(false)
+	if err != nil || len(modified) != 0 {
+		t.Fatalf("Undo = %v, %v", modified, err)
+	}
+
+	if got := readFile(t, filepath.Join(root, "a.go")); got != "old\n" {
+		t.Errorf("撤销后 a.go = %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, "b.go")); got != "b\n" {
+		t.Errorf("撤销后 b.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir")); !os.IsNotExist(err) {
+		t.Errorf("为新文件创建的目录应被删除: %v", err)
+	}
+	if journal, err := Latest(root); err != nil || journal != nil {
+		t.Errorf("撤销后记录应被删除: %v, %v", journal, err)
+	}
+}
+
+func TestUndoRefusesModifiedFiles(t *testing.T) {
+	root, journal := writeTestChanges(t)
+	writeFiles(t, root, map[string]string{"a.go": "edited by hand\n"})
+
+	modified, err := journal.Undo(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified) != 1 || modified[0] != "a.go" {
+		t.Fatalf("modified = %v", modified)
+	}
+	// 拒绝时不恢复任何文件，记录保留
+	if got := readFile(t, filepath.Join(root, "a.go")); got != "edited by hand\n" {
+		t.Errorf("a.go 被改动: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir", "c.go")); err != nil {
+		t.Errorf("dir/c.go 被改动: %v", err)
+	}
+	if latest, err := Latest(root); err != nil || latest == nil {
+		t.Fatalf("记录应保留: %v, %v", latest, err)
+	}
+
+	if modified, err := journal.Undo(true); err != nil || len(modified) != 0 {
+		t.Fatalf("force 时 Undo = %v, %v", modified, err)
+	}
+	if got := readFile(t, filepath.Join(root, "a.go")); got != "old\n" {
+		t.Errorf("force 撤销后 a.go = %q", got)
+	}
+}
+
+func TestWriteRejectsSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要创建符号链接的权限")
+	}
+	isolateCache(t)
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []*FileChange{{Path: "link/evil.go", New: "x\n"}}
+	if _, err := Write(root, "reply.md", changes); err == nil {
+		t.Fatal("应拒绝经符号链接写到目标目录之外")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil.go")); !os.IsNotExist(err) {
+		t.Errorf("目标目录之外出现了文件: %v", err)
+	}
+	if journal, err := Latest(root); err != nil || journal != nil {
+		t.Errorf("失败时不应留下撤销记录: %v, %v", journal, err)
+	}
+}
@@ -0,0 +1,302 @@
+package apply
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/unpack"
+)
+
+// Edit 回复中针对单个文件的一处修改，Content 与 Patch 只会设置其中一个
+type Edit struct {
+	Path    string
+	Content string
+	Patch   *FilePatch
+	Source  string
+}
+
+// Reply 模型回复的解析结果
+type Reply struct {
+	Edits []*Edit
+	// Skipped 无法确定目标文件而被忽略的代码块位置
+	Skipped []string
+}
+
+var (
+	headingPattern    = regexp.MustCompile(`^#{1,6}\s+(?:\d+\.\s+)?(.+?)\s*#*$`)
+	labelPattern      = regexp.MustCompile(`^(?i:文件|路径|file|path|filename)\s*[:：]\s*(.+)$`)
+	listPrefix        = regexp.MustCompile(`^(?:[-*+]\s+|\d+\.\s+)`)
+	inlinePathPattern = regexp.MustCompile("`([^`\\s]+)`")
+	commentPathLines  = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(?://|#|--|;)\s*(?:(?i:file|path|filename|文件|路径)\s*[:：]\s*)?(\S+)\s*$`),
+		regexp.MustCompile(`^\s*/\*\s*(?:(?i:file|path|filename|文件|路径)\s*[:：]\s*)?(\S+)\s*\*/\s*$`),
+		regexp.MustCompile(`^\s*<!--\s*(?:(?i:file|path|filename|文件|路径)\s*[:：]\s*)?(\S+)\s*-->\s*$`),
+	}
+)
+
+// ParseFile 解析保存在文件中的模型回复，path 为 "-" 时读取标准输入
+func ParseFile(path string) (*Reply, error) {
+	if path == "-" {
+		return Parse(os.Stdin, "stdin")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reply, err := Parse(file, path)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return reply, nil
+}
+
+// Parse 解析模型回复中的代码块
+//
+// 目标路径依次从以下位置确定：
+//   - 代码块前的生成器标题 "### N. path"，带行号范围的片段会按行号拼接
+//   - 代码块前的 Markdown 标题、**path**、`path` 或 "文件: path"
+//   - 代码块语言后的路径，例如 ```go main.go
+//   - 代码块第一行的路径注释，例如 // main.go，该行不会写入文件
+//
+// 语言为 diff/patch 或内容以 diff 头部开始的代码块按 unified diff 处理
+func Parse(r io.Reader, source string) (*Reply, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, strings.TrimRight(s.Text(), "\r"))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	reply := &Reply{}
+	// 生成器格式的分段片段，按路径收集后拼接
+	ranged := make(map[string][]*unpack.Chunk)
+	placeholders := make(map[string]*Edit)
+	var rangedOrder []string
+
+	label := ""
+	for i := 0; i < len(lines); i++ {
+		size, lang, ok := generator.ParseFenceOpen(lines[i])
+		if !ok {
+			// 片段标题之后的说明行不覆盖标题
+			if _, isTitle := generator.ParseChunkTitle(label); isTitle && generator.IsChunkNote(lines[i]) {
+				continue
+			}
+			if strings.TrimSpace(lines[i]) != "" {
+				label = lines[i]
+			}
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !generator.IsFenceClose(lines[end], size) {
+			end++
+		}
+		if end >= len(lines) {
+			return nil, fmt.Errorf("第 %d 行的代码块没有结束", i+1)
+		}
+
+		body := lines[i+1 : end]
+		where := fmt.Sprintf("%s:%d", source, i+1)
+		prev := label
+		label = ""
+		i = end
+
+		title, isTitle := generator.ParseChunkTitle(prev)
+		langName, langPath := splitInfo(lang)
+
+		if isDiffBlock(langName, body) || (isTitle && title.Diff) {
+			patches, err := parsePatches(body)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", where, err)
+			}
+			fallback := langPath
+			if isTitle {
+				fallback = title.Path
+			} else if fallback == "" {
+				fallback = pathFromLabel(prev)
+			}
+			for _, patch := range patches {
+				if patch.NewPath == "" && patch.OldPath == "" {
+					if fallback == "" {
+						return nil, fmt.Errorf("%s: diff 缺少文件路径", where)
+					}
+					patch.OldPath, patch.NewPath = fallback, fallback
+				}
+				reply.Edits = append(reply.Edits, &Edit{Path: patch.Path(), Patch: patch, Source: where})
+			}
+			continue
+		}
+
+		// 照抄生成器格式的回复可能带有行号
+		if isTitle {
+			body, _ = generator.StripLineNumbers(body)
+		}
+
+		if isTitle && title.StartLine > 0 {
+			if _, seen := ranged[title.Path]; !seen {
+				rangedOrder = append(rangedOrder, title.Path)
+				// 占位，保证拼接后的文件出现在原来的位置
+				placeholders[title.Path] = &Edit{Path: title.Path, Source: where}
+				reply.Edits = append(reply.Edits, placeholders[title.Path])
+			}
+			ranged[title.Path] = append(ranged[title.Path], &unpack.Chunk{
+				Title:  title,
+				Lines:  append([]string{}, body...),
+				Source: where,
+			})
+			continue
+		}
+
+		path := ""
+		switch {
+		case isTitle:
+			path = title.Path
+		case pathFromLabel(prev) != "":
+			path = pathFromLabel(prev)
+		case langPath != "":
+			path = langPath
+		}
+
+		if len(body) > 0 {
+			if commentPath := pathFromComment(body[0]); commentPath != "" && (path == "" || commentPath == path) {
+				path = commentPath
+				body = body[1:]
+			}
+		}
+
+		if path == "" {
+			reply.Skipped = append(reply.Skipped, where)
+			continue
+		}
+
+		reply.Edits = append(reply.Edits, &Edit{Path: path, Content: strings.Join(body, "\n"), Source: where})
+	}
+
+	for _, path := range rangedOrder {
+		content, err := unpack.Stitch(ranged[path])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		placeholders[path].Content = content
+	}
+
+	return reply, nil
+}
+
+// splitInfo 拆分代码块的信息串，支持 "go main.go" 与 "go:main.go"
+func splitInfo(info string) (string, string) {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	if len(fields) == 1 {
+		if idx := strings.Index(fields[0], ":"); idx > 0 && looksLikePath(fields[0][idx+1:]) {
+			return fields[0][:idx], fields[0][idx+1:]
+		}
+		return fields[0], ""
+	}
+	if looksLikePath(fields[1]) {
+		return fields[0], fields[1]
+	}
+	return fields[0], ""
+}
+
+func isDiffBlock(lang string, body []string) bool {
+	switch strings.ToLower(lang) {
+	case "diff", "patch", "udiff":
+		return true
+	}
+
+	for i, line := range body {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			return true
+		}
+		return strings.HasPrefix(line, "--- ") && i+1 < len(body) && strings.HasPrefix(body[i+1], "+++ ")
+	}
+	return false
+}
+
+// pathFromLabel 从代码块前一行的标题或说明中提取路径
+func pathFromLabel(line string) string {
+	text := strings.TrimSpace(line)
+	if text == "" {
+		return ""
+	}
+
+	if m := headingPattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	text = listPrefix.ReplaceAllString(text, "")
+	text = strings.TrimSuffix(strings.TrimSuffix(text, ":"), "：")
+	if m := labelPattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	text = strings.TrimSpace(text)
+
+	for _, wrap := range []string{"**", "__", "`"} {
+		if strings.HasPrefix(text, wrap) && strings.HasSuffix(text, wrap) && len(text) > 2*len(wrap) {
+			text = text[len(wrap) : len(text)-len(wrap)]
+		}
+	}
+	text = strings.Trim(strings.TrimSpace(text), "`")
+
+	if looksLikePath(text) {
+		return text
+	}
+
+	// 句子中用反引号标出的路径，例如 "修改 `main.go`:"
+	matches := inlinePathPattern.FindAllStringSubmatch(line, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		if looksLikePath(matches[i][1]) {
+			return matches[i][1]
+		}
+	}
+	return ""
+}
+
+func pathFromComment(line string) string {
+	for _, pattern := range commentPathLines {
+		if m := pattern.FindStringSubmatch(line); m != nil && looksLikePath(m[1]) {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// looksLikePath 粗略判断文本是否是相对文件路径，避免把普通标题当成路径
+func looksLikePath(text string) bool {
+	if text == "" || len(text) > 255 || strings.ContainsAny(text, " \t<>|\"*?") {
+		return false
+	}
+	if strings.Contains(text, "://") {
+		return false
+	}
+	base := text[strings.LastIndex(text, "/")+1:]
+	if base == "" {
+		return false
+	}
+	if strings.Contains(base, ".") && !strings.HasSuffix(base, ".") {
+		return true
+	}
+	// 无扩展名的常见文件名
+	switch base {
+	case "Makefile", "Dockerfile", "LICENSE", "Gemfile", "Rakefile", "Procfile", "Jenkinsfile", "Vagrantfile":
+		return true
+	}
+	return strings.Contains(text, "/")
+}
@@ -0,0 +1,304 @@
+package apply
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const devNull = "/dev/null"
+
+// FilePatch 单个文件的 unified diff
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []*Hunk
+}
+
+// Hunk diff 中的一个改动块，Lines 保留 ' '、'-'、'+' 前缀
+type Hunk struct {
+	OldStart int
+	OldLines int
+	Lines    []string
+}
+
+// Path 补丁作用的目标路径，删除文件时为旧路径
+func (p *FilePatch) Path() string {
+	if p.NewPath == "" || p.NewPath == devNull {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+// IsNew 是否新建文件
+func (p *FilePatch) IsNew() bool {
+	return p.OldPath == devNull
+}
+
+// IsDelete 是否删除文件
+func (p *FilePatch) IsDelete() bool {
+	return p.NewPath == devNull
+}
+
+// IsRename 是否重命名文件
+func (p *FilePatch) IsRename() bool {
+	return !p.IsNew() && !p.IsDelete() && p.OldPath != "" && p.NewPath != "" && cleanPath(p.OldPath) != cleanPath(p.NewPath)
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatches 解析 unified diff，可包含多个文件
+//
+// 模型生成的 diff 行数统计经常不准确，这里不依赖 @@ 中的行数，
+// 改动块一直延续到下一个 @@ 或文件头；空行视为空白的上下文行
+func parsePatches(lines []string) ([]*FilePatch, error) {
+	var patches []*FilePatch
+	var current *FilePatch
+	var hunk *Hunk
+
+	startFile := func() {
+		current = &FilePatch{}
+		patches = append(patches, current)
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			startFile()
+			if oldPath, newPath, ok := parseGitHeader(line); ok {
+				current.OldPath, current.NewPath = oldPath, newPath
+			}
+			continue
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// 同一文件的 diff --git 头之后紧跟 ---/+++，此时不再新建
+			if current == nil || len(current.Hunks) > 0 {
+				startFile()
+			}
+			current.OldPath = patchFilePath(line[4:], "a/")
+			current.NewPath = patchFilePath(lines[i+1][4:], "b/")
+			i++
+			continue
+
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				startFile()
+			}
+			hunk = &Hunk{}
+			if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+				hunk.OldStart, _ = strconv.Atoi(m[1])
+				hunk.OldLines = 1
+				if m[2] != "" {
+					hunk.OldLines, _ = strconv.Atoi(m[2])
+				}
+			} else {
+				// 没有行号的 "@@ ... @@"，在整个文件中查找
+				hunk.OldStart, hunk.OldLines = -1, -1
+			}
+			current.Hunks = append(current.Hunks, hunk)
+			continue
+		}
+
+		if hunk == nil {
+			if current != nil {
+				switch {
+				case strings.HasPrefix(line, "rename from "):
+					current.OldPath = strings.TrimPrefix(line, "rename from ")
+				case strings.HasPrefix(line, "rename to "):
+					current.NewPath = strings.TrimPrefix(line, "rename to ")
+				case strings.HasPrefix(line, "new file mode"):
+					current.OldPath = devNull
+				case strings.HasPrefix(line, "deleted file mode"):
+					current.NewPath = devNull
+				}
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			hunk.Lines = append(hunk.Lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.Lines = append(hunk.Lines, line)
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			return nil, fmt.Errorf("无法识别的 diff 行: %q", line)
+		}
+	}
+
+	for _, patch := range patches {
+		for _, h := range patch.Hunks {
+			// 代码块末尾多余的空行不作为上下文
+			for len(h.Lines) > 0 && h.Lines[len(h.Lines)-1] == " " {
+				h.Lines = h.Lines[:len(h.Lines)-1]
+			}
+		}
+		if len(patch.Hunks) == 0 && !patch.IsRename() && !patch.IsDelete() {
+			return nil, fmt.Errorf("%s: diff 中没有改动块", patch.Path())
+		}
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("未找到 diff 内容")
+	}
+
+	return patches, nil
+}
+
+// parseGitHeader 解析 "diff --git a/x b/y"
+func parseGitHeader(line string) (string, string, bool) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if !strings.HasPrefix(rest, "a/") {
+		return "", "", false
+	}
+	idx := strings.Index(rest, " b/")
+	if idx < 0 {
+		return "", "", false
+	}
+	return rest[2:idx], rest[idx+3:], true
+}
+
+// patchFilePath 去掉 ---/+++ 行中的 a/ b/ 前缀与时间戳
+func patchFilePath(text, prefix string) string {
+	if idx := strings.Index(text, "\t"); idx >= 0 {
+		text = text[:idx]
+	}
+	text = strings.TrimSpace(text)
+	if text == devNull {
+		return devNull
+	}
+	return strings.TrimPrefix(text, prefix)
+}
+
+// Apply 将补丁应用到 old 上
+//
+// 先按 @@ 中的行号就近查找上下文，找不到时忽略行尾空白，再忽略缩进，
+// 以容忍模型在上下文行中的细微偏差
+func (p *FilePatch) Apply(old string) (string, error) {
+	crlf := strings.Contains(old, "\r\n")
+	trailingNewline := old == "" || strings.HasSuffix(old, "\n")
+
+	text := old
+	if crlf {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	lines := splitLines(text)
+
+	// offset 为已应用的改动块造成的行号偏移，cursor 保证改动块按顺序应用
+	offset, cursor := 0, 0
+	for n, hunk := range p.Hunks {
+		var from []string
+		for _, line := range hunk.Lines {
+			if line[0] == ' ' || line[0] == '-' {
+				from = append(from, line[1:])
+			}
+		}
+
+		hint := hunk.OldStart - 1 + offset
+		if hunk.OldLines == 0 {
+			// "-N,0" 表示插入到第 N 行之后
+			hint = hunk.OldStart + offset
+		}
+
+		pos := -1
+		if len(from) == 0 {
+			pos = hint
+			if pos < cursor || hunk.OldStart < 0 {
+				pos = len(lines)
+			}
+			if pos > len(lines) {
+				pos = len(lines)
+			}
+		} else {
+			pos = findBlock(lines, from, hint, cursor)
+		}
+		if pos < 0 {
+			return "", fmt.Errorf("第 %d 个改动块与文件内容不匹配: %s", n+1, describeHunk(from))
+		}
+
+		// 模糊匹配时上下文行保留文件中的原文
+		var replaced []string
+		k := pos
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case ' ':
+				replaced = append(replaced, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				replaced = append(replaced, line[1:])
+			}
+		}
+
+		lines = append(lines[:pos], append(replaced, lines[pos+len(from):]...)...)
+		offset += len(replaced) - len(from)
+		cursor = pos + len(replaced)
+	}
+
+	result := strings.Join(lines, "\n")
+	if len(lines) > 0 && trailingNewline {
+		result += "\n"
+	}
+	if crlf {
+		result = strings.ReplaceAll(result, "\n", "\r\n")
+	}
+	return result, nil
+}
+
+// findBlock 在 lines 中查找 block，优先选择离 hint 最近的位置
+func findBlock(lines, block []string, hint, cursor int) int {
+	normalizers := []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return strings.TrimRight(s, " \t") },
+		strings.TrimSpace,
+	}
+
+	for _, normalize := range normalizers {
+		best := -1
+		for pos := cursor; pos+len(block) <= len(lines); pos++ {
+			if !matchAt(lines, block, pos, normalize) {
+				continue
+			}
+			if best < 0 || distance(pos, hint) < distance(best, hint) {
+				best = pos
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, block []string, pos int, normalize func(string) string) bool {
+	for i, line := range block {
+		if normalize(lines[pos+i]) != normalize(line) {
+			return false
+		}
+	}
+	return true
+}
+
+func distance(a, b int) int {
+	if b < 0 {
+		return a
+	}
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func describeHunk(from []string) string {
+	for _, line := range from {
+		if strings.TrimSpace(line) != "" {
+			return strconv.Quote(strings.TrimSpace(line))
+		}
+	}
+	return "(空白)"
+}
@@ -0,0 +1,189 @@
+package apply
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/unpack"
+)
+
+// FileChange 计划写入的单个文件变更
+type FileChange struct {
+	Path    string
+	Old     string
+	New     string
+	Existed bool
+	Delete  bool
+}
+
+// Status 变更类型：A 新增 / M 修改 / D 删除
+func (c *FileChange) Status() string {
+	switch {
+	case c.Delete:
+		return "D"
+	case !c.Existed:
+		return "A"
+	}
+	return "M"
+}
+
+// Stat 新增与删除的行数
+func (c *FileChange) Stat() (int, int) {
+	return diffStat(c.Old, c.New)
+}
+
+// Diff 变更的 unified diff 预览
+func (c *FileChange) Diff() string {
+	oldName, newName := "a/"+c.Path, "b/"+c.Path
+	if !c.Existed {
+		oldName = devNull
+	}
+	if c.Delete {
+		newName = devNull
+	}
+	return UnifiedDiff(oldName, newName, c.Old, c.New, 3)
+}
+
+// Plan 读取 root 下的现有文件，计算每个文件修改后的内容
+//
+// 同一文件的多处修改按出现顺序依次应用，"./a.go" 与 "a.go" 视为同一文件；
+// 内容没有变化的文件会被省略
+func Plan(root string, edits []*Edit) ([]*FileChange, error) {
+	changes := make(map[string]*FileChange)
+	var order []string
+
+	load := func(path string) (*FileChange, error) {
+		path = cleanPath(path)
+		if change, ok := changes[path]; ok {
+			return change, nil
+		}
+
+		target, err := unpack.SafeJoin(root, path)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &FileChange{Path: path}
+		data, err := os.ReadFile(target)
+		switch {
+		case err == nil:
+			change.Old = string(data)
+			change.New = change.Old
+			change.Existed = true
+		case os.IsNotExist(err):
+		default:
+			return nil, err
+		}
+
+		changes[path] = change
+		order = append(order, path)
+		return change, nil
+	}
+
+	for _, edit := range edits {
+		if edit.Patch == nil {
+			change, err := load(edit.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", edit.Source, err)
+			}
+			change.New = normalizeContent(edit.Content, change.Old, change.Existed)
+			change.Delete = false
+			continue
+		}
+
+		if err := planPatch(edit, load); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", edit.Source, edit.Path, err)
+		}
+	}
+
+	var result []*FileChange
+	for _, path := range order {
+		change := changes[path]
+		if change.Delete && !change.Existed {
+			continue
+		}
+		if !change.Delete && change.Existed && change.New == change.Old {
+			continue
+		}
+		result = append(result, change)
+	}
+	return result, nil
+}
+
+func planPatch(edit *Edit, load func(string) (*FileChange, error)) error {
+	patch := edit.Patch
+
+	source := patch.OldPath
+	if patch.IsNew() || source == "" {
+		source = patch.Path()
+	}
+	from, err := load(source)
+	if err != nil {
+		return err
+	}
+
+	base := from.New
+	if patch.IsNew() {
+		if from.Existed && !from.Delete {
+			return fmt.Errorf("文件已存在，无法按新文件创建")
+		}
+		base = ""
+	} else if !from.Existed && from.New == "" {
+		return fmt.Errorf("文件不存在")
+	}
+
+	if patch.IsDelete() {
+		from.New = ""
+		from.Delete = true
+		return nil
+	}
+
+	content, err := patch.Apply(base)
+	if err != nil {
+		return err
+	}
+
+	if !patch.IsRename() {
+		from.New = content
+		from.Delete = false
+		return nil
+	}
+
+	to, err := load(patch.NewPath)
+	if err != nil {
+		return err
+	}
+	from.New = ""
+	from.Delete = true
+	to.New = content
+	to.Delete = false
+	return nil
+}
+
+// cleanPath 统一回复中的路径写法，作为同一文件的键；跳出目录的 ".." 会保留，由 SafeJoin 拒绝
+func cleanPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// normalizeContent 代码块中的完整文件内容沿用原文件的换行风格与末尾换行
+func normalizeContent(content, old string, existed bool) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return ""
+	}
+
+	if !existed || strings.HasSuffix(old, "\n") {
+		content += "\n"
+	}
+	if strings.Contains(old, "\r\n") {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	return content
+}
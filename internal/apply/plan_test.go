@@ -0,0 +1,90 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func parseReply(t *testing.T, text string) []*Edit {
+	t.Helper()
+	reply, err := Parse(strings.NewReader(text), "reply.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply.Edits
+}
+
+func TestPlanMergesEquivalentPaths(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n\nvar x = 1\n"})
+
+	edits := parseReply(t, "### ./a.go\n\n```go\npackage a\n\nvar x = 2\n```\n\n"+
+		"```diff\n--- a/a.go\n+++ b/a.go\n@@ -3 +3 @@\n-var x = 2\n+var x = 3\n```\n")
+	changes, err := Plan(root, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("./a.go 与 a.go 应合并为一个变更: %d", len(changes))
+	}
+	if changes[0].Path != "a.go" || changes[0].Status() != "M" || changes[0].New != "package a\n\nvar x = 3\n" {
+		t.Errorf("change = %+v", changes[0])
+	}
+}
+
+func TestPlanStatuses(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "old\n", "b.go": "b\n", "same.go": "same\n"})
+
+	edits := parseReply(t, "```diff\n--- a/b.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-b\n```\n\n"+
+		"### new/c.go\n\n```go\nc\n```\n\n### same.go\n\n```go\nsame\n```\n\n"+
+		"```diff\n--- a/a.go\n+++ b/d.go\n@@ -1 +1 @@\n-old\n+new\n```\n")
+	changes, err := Plan(root, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.Status()+" "+change.Path)
+	}
+	if strings.Join(got, ",") != "D b.go,A new/c.go,D a.go,A d.go" {
+		t.Errorf("changes = %v", got)
+	}
+}
+
+func TestPlanRejectsEscapes(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"../x.go", "a/../../x.go", "/etc/passwd"} {
+		if _, err := Plan(root, []*Edit{{Path: path, Content: "x", Source: "reply.md:1"}}); err == nil {
+			t.Errorf("应拒绝 %q", path)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	outside := t.TempDir()
+	writeFiles(t, outside, map[string]string{"x.go": "secret\n"})
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Plan(root, []*Edit{{Path: "link/x.go", Content: "x", Source: "reply.md:1"}}); err == nil {
+		t.Error("应拒绝经符号链接读写目标目录之外的文件")
+	}
+}
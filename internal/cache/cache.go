@@ -0,0 +1,275 @@
+// Package cache 按内容哈希缓存每个文件的处理结果，重复运行时未变化的文件跳过检测、压缩与 token 计数；
+// 文件本身仍需读取，以计算哈希并输出内容
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"printcode2llm/internal/version"
+)
+
+// formatVersion 缓存条目格式的版本，条目结构变化时递增，旧条目自然失效
+const formatVersion = "1"
+
+// 缓存目录的上限；Open 每隔 pruneInterval 清理一次，先删除超过 MaxAge 未使用的条目，
+// 仍超过 MaxSize 时按最近使用时间从旧到新删除
+const (
+	MaxSize = 512 << 20
+	MaxAge  = 30 * 24 * time.Hour
+
+	pruneInterval = 24 * time.Hour
+	// touchInterval 命中时条目的修改时间早于该间隔才更新，作为最近使用时间
+	touchInterval = time.Hour
+	// pruneMarker 记录上次清理时间的文件
+	pruneMarker = ".pruned"
+)
+
+// Cache 磁盘缓存，每个条目一个 JSON 文件
+//
+// 写入先落到临时文件再改名，多个 ptlm 进程同时读写同一目录时不会读到半个文件；
+// 读取失败的条目按未命中处理。条目的修改时间即最近使用时间，清理时据此淘汰。
+// nil 表示不使用缓存，所有方法都可以安全调用
+type Cache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Dir 默认的缓存目录
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录: %w", err)
+	}
+	return filepath.Join(dir, "ptlm", "cache"), nil
+}
+
+// Open 打开（必要时创建）缓存目录，距上次清理超过一天时按 MaxAge 与 MaxSize 清理
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+
+	marker := filepath.Join(dir, pruneMarker)
+	if info, err := os.Stat(marker); err != nil || time.Since(info.ModTime()) > pruneInterval {
+		// 先更新标记，同时启动的其他进程不再重复清理
+		if os.WriteFile(marker, nil, 0644) == nil {
+			Prune(dir, MaxSize, MaxAge)
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key 由各部分计算条目的键；ptlm 版本不同时键也不同，升级后不会用到旧的压缩结果。
+// 本地构建的版本号都是 "dev"，调用方应在 parts 中加入各自的格式版本，处理逻辑变化时递增
+func Key(parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(formatVersion + "\x00" + version.Version))
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get 读取条目到 v，不存在或无法解析时返回 false
+func (c *Cache) Get(key string, v any) bool {
+	if c == nil {
+		return false
+	}
+	path := c.path(key)
+	data, modTime, err := readEntry(path)
+	if err == nil && json.Unmarshal(data, v) == nil {
+		c.hits.Add(1)
+		if now := time.Now(); now.Sub(modTime) > touchInterval {
+			os.Chtimes(path, now, now)
+		}
+		return true
+	}
+	c.misses.Add(1)
+	return false
+}
+
+func readEntry(path string) ([]byte, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := io.ReadAll(f)
+	return data, info.ModTime(), err
+}
+
+// Put 写入条目；缓存只是加速手段，写入失败时忽略
+func (c *Cache) Put(key string, v any) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	target := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// 其他进程可能已经写入同一个条目，内容相同，改名失败时直接丢弃
+	if err != nil || os.Rename(tmp.Name(), target) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Hits 本次运行命中的次数
+func (c *Cache) Hits() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.hits.Load())
+}
+
+// Misses 本次运行未命中的次数
+func (c *Cache) Misses() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.misses.Load())
+}
+
+// Stats 缓存目录的统计
+type Stats struct {
+	Dir     string
+	Entries int
+	Size    int64
+}
+
+// ReadStats 统计缓存目录中的条目数与占用空间，目录不存在时返回零值
+func ReadStats(dir string) (*Stats, error) {
+	stats := &Stats{Dir: dir}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取缓存目录失败: %w", err)
+	}
+	return stats, nil
+}
+
+// PruneResult 一次清理的结果
+type PruneResult struct {
+	Removed int
+	Freed   int64
+	// Remaining 清理后剩余的条目
+	Remaining Stats
+}
+
+// Prune 删除超过 maxAge 未使用的条目（maxAge 为 0 时不按时间删除），剩余条目总大小仍超过
+// maxSize 时按最近使用时间从旧到新删除（maxSize 为 0 时不限制）；遗留的临时文件超过一小时也一并删除
+func Prune(dir string, maxSize int64, maxAge time.Duration) (*PruneResult, error) {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	result := &PruneResult{Remaining: Stats{Dir: dir}}
+	now := time.Now()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		switch {
+		case strings.HasPrefix(d.Name(), ".tmp-"):
+			if now.Sub(info.ModTime()) > time.Hour {
+				os.Remove(path)
+			}
+		case filepath.Ext(path) == ".json":
+			entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取缓存目录失败: %w", err)
+	}
+
+	// 从最近使用的开始累计，超过大小上限之后更早使用的条目全部删除
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.After(entries[j].modTime)
+	})
+	full := false
+	for _, e := range entries {
+		full = full || (maxSize > 0 && result.Remaining.Size+e.size > maxSize)
+		expired := maxAge > 0 && now.Sub(e.modTime) > maxAge
+		if !full && !expired {
+			result.Remaining.Entries++
+			result.Remaining.Size += e.size
+			continue
+		}
+		if err := os.Remove(e.path); err == nil || os.IsNotExist(err) {
+			result.Removed++
+			result.Freed += e.size
+		}
+	}
+	return result, nil
+}
+
+// Clear 删除缓存目录中的全部条目
+func Clear(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("清空缓存失败: %w", err)
+	}
+	return nil
+}
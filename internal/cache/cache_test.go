@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// putAged 写入条目并把最近使用时间设为 age 之前
+func putAged(t *testing.T, c *Cache, key string, v any, age time.Duration) string {
+	t.Helper()
+	c.Put(key, v)
+	path := c.path(key)
+	when := time.Now().Add(-age)
+	if err := os.Chtimes(path, when, when); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestGetPut(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := Key("test", "a")
+	var v string
+	if c.Get(key, &v) {
+		t.Fatal("空缓存不应命中")
+	}
+	c.Put(key, "value")
+	if !c.Get(key, &v) || v != "value" {
+		t.Fatalf("Get = %q", v)
+	}
+	if c.Hits() != 1 || c.Misses() != 1 {
+		t.Errorf("hits=%d misses=%d", c.Hits(), c.Misses())
+	}
+
+	var nilCache *Cache
+	nilCache.Put(key, "value")
+	if nilCache.Get(key, &v) {
+		t.Error("nil 缓存不应命中")
+	}
+}
+
+func TestGetTouchesEntry(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key("test", "touch")
+	path := putAged(t, c, key, "value", 48*time.Hour)
+
+	var v string
+	if !c.Get(key, &v) {
+		t.Fatal("应命中")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Errorf("命中后未更新最近使用时间: %v", info.ModTime())
+	}
+}
+
+func TestPruneByAge(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := putAged(t, c, Key("fresh"), "x", time.Hour)
+	stale := putAged(t, c, Key("stale"), "x", 40*24*time.Hour)
+	tmp := filepath.Join(dir, ".tmp-123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(tmp, old, old)
+
+	result, err := Prune(dir, 0, MaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 1 || result.Remaining.Entries != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if !exists(fresh) || exists(stale) || exists(tmp) {
+		t.Errorf("fresh=%v stale=%v tmp=%v", exists(fresh), exists(stale), exists(tmp))
+	}
+}
+
+func TestPruneBySizeKeepsRecent(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := string(make([]byte, 100))
+	var paths []string
+	for i := 0; i < 5; i++ {
+		// i 越大越久未使用
+		paths = append(paths, putAged(t, c, Key("size", string(rune('a'+i))), value, time.Duration(i+1)*time.Hour))
+	}
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Prune(dir, 3*info.Size(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 2 || result.Remaining.Entries != 3 || result.Remaining.Size > 3*info.Size() {
+		t.Errorf("result = %+v", result)
+	}
+	for i, path := range paths {
+		if want := i < 3; exists(path) != want {
+			t.Errorf("条目 %d: exists=%v, want %v", i, exists(path), want)
+		}
+	}
+}
+
+func TestOpenPrunesOncePerInterval(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := putAged(t, c, Key("stale"), "x", 40*24*time.Hour)
+
+	// 刚清理过，不再清理
+	if _, err := Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(stale) {
+		t.Fatal("间隔内不应再次清理")
+	}
+
+	old := time.Now().Add(-2 * pruneInterval)
+	if err := os.Chtimes(filepath.Join(dir, pruneMarker), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if exists(stale) {
+		t.Error("超过间隔后应清理过期条目")
+	}
+}
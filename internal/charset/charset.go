@@ -0,0 +1,101 @@
+// Package charset 把 UTF-16、GBK/GB18030、Big5、Shift_JIS 与 Latin-1 编码的文本转换为 UTF-8，
+// 没有 BOM 时按字节分布推测编码
+package charset
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 编码名称，也是文件元信息中显示的名称
+const (
+	UTF8     = "UTF-8"
+	UTF8BOM  = "UTF-8 with BOM"
+	UTF16LE  = "UTF-16 LE"
+	UTF16BE  = "UTF-16 BE"
+	GBK      = "GBK"
+	GB18030  = "GB18030"
+	Big5     = "Big5"
+	ShiftJIS = "Shift_JIS"
+	Latin1   = "ISO-8859-1"
+)
+
+// aliases 配置中可以使用的名称，比较时忽略大小写、空格、- 与 _
+var aliases = map[string]string{
+	"utf8":       UTF8,
+	"utf8bom":    UTF8BOM,
+	"utf16le":    UTF16LE,
+	"utf16be":    UTF16BE,
+	"gbk":        GBK,
+	"gb2312":     GBK,
+	"cp936":      GBK,
+	"windows936": GBK,
+	"gb18030":    GB18030,
+	"big5":       Big5,
+	"cp950":      Big5,
+	"shiftjis":   ShiftJIS,
+	"sjis":       ShiftJIS,
+	"cp932":      ShiftJIS,
+	"windows31j": ShiftJIS,
+	"latin1":     Latin1,
+	"iso88591":   Latin1,
+}
+
+// Lookup 返回名称对应的编码，例如 "gb2312"、"cp932"、"utf-16le"
+func Lookup(name string) (string, bool) {
+	key := strings.ToLower(name)
+	key = strings.NewReplacer("-", "", "_", "", " ", "").Replace(key)
+	enc, ok := aliases[key]
+	return enc, ok
+}
+
+// Decode 按编码转换为 UTF-8，去掉开头的 BOM；无法解码的字节替换为 U+FFFD
+func Decode(data []byte, encoding string) string {
+	text, _ := decode(data, encoding)
+	return text
+}
+
+// decode 同 Decode，同时返回无法解码的序列数
+func decode(data []byte, encoding string) (string, int) {
+	switch encoding {
+	case UTF16LE:
+		return decodeUTF16(trimPrefix(data, 0xFF, 0xFE), false)
+	case UTF16BE:
+		return decodeUTF16(trimPrefix(data, 0xFE, 0xFF), true)
+	case GBK, GB18030:
+		return decodeGB18030(data)
+	case Big5:
+		return big5Table.decode(data)
+	case ShiftJIS:
+		return decodeShiftJIS(data)
+	case Latin1:
+		return decodeLatin1(data), 0
+	}
+	data = trimPrefix(data, 0xEF, 0xBB, 0xBF)
+	if utf8.Valid(data) {
+		return string(data), 0
+	}
+	return strings.ToValidUTF8(string(data), string(utf8.RuneError)), 1
+}
+
+// decodeLatin1 每个字节对应同值的码位，任何字节序列都能解码
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// trimPrefix 去掉开头的 BOM
+func trimPrefix(data []byte, bom ...byte) []byte {
+	if len(data) < len(bom) {
+		return data
+	}
+	for i, b := range bom {
+		if data[i] != b {
+			return data
+		}
+	}
+	return data[len(bom):]
+}
@@ -0,0 +1,91 @@
+package charset
+
+import "testing"
+
+func TestDetectAndDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		text     string
+	}{
+		{"ASCII", []byte("package main\n"), UTF8, "package main\n"},
+		{"UTF-8", []byte("// 中文\n"), UTF8, "// 中文\n"},
+		{"UTF-8 BOM", []byte("\xEF\xBB\xBFa = 1\n"), UTF8BOM, "a = 1\n"},
+		{"UTF-16 LE BOM", []byte("\xFF\xFEa\x00=\x00-N\x87e\n\x00"), UTF16LE, "a=中文\n"},
+		{"UTF-16 BE BOM", []byte("\xFE\xFF\x00a\x00=N-e\x87\x00\n"), UTF16BE, "a=中文\n"},
+		{"UTF-16 LE 无 BOM", []byte("i\x00n\x00t\x00 \x00x\x00;\x00\n\x00"), UTF16LE, "int x;\n"},
+		// 中 D6D0 文 CEC4 注 D7A2 释 CACD
+		{"GBK", []byte("// \xD6\xD0\xCE\xC4\xD7\xA2\xCA\xCD\nint x;\n"), GBK, "// 中文注释\nint x;\n"},
+		// こんにちは
+		{"Shift_JIS", []byte("// \x82\xB1\x82\xF1\x82\xC9\x82\xBF\x82\xCD\n"), ShiftJIS, "// こんにちは\n"},
+		{"Latin-1", []byte("# caf\xE9 na\xEFve r\xE9sum\xE9\n"), Latin1, "# café naïve résumé\n"},
+	}
+
+	for _, tt := range tests {
+		enc, ok := Detect(tt.data)
+		if !ok || enc != tt.encoding {
+			t.Errorf("%s: Detect = %q %v, want %q", tt.name, enc, ok, tt.encoding)
+			continue
+		}
+		if got := Decode(tt.data, enc); got != tt.text {
+			t.Errorf("%s: Decode = %q, want %q", tt.name, got, tt.text)
+		}
+	}
+}
+
+func TestDecodeSpecified(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		text     string
+	}{
+		// 中 A4A4 文 A4E5，与 GBK 难以区分，需要在 source_encoding 中指定
+		{"Big5", []byte("\xA4\xA4\xA4\xE5"), Big5, "中文"},
+		{"GB18030", []byte("\xD6\xD0"), GB18030, "中"},
+		{"无效的 GBK 序列", []byte("a\xFFb"), GBK, "a�b"},
+		{"UTF-16 奇数长度", []byte("a\x00b"), UTF16LE, "a�"},
+		{"无效的 UTF-8", []byte("a\xFFb"), UTF8, "a�b"},
+	}
+
+	for _, tt := range tests {
+		if got := Decode(tt.data, tt.encoding); got != tt.text {
+			t.Errorf("%s: Decode = %q, want %q", tt.name, got, tt.text)
+		}
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	// 0x80-0x9F 在 Latin-1 中是控制字符，也不像各种双字节编码
+	for _, data := range [][]byte{
+		[]byte("\x93quoted\x94 \x01\xFF"),
+		[]byte("x\x85\x85\x85\x85"),
+	} {
+		if enc, ok := Detect(data); ok {
+			t.Errorf("%q: Detect = %q，应无法识别", data, enc)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := map[string]string{
+		"gb2312":      GBK,
+		"CP936":       GBK,
+		"GB-18030":    GB18030,
+		"shift_jis":   ShiftJIS,
+		"Windows-31J": ShiftJIS,
+		"UTF-16LE":    UTF16LE,
+		"utf 8":       UTF8,
+		"latin1":      Latin1,
+		"ISO-8859-1":  Latin1,
+	}
+	for name, want := range tests {
+		if got, ok := Lookup(name); !ok || got != want {
+			t.Errorf("Lookup(%q) = %q %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := Lookup("ebcdic"); ok {
+		t.Error("未知的编码应返回 false")
+	}
+}
@@ -0,0 +1,186 @@
+package charset
+
+import (
+	"embed"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+//go:embed tables/*.bin
+var tableFS embed.FS
+
+// 双字节表的范围：首字节 0x81-0xFE，尾字节 0x40-0xFE
+const (
+	leadMin   = 0x81
+	trailMin  = 0x40
+	trailMax  = 0xFE
+	trailSpan = trailMax - trailMin + 1
+)
+
+// dbcsTable 双字节编码的解码表，从 tables 目录中按需加载；码位为 0 表示未定义
+type dbcsTable struct {
+	name  string
+	once  sync.Once
+	runes []uint16
+}
+
+var (
+	gbTable   = &dbcsTable{name: "tables/gb18030.bin"}
+	big5Table = &dbcsTable{name: "tables/big5.bin"}
+	sjisTable = &dbcsTable{name: "tables/shiftjis.bin"}
+)
+
+// lookup 返回双字节序列对应的字符，未定义时为 0
+func (t *dbcsTable) lookup(lead, trail byte) rune {
+	t.once.Do(func() {
+		data, err := tableFS.ReadFile(t.name)
+		if err != nil {
+			panic("读取编码表失败: " + err.Error())
+		}
+		t.runes = make([]uint16, len(data)/2)
+		for i := range t.runes {
+			t.runes[i] = binary.LittleEndian.Uint16(data[i*2:])
+		}
+	})
+
+	if lead < leadMin || lead == 0xFF || trail < trailMin || trail > trailMax {
+		return 0
+	}
+	return rune(t.runes[int(lead-leadMin)*trailSpan+int(trail-trailMin)])
+}
+
+// decode 按双字节表解码，ASCII 原样保留；Big5 没有其他单字节字符
+func (t *dbcsTable) decode(data []byte) (string, int) {
+	var builder strings.Builder
+	builder.Grow(len(data) * 3 / 2)
+	invalid := 0
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			builder.WriteByte(b)
+			i++
+			continue
+		}
+		if i+1 < len(data) {
+			if r := t.lookup(b, data[i+1]); r != 0 {
+				builder.WriteRune(r)
+				i += 2
+				continue
+			}
+		}
+		builder.WriteRune(utf8.RuneError)
+		invalid++
+		i++
+	}
+
+	return builder.String(), invalid
+}
+
+// decodeShiftJIS 在双字节表之外处理 0xA1-0xDF 的半角片假名
+func decodeShiftJIS(data []byte) (string, int) {
+	var builder strings.Builder
+	builder.Grow(len(data) * 3 / 2)
+	invalid := 0
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			builder.WriteByte(b)
+			i++
+			continue
+		case b >= 0xA1 && b <= 0xDF:
+			builder.WriteRune(rune(b) - 0xA1 + 0xFF61)
+			i++
+			continue
+		}
+		if i+1 < len(data) {
+			if r := sjisTable.lookup(b, data[i+1]); r != 0 {
+				builder.WriteRune(r)
+				i += 2
+				continue
+			}
+		}
+		builder.WriteRune(utf8.RuneError)
+		invalid++
+		i++
+	}
+
+	return builder.String(), invalid
+}
+
+// decodeGB18030 解码 GBK 与 GB18030：双字节查表，四字节按区间换算
+func decodeGB18030(data []byte) (string, int) {
+	var builder strings.Builder
+	builder.Grow(len(data) * 3 / 2)
+	invalid := 0
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			builder.WriteByte(b)
+			i++
+			continue
+		}
+		if i+3 < len(data) && isGB18030Four(data[i:i+4]) {
+			if r := gb18030Four(data[i : i+4]); r != 0 {
+				builder.WriteRune(r)
+				i += 4
+				continue
+			}
+		}
+		if i+1 < len(data) {
+			if r := gbTable.lookup(b, data[i+1]); r != 0 {
+				builder.WriteRune(r)
+				i += 2
+				continue
+			}
+		}
+		builder.WriteRune(utf8.RuneError)
+		invalid++
+		i++
+	}
+
+	return builder.String(), invalid
+}
+
+// isGB18030Four 是否是四字节序列的形式：首、三字节 0x81-0xFE，二、四字节为数字
+func isGB18030Four(b []byte) bool {
+	return b[0] >= 0x81 && b[0] <= 0xFE && b[1] >= '0' && b[1] <= '9' &&
+		b[2] >= 0x81 && b[2] <= 0xFE && b[3] >= '0' && b[3] <= '9'
+}
+
+// gb18030Four 四字节序列对应的字符，未定义时为 0
+func gb18030Four(b []byte) rune {
+	index := ((uint32(b[0]-0x81)*10+uint32(b[1]-'0'))*126+uint32(b[2]-0x81))*10 + uint32(b[3]-'0')
+
+	// 0x90308130 起依次对应 U+10000 之后的码位
+	const supplementary = 189000
+	if index >= supplementary {
+		if r := rune(index-supplementary) + 0x10000; r <= utf8.MaxRune {
+			return r
+		}
+		return 0
+	}
+	if index >= gb18030BMPEnd {
+		return 0
+	}
+
+	lo, hi := 0, len(gb18030Ranges)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if gb18030Ranges[mid][0] <= index {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	start := gb18030Ranges[lo]
+	if index < start[0] {
+		return 0
+	}
+	return rune(start[1] + index - start[0])
+}
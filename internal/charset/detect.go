@@ -0,0 +1,199 @@
+package charset
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sampleSize 判断 UTF-16 时检查的字节数
+const sampleSize = 8192
+
+// Detect 推测文本编码：先看 BOM，再看是否像 UTF-16、是否是合法的 UTF-8，
+// 再比较 GBK/GB18030、Big5 与 Shift_JIS 的解码结果，最后按 Latin-1 解读时没有控制字符才视为 Latin-1；
+// 都不像时返回 false。只有极少数无效序列的 UTF-8 文本仍按 UTF-8 处理，无效的字节转换时替换为 U+FFFD
+func Detect(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8BOM, true
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return UTF16LE, true
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return UTF16BE, true
+	}
+
+	sample := data
+	if len(sample) > sampleSize {
+		sample = sample[:sampleSize]
+	}
+	if enc, ok := guessUTF16(sample); ok {
+		if text, invalid := decode(data, enc); invalid == 0 && !hasControl(text) {
+			return enc, true
+		}
+	}
+
+	if utf8.Valid(data) || mostlyUTF8(data) {
+		return UTF8, true
+	}
+	if enc, ok := guessLegacy(data); ok {
+		return enc, true
+	}
+	// 西欧语言的旧文件：0x80-0x9F 在 Latin-1 中是控制字符，出现时不是 Latin-1 文本
+	if !hasControl(decodeLatin1(data)) {
+		return Latin1, true
+	}
+	return "", false
+}
+
+// hasControl 是否包含制表、换行、换页以外的控制字符，UTF-16 文本中不应出现
+func hasControl(text string) bool {
+	for _, r := range text {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' && r != '\f' {
+			return true
+		}
+	}
+	return false
+}
+
+// mostlyUTF8 无效的字节不超过多字节字符的 1%
+func mostlyUTF8(data []byte) bool {
+	valid, invalid := 0, 0
+	for i := 0; i < len(data); {
+		if data[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		} else {
+			valid++
+		}
+		i += size
+	}
+	return valid > 0 && invalid*100 <= valid
+}
+
+// legacyScore 按某种双字节编码解读时的统计
+type legacyScore struct {
+	// total 非 ASCII 字符数，typical 其中落在该编码常用区的字符数
+	total   int
+	typical float64
+	invalid int
+	// fourByte 出现了 GB18030 四字节序列
+	fourByte bool
+}
+
+// ratio 常用字符的比例，无法解码的序列超过 1% 时为 0
+func (s legacyScore) ratio() float64 {
+	if s.total == 0 || s.invalid*100 > s.total {
+		return 0
+	}
+	return s.typical / float64(s.total)
+}
+
+// minLegacyRatio 常用字符比例低于该值时不认为是这种编码
+const minLegacyRatio = 0.6
+
+// guessLegacy 分别按 GBK、Big5、Shift_JIS 解读，取常用字符比例最高的一种；
+// 比例相同时按这个顺序优先
+func guessLegacy(data []byte) (string, bool) {
+	best, bestRatio := "", minLegacyRatio
+
+	gb := scoreGB(data)
+	if r := gb.ratio(); r >= bestRatio {
+		best, bestRatio = GBK, r
+		if gb.fourByte {
+			best = GB18030
+		}
+	}
+	if r := scoreBig5(data).ratio(); r > bestRatio {
+		best, bestRatio = Big5, r
+	}
+	if r := scoreShiftJIS(data).ratio(); r > bestRatio {
+		best = ShiftJIS
+	}
+
+	return best, best != ""
+}
+
+// scoreGB 常用区为 GB2312 的符号区 (A1-A9) 与汉字区 (B0-F7)
+func scoreGB(data []byte) legacyScore {
+	var s legacyScore
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			i++
+			continue
+		}
+		s.total++
+		if i+3 < len(data) && isGB18030Four(data[i:i+4]) && gb18030Four(data[i:i+4]) != 0 {
+			s.fourByte = true
+			i += 4
+			continue
+		}
+		if i+1 < len(data) && gbTable.lookup(b, data[i+1]) != 0 {
+			if trail := data[i+1]; trail >= 0xA1 && ((b >= 0xA1 && b <= 0xA9) || (b >= 0xB0 && b <= 0xF7)) {
+				s.typical++
+			}
+			i += 2
+			continue
+		}
+		s.invalid++
+		i++
+	}
+	return s
+}
+
+// scoreBig5 常用区为符号 (A1-A3) 与常用字 (A4-C6)，次常用字 (C9-F9) 计一半
+func scoreBig5(data []byte) legacyScore {
+	var s legacyScore
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			i++
+			continue
+		}
+		s.total++
+		if i+1 < len(data) && big5Table.lookup(b, data[i+1]) != 0 {
+			switch {
+			case b >= 0xA1 && b <= 0xC6:
+				s.typical++
+			case b >= 0xC9 && b <= 0xF9:
+				s.typical += 0.5
+			}
+			i += 2
+			continue
+		}
+		s.invalid++
+		i++
+	}
+	return s
+}
+
+// scoreShiftJIS 常用区为 JIS X 0208 的符号、假名与第一、二水准汉字，半角片假名不计
+func scoreShiftJIS(data []byte) legacyScore {
+	var s legacyScore
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			i++
+			continue
+		}
+		s.total++
+		if b >= 0xA1 && b <= 0xDF {
+			i++
+			continue
+		}
+		if i+1 < len(data) && sjisTable.lookup(b, data[i+1]) != 0 {
+			if b <= 0x84 || (b >= 0x88 && b <= 0x9F) || (b >= 0xE0 && b <= 0xEA) {
+				s.typical++
+			}
+			i += 2
+			continue
+		}
+		s.invalid++
+		i++
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package charset
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// decodeUTF16 转换 UTF-16，末尾多出的单个字节与不成对的代理项替换为 U+FFFD
+func decodeUTF16(data []byte, bigEndian bool) (string, int) {
+	var builder strings.Builder
+	builder.Grow(len(data))
+	invalid := 0
+
+	unit := func(i int) uint16 {
+		if bigEndian {
+			return uint16(data[i])<<8 | uint16(data[i+1])
+		}
+		return uint16(data[i+1])<<8 | uint16(data[i])
+	}
+
+	i := 0
+	for ; i+1 < len(data); i += 2 {
+		u := unit(i)
+		switch {
+		case utf16.IsSurrogate(rune(u)):
+			if u < 0xDC00 && i+3 < len(data) {
+				if r := utf16.DecodeRune(rune(u), rune(unit(i+2))); r != utf8.RuneError {
+					builder.WriteRune(r)
+					i += 2
+					continue
+				}
+			}
+			builder.WriteRune(utf8.RuneError)
+			invalid++
+		default:
+			builder.WriteRune(rune(u))
+		}
+	}
+	if i < len(data) {
+		builder.WriteRune(utf8.RuneError)
+		invalid++
+	}
+
+	return builder.String(), invalid
+}
+
+// guessUTF16 没有 BOM 时按零字节的位置判断是否是以 ASCII 为主的 UTF-16 文本
+func guessUTF16(sample []byte) (string, bool) {
+	if len(sample) < 4 {
+		return "", false
+	}
+	pairs := len(sample) / 2
+	evenZeros, oddZeros := 0, 0
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	switch {
+	case oddZeros*10 >= pairs*4 && evenZeros*20 < pairs:
+		return UTF16LE, true
+	case evenZeros*10 >= pairs*4 && oddZeros*20 < pairs:
+		return UTF16BE, true
+	}
+	return "", false
+}
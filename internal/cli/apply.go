@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"fmt"
+
+	"printcode2llm/internal/apply"
+	"printcode2llm/internal/redact"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyDir    string
+	applyDryRun bool
+	applyYes    bool
+	applyUndo   bool
+	applyForce  bool
+	applyStat   bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <回复.md>",
+	Short: "将模型回复中的代码写回项目",
+	Long: `将模型回复中的代码块写回项目目录
+
+目标文件按以下约定识别:
+  - 与生成文档相同的 "### N. 路径" 标题，支持 "(续: 行 a-b)" 分段
+  - 代码块前的标题、**路径**、` + "`路径`" + ` 或 "文件: 路径"
+  - 代码块第一行的路径注释，例如 // main.go
+  - diff/patch 代码块按 unified diff 应用，支持新增、删除、重命名
+
+写入前展示每个文件的 diff 并确认，原文件备份到用户缓存目录，
+可以用 ptlm apply --undo 撤销最近一次写入。回复文件为 - 时读取标准输入。
+
+示例:
+  ptlm apply reply.md
+  ptlm apply --dry-run reply.md
+  pbpaste | ptlm apply -y -
+  ptlm apply --undo`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if applyUndo {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runApply,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&applyDir, "dir", "C", ".", "项目目录")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "只预览变更，不写入文件")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "不询问直接写入")
+	applyCmd.Flags().BoolVar(&applyStat, "stat", false, "只显示变更统计，不显示 diff")
+	applyCmd.Flags().BoolVar(&applyUndo, "undo", false, "撤销最近一次 apply")
+	applyCmd.Flags().BoolVar(&applyForce, "force", false, "撤销时忽略文件在 apply 之后的修改")
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	if applyUndo {
+		return runApplyUndo()
+	}
+
+	source := args[0]
+	if source == "-" && !applyYes && !applyDryRun {
+		return fmt.Errorf("从标准输入读取回复时需要指定 --yes 或 --dry-run")
+	}
+
+	ui.PrintHeader("应用模型回复")
+
+	reply, err := apply.ParseFile(source)
+	if err != nil {
+		return err
+	}
+	for _, where := range reply.Skipped {
+		ui.PrintWarning("%s: 无法确定代码块对应的文件，已跳过", where)
+	}
+	if len(reply.Edits) == 0 {
+		return fmt.Errorf("回复中没有找到可应用的代码块")
+	}
+
+	changes, err := apply.Plan(applyDir, reply.Edits)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		ui.PrintInfo("所有文件都与回复内容一致，无需修改")
+		return nil
+	}
+
+	ui.PrintSection("变更预览")
+	totalAdded, totalRemoved := 0, 0
+	for _, change := range changes {
+		added, removed := change.Stat()
+		totalAdded += added
+		totalRemoved += removed
+
+		ui.PrintStep("%s %s (+%d -%d)", change.Status(), change.Path, added, removed)
+		if !applyStat {
+			ui.PrintDiff(change.Diff())
+			fmt.Println()
+		}
+	}
+	ui.PrintInfo("共 %d 个文件，+%d -%d", len(changes), totalAdded, totalRemoved)
+
+	// 生成文档时遮盖的密钥被模型原样抄回来，写入后会覆盖真实值
+	for _, change := range changes {
+		if redact.ContainsPlaceholder(change.New) && !redact.ContainsPlaceholder(change.Old) {
+			ui.PrintWarning("%s 中含有 [REDACTED:...] 占位符，写入前请替换为真实值", change.Path)
+		}
+	}
+
+	if applyDryRun {
+		return nil
+	}
+
+	if !applyYes {
+		fmt.Print("是否写入？(y/N): ")
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" {
+			ui.PrintInfo("已取消")
+			return nil
+		}
+	}
+
+	journal, err := apply.Write(applyDir, source, changes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	ui.PrintSuccess("已写入 %d 个文件，可使用 ptlm apply --undo 撤销", len(changes))
+	ui.PrintInfo("备份位置: %s", journal.Dir())
+	return nil
+}
+
+func runApplyUndo() error {
+	journal, err := apply.Latest(applyDir)
+	if err != nil {
+		return err
+	}
+	if journal == nil {
+		return fmt.Errorf("%s 没有可撤销的 apply 记录", applyDir)
+	}
+
+	ui.PrintHeader("撤销 apply")
+	ui.PrintInfo("记录时间: %s (%s)", journal.Time.Format("2006-01-02 15:04:05"), journal.Source)
+
+	modified, err := journal.Undo(applyForce)
+	if err != nil {
+		return err
+	}
+	if len(modified) > 0 {
+		for _, path := range modified {
+			ui.PrintWarning("%s 在 apply 之后被修改过", path)
+		}
+		return fmt.Errorf("撤销会丢失上述修改，确认后使用 --force 强制撤销")
+	}
+
+	for _, entry := range journal.Entries {
+		if entry.Existed {
+			ui.PrintStep("已恢复 %s", entry.Path)
+		} else {
+			ui.PrintStep("已删除 %s", entry.Path)
+		}
+	}
+
+	fmt.Println()
+	ui.PrintSuccess("已撤销 %d 个文件的修改", len(journal.Entries))
+	return nil
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/llm"
+	"printcode2llm/internal/tokenizer"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	askProvider string
+	askURL      string
+	askModel    string
+	askSave     bool
+	askConfig   string
+	askTokens   int
+	askChars    int
+	askSkeleton bool
+	askUltra    bool
+	askExclude  string
+	askOnly     string
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask 问题 [项目目录...]",
+	Short: "把整理好的代码连同问题发送给大模型接口，流式输出回答",
+	Long: `把整理好的代码连同问题发送给大模型接口，流式输出回答
+
+各分段按顺序逐段发送，模型对每段的回复保留在对话中，问题附在最后一段之后；
+回答输出到标准输出，对话记录保存为 LLM_ASK_<时间>.md。
+全部分段、问题与回复超过 ask.context_window 时在发送前报错。
+
+接口、模型与密钥来自配置文件的 ask 部分，或环境变量:
+  PTLM_ASK_PROVIDER   openai / anthropic
+  PTLM_ASK_BASE_URL   接口地址，例如 http://127.0.0.1:11434/v1
+  PTLM_ASK_MODEL      模型名称
+  PTLM_ASK_API_KEY    密钥(也可以使用 OPENAI_API_KEY / ANTHROPIC_API_KEY)
+
+示例:
+  ptlm ask "这个项目的入口在哪里？" .
+  ptlm ask --skeleton -t 60000 "梳理一下模块之间的依赖" ./app`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAsk,
+}
+
+func init() {
+	rootCmd.AddCommand(askCmd)
+	askCmd.Flags().StringVar(&askProvider, "provider", "", "接口风格: openai/anthropic")
+	askCmd.Flags().StringVar(&askURL, "url", "", "接口地址")
+	askCmd.Flags().StringVarP(&askModel, "model", "m", "", "模型名称")
+	askCmd.Flags().BoolVar(&askSave, "save", true, "保存对话记录")
+	askCmd.Flags().StringVarP(&askConfig, "config", "f", "", "配置文件路径")
+	askCmd.Flags().IntVarP(&askTokens, "tokens", "t", 0, "每段最大 token 数；未指定词表时为估算值")
+	askCmd.Flags().IntVarP(&askChars, "chars", "c", 0, "每段最大字符数")
+	askCmd.Flags().BoolVar(&askSkeleton, "skeleton", false, "骨架模式: 只保留声明、签名与文档注释")
+	askCmd.Flags().BoolVarP(&askUltra, "ultra-compress", "u", false, "超级压缩")
+	askCmd.Flags().StringVar(&askExclude, "exclude", "", "排除模式(逗号分隔)")
+	askCmd.Flags().StringVar(&askOnly, "only", "", "只整理匹配的路径(逗号分隔，支持 **)")
+}
+
+func runAsk(cmd *cobra.Command, args []string) error {
+	question := strings.TrimSpace(args[0])
+	if question == "" {
+		return fmt.Errorf("问题不能为空")
+	}
+	dirs := args[1:]
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	cfg, err := config.LoadFor(askConfig, dirs)
+	if err != nil {
+		ui.PrintWarning("配置加载失败: %v", err)
+		cfg = config.Default()
+	}
+	if askTokens > 0 {
+		cfg.Output.MaxTokens = askTokens
+	}
+	if askChars > 0 {
+		cfg.Output.MaxChars = askChars
+	}
+	if cmd.Flags().Changed("skeleton") {
+		cfg.Output.Skeleton = askSkeleton
+	}
+	if askUltra {
+		cfg.Output.UltraCompress = true
+		cfg.Output.Compress = true
+	}
+	for _, p := range strings.Split(askExclude, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.CustomIgnore.Patterns = append(cfg.CustomIgnore.Patterns, p)
+		}
+	}
+	for _, p := range strings.Split(askOnly, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.Include.Patterns = append(cfg.Include.Patterns, p)
+		}
+	}
+	if err := generator.ValidateFormat(cfg.Output.Format); err != nil {
+		return err
+	}
+
+	client := askClient(cfg)
+	if err := client.Validate(); err != nil {
+		return fmt.Errorf("%w，请在配置文件的 ask 部分或环境变量 PTLM_ASK_MODEL 中设置", err)
+	}
+
+	ui.PrintHeader("PrintCode2LLM")
+	ui.PrintInfo("接口: %s", client.Endpoint())
+	ui.PrintInfo("模型: %s", client.Model)
+	ui.PrintBlank()
+
+	results, err := generateProjects(cfg, dirs)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("没有成功处理任何项目")
+	}
+
+	var segments []*generator.Segment
+	for _, r := range results {
+		segments = append(segments, r.Segments...)
+	}
+	if err := askCheckContext(cfg, segments, question); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	started := time.Now()
+	acks, streamErr := askParts(ctx, client, cfg.Ask.System, segments[:len(segments)-1])
+	reply := ""
+	if streamErr == nil {
+		messages := askConversation(segments, acks, question)
+		ui.PrintSection("回答")
+		reply, streamErr = client.Stream(ctx, cfg.Ask.System, messages, func(text string) {
+			fmt.Fprint(os.Stdout, text)
+		})
+		fmt.Fprintln(os.Stdout)
+		ui.PrintBlank()
+	}
+
+	if askSave {
+		name := fmt.Sprintf("%s_%s.md", cfg.Ask.TranscriptPrefix, started.Format("20060102_150405"))
+		transcript := askTranscript(client, results, segments, acks, question, reply, streamErr, started)
+		if err := os.WriteFile(name, []byte(transcript), 0644); err != nil {
+			ui.PrintWarning("保存对话记录失败: %v", err)
+		} else {
+			ui.PrintSuccess("对话记录: %s", name)
+		}
+	}
+
+	if streamErr != nil {
+		return fmt.Errorf("请求失败: %w", streamErr)
+	}
+	return nil
+}
+
+// askClient 按 命令行参数 > 环境变量 > 配置文件 的顺序确定接口设置
+func askClient(cfg *config.Config) *llm.Client {
+	ask := cfg.Ask
+	for _, o := range []struct {
+		dst  *string
+		env  string
+		flag string
+	}{
+		{&ask.Provider, "PTLM_ASK_PROVIDER", askProvider},
+		{&ask.BaseURL, "PTLM_ASK_BASE_URL", askURL},
+		{&ask.Model, "PTLM_ASK_MODEL", askModel},
+	} {
+		if v := os.Getenv(o.env); v != "" {
+			*o.dst = v
+		}
+		if o.flag != "" {
+			*o.dst = o.flag
+		}
+	}
+	ask.Provider = strings.ToLower(ask.Provider)
+
+	keyEnv := ask.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "OPENAI_API_KEY"
+		if ask.Provider == llm.ProviderAnthropic {
+			keyEnv = "ANTHROPIC_API_KEY"
+		}
+	}
+	key := os.Getenv("PTLM_ASK_API_KEY")
+	if key == "" {
+		key = os.Getenv(keyEnv)
+	}
+
+	return &llm.Client{
+		Provider:  ask.Provider,
+		BaseURL:   ask.BaseURL,
+		Model:     ask.Model,
+		APIKey:    key,
+		MaxTokens: ask.MaxTokens,
+	}
+}
+
+// askAckTokens 非最后一段的回复上限，这些回复只需确认收到，也计入上下文窗口
+const askAckTokens = 512
+
+// askCheckContext 发送前估算整个对话的 token 数：全部分段、问题、每段的确认回复与最终回答，
+// 超过上下文窗口时直接报错，而不是等接口拒绝或截断
+func askCheckContext(cfg *config.Config, segments []*generator.Segment, question string) error {
+	window := cfg.Ask.ContextWindow
+	if window <= 0 {
+		return nil
+	}
+	tok, err := tokenizer.Load(cfg.Output.Tokenizer, cfg.Output.TokenizerFile)
+	if err != nil {
+		tok, _ = tokenizer.Load(tokenizer.Heuristic, "")
+	}
+	total := tok.Count(question) + (len(segments)-1)*askAckTokens + cfg.Ask.MaxTokens
+	for _, seg := range segments {
+		total += seg.TokenCount
+	}
+	if total > window {
+		return fmt.Errorf("发送内容约 %s token（%d 个分段、问题与回复），超过上下文窗口 %s token（ask.context_window），"+
+			"请用 --skeleton、-u、--only 或 --exclude 缩小范围，或调大 context_window",
+			ui.FormatNumber(total), len(segments), ui.FormatNumber(window))
+	}
+	return nil
+}
+
+// askParts 逐段发送最后一段之前的分段，每次请求带上之前的分段与模型的回复，返回模型对各段的回复；
+// 出错时返回已收到的回复
+func askParts(ctx context.Context, client *llm.Client, system string, parts []*generator.Segment) ([]string, error) {
+	ack := *client
+	if ack.MaxTokens <= 0 || ack.MaxTokens > askAckTokens {
+		ack.MaxTokens = askAckTokens
+	}
+	total := len(parts) + 1
+	var acks []string
+	var messages []llm.Message
+	for i, seg := range parts {
+		ui.PrintStep("发送第 %d/%d 部分", i+1, total)
+		messages = append(messages, llm.Message{Role: "user", Content: seg.Content})
+		reply, err := ack.Stream(ctx, system, messages, nil)
+		if err != nil {
+			return acks, fmt.Errorf("第 %d/%d 部分: %w", i+1, total, err)
+		}
+		if strings.TrimSpace(reply) == "" {
+			return acks, fmt.Errorf("第 %d/%d 部分: 模型没有回复", i+1, total)
+		}
+		acks = append(acks, reply)
+		messages = append(messages, llm.Message{Role: "assistant", Content: reply})
+	}
+	return acks, nil
+}
+
+// askConversation 最终请求的对话：各分段与模型对它们的实际回复交替，问题附在最后一段之后
+func askConversation(segments []*generator.Segment, acks []string, question string) []llm.Message {
+	var messages []llm.Message
+	for i, seg := range segments {
+		if i < len(segments)-1 {
+			messages = append(messages,
+				llm.Message{Role: "user", Content: seg.Content},
+				llm.Message{Role: "assistant", Content: acks[i]},
+			)
+			continue
+		}
+		messages = append(messages, llm.Message{Role: "user", Content: seg.Content + "\n\n---\n\n" + question})
+	}
+	return messages
+}
+
+// askTranscript 对话记录：问题、回答、发送的全部分段与模型对各段的回复
+func askTranscript(client *llm.Client, results []*generator.Result, segments []*generator.Segment, acks []string, question, reply string, streamErr error, started time.Time) string {
+	var b strings.Builder
+	tokens := 0
+	var names []string
+	for _, r := range results {
+		names = append(names, r.ProjectName)
+	}
+	for _, seg := range segments {
+		tokens += seg.TokenCount
+	}
+
+	b.WriteString("# ptlm ask\n\n")
+	fmt.Fprintf(&b, "- **时间**: %s\n", started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- **接口**: %s (%s)\n", client.Endpoint(), client.Provider)
+	fmt.Fprintf(&b, "- **模型**: %s\n", client.Model)
+	fmt.Fprintf(&b, "- **项目**: %s\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "- **发送**: %d 个分段，约 %s token\n", len(segments), ui.FormatNumber(tokens))
+	fmt.Fprintf(&b, "- **耗时**: %s\n\n", time.Since(started).Round(time.Second))
+
+	b.WriteString("## 问题\n\n")
+	b.WriteString(question + "\n\n")
+	b.WriteString("## 回答\n\n")
+	b.WriteString(strings.TrimRight(reply, "\n") + "\n\n")
+	if streamErr != nil {
+		fmt.Fprintf(&b, "> 回答未完成: %v\n\n", streamErr)
+	}
+
+	b.WriteString("## 发送的内容\n\n")
+	for i, seg := range segments {
+		fmt.Fprintf(&b, "<details>\n<summary>第 %d/%d 部分 (%s token)</summary>\n\n", i+1, len(segments), ui.FormatNumber(seg.TokenCount))
+		b.WriteString(strings.TrimRight(seg.Content, "\n") + "\n\n</details>\n\n")
+		if i < len(acks) {
+			fmt.Fprintf(&b, "> 模型回复: %s\n\n", strings.ReplaceAll(strings.TrimSpace(acks[i]), "\n", "\n> "))
+		}
+	}
+	return b.String()
+}
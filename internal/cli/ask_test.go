@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/llm"
+	"printcode2llm/internal/ui"
+)
+
+// chatRequest 测试服务收到的请求
+type chatRequest struct {
+	MaxTokens int           `json:"max_tokens"`
+	Messages  []llm.Message `json:"messages"`
+}
+
+// fakeChat 兼容 /chat/completions 的测试服务，第 n 次请求回复 "reply n"
+func fakeChat(t *testing.T) (*llm.Client, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"reply %d\"}}]}\n\n", len(requests))
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	client := &llm.Client{Provider: llm.ProviderOpenAI, BaseURL: srv.URL, Model: "test", MaxTokens: 4096}
+	return client, &requests
+}
+
+func testSegments(contents ...string) []*generator.Segment {
+	var segments []*generator.Segment
+	for i, c := range contents {
+		segments = append(segments, &generator.Segment{Content: c, PartNum: i + 1, TotalPart: len(contents), TokenCount: 1000})
+	}
+	return segments
+}
+
+func TestAskPartsUsesModelReplies(t *testing.T) {
+	defer ui.SetOutput(ui.Writer())
+	ui.SetOutput(io.Discard)
+
+	client, requests := fakeChat(t)
+	segments := testSegments("part 1", "part 2", "part 3")
+	acks, err := askParts(context.Background(), client, "", segments[:len(segments)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(acks, ",") != "reply 1,reply 2" {
+		t.Fatalf("acks = %v", acks)
+	}
+
+	// 第二次请求带上第一段与模型的实际回复，确认回复限制长度
+	second := (*requests)[1]
+	want := []llm.Message{
+		{Role: "user", Content: "part 1"},
+		{Role: "assistant", Content: "reply 1"},
+		{Role: "user", Content: "part 2"},
+	}
+	if fmt.Sprint(second.Messages) != fmt.Sprint(want) || second.MaxTokens != askAckTokens {
+		t.Errorf("第二次请求 = %+v", second)
+	}
+
+	messages := askConversation(segments, acks, "问题")
+	if len(messages) != 5 || messages[3].Content != "reply 2" || !strings.HasSuffix(messages[4].Content, "问题") {
+		t.Errorf("最终对话 = %+v", messages)
+	}
+	for _, m := range messages {
+		if strings.Contains(m.Content, "已收到") {
+			t.Errorf("对话中不应有虚构的回复: %q", m.Content)
+		}
+	}
+}
+
+func TestAskPartsStopsOnError(t *testing.T) {
+	defer ui.SetOutput(ui.Writer())
+	ui.SetOutput(io.Discard)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, `{"error":{"message":"context length exceeded"}}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	client := &llm.Client{Provider: llm.ProviderOpenAI, BaseURL: srv.URL, Model: "test"}
+
+	acks, err := askParts(context.Background(), client, "", testSegments("part 1", "part 2"))
+	if err == nil || !strings.Contains(err.Error(), "第 1/3 部分") || len(acks) != 0 || calls != 1 {
+		t.Errorf("acks=%v err=%v calls=%d", acks, err, calls)
+	}
+}
+
+func TestAskCheckContext(t *testing.T) {
+	cfg := config.Default()
+	cfg.Ask.MaxTokens = 4096
+	segments := testSegments("part 1", "part 2", "part 3")
+
+	cfg.Ask.ContextWindow = 128000
+	if err := askCheckContext(cfg, segments, "问题"); err != nil {
+		t.Errorf("放得下时不应报错: %v", err)
+	}
+
+	// 3 个分段 3000 + 2 次确认回复 + 最终回答 4096，超过 8000
+	cfg.Ask.ContextWindow = 8000
+	err := askCheckContext(cfg, segments, "问题")
+	if err == nil || !strings.Contains(err.Error(), "context_window") {
+		t.Errorf("超过上下文窗口时 err = %v", err)
+	}
+
+	cfg.Ask.ContextWindow = -1
+	if err := askCheckContext(cfg, segments, "问题"); err != nil {
+		t.Errorf("-1 不检查: %v", err)
+	}
+}
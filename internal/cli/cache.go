@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "磁盘缓存管理",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "显示缓存占用",
+	RunE:  runCacheStats,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "删除长期未使用的条目，并把缓存控制在大小上限之内",
+	RunE:  runCachePrune,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "清空缓存",
+	RunE:  runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	dir, err := cache.Dir()
+	if err != nil {
+		return err
+	}
+	stats, err := cache.ReadStats(dir)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintHeader("缓存")
+	ui.PrintInfo("位置: %s", stats.Dir)
+	ui.PrintInfo("条目: %s 个", ui.FormatNumber(stats.Entries))
+	ui.PrintInfo("大小: %s", ui.FormatBytes(stats.Size))
+	fmt.Println()
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	dir, err := cache.Dir()
+	if err != nil {
+		return err
+	}
+	result, err := cache.Prune(dir, cache.MaxSize, cache.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintSuccess("已删除 %s 个条目 (%s)，剩余 %s 个 (%s)", ui.FormatNumber(result.Removed), ui.FormatBytes(result.Freed),
+		ui.FormatNumber(result.Remaining.Entries), ui.FormatBytes(result.Remaining.Size))
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := cache.Dir()
+	if err != nil {
+		return err
+	}
+	stats, err := cache.ReadStats(dir)
+	if err != nil {
+		return err
+	}
+	if err := cache.Clear(dir); err != nil {
+		return err
+	}
+
+	ui.PrintSuccess("已清空缓存，删除 %s 个条目 (%s)", ui.FormatNumber(stats.Entries), ui.FormatBytes(stats.Size))
+	return nil
+}
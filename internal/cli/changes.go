@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"printcode2llm/internal/gitrepo"
+	"printcode2llm/internal/scanner"
+)
+
+var (
+	changeSpec gitrepo.Spec
+	diffMode   string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&changeSpec.Since, "since", "", "只包含相对指定分支/提交的变更")
+	rootCmd.Flags().BoolVar(&changeSpec.Staged, "staged", false, "只包含已暂存的变更")
+	rootCmd.Flags().BoolVar(&changeSpec.Uncommitted, "uncommitted", false, "只包含未提交的变更(含未跟踪文件)")
+	rootCmd.Flags().StringVar(&changeSpec.Commit, "commit", "", "只包含指定提交的变更")
+	rootCmd.Flags().StringVar(&diffMode, "diff", "", "变更模式下的 diff 输出: none/append/only")
+}
+
+// validateDiffMode 检查 diff 输出模式
+func validateDiffMode(mode string) error {
+	switch mode {
+	case "", "none", "append", "only":
+		return nil
+	}
+	return fmt.Errorf("无效的 diff 模式: %s (可选: none/append/only)", mode)
+}
+
+// collectChanges 获取项目目录内的变更，键为相对项目目录的路径
+func collectChanges(projectDir string) (map[string]*gitrepo.Change, error) {
+	repo, err := gitrepo.Open(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := repo.Changes(changeSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	absDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+
+	result := make(map[string]*gitrepo.Change)
+	for _, change := range changes {
+		rel, err := filepath.Rel(absDir, filepath.Join(repo.Root, filepath.FromSlash(change.Path)))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		result[filepath.ToSlash(rel)] = change
+	}
+
+	return result, nil
+}
+
+// changeFilter 只保留变更过的文件
+func changeFilter(changes map[string]*gitrepo.Change) func(string) bool {
+	return func(relPath string) bool {
+		_, ok := changes[relPath]
+		return ok
+	}
+}
+
+// applyChanges 为扫描结果附加变更状态与 diff，并以 diff 形式补充已删除的文件；
+// fsys 为读取内容的提交或暂存区快照，其中没有的变更文件（例如子模块）同样只输出 diff
+func applyChanges(projectDir string, fsys fs.FS, files []*scanner.FileInfo, changes map[string]*gitrepo.Change) []*scanner.FileInfo {
+	for _, file := range files {
+		if change, ok := changes[file.RelPath]; ok {
+			file.ChangeStatus = change.Status
+			file.Diff = change.Diff
+		}
+	}
+
+	for relPath, change := range changes {
+		if change.Status != "D" && !missingFrom(fsys, relPath) {
+			continue
+		}
+		files = append(files, &scanner.FileInfo{
+			Path:         filepath.Join(projectDir, filepath.FromSlash(relPath)),
+			RelPath:      relPath,
+			Language:     "diff",
+			IsCode:       true,
+			Encoding:     "UTF-8",
+			ChangeStatus: change.Status,
+			Diff:         change.Diff,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].RelPath < files[j].RelPath
+	})
+
+	return files
+}
+
+// missingFrom 快照中是否没有这个文件，fsys 为 nil（读取工作区）时总是 false
+func missingFrom(fsys fs.FS, relPath string) bool {
+	if fsys == nil {
+		return false
+	}
+	info, err := fs.Stat(fsys, relPath)
+	return err != nil || info.IsDir()
+}
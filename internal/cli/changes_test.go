@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"printcode2llm/internal/gitrepo"
+	"printcode2llm/internal/scanner"
+)
+
+func TestApplyChangesMissingFromSnapshot(t *testing.T) {
+	snapshot := fstest.MapFS{"a.go": &fstest.MapFile{Data: []byte("package a\n")}}
+	files := []*scanner.FileInfo{{RelPath: "a.go", Content: "package a\n"}}
+	changes := map[string]*gitrepo.Change{
+		"a.go":   {Path: "a.go", Status: "M", Diff: "diff a"},
+		"sub":    {Path: "sub", Status: "M", Diff: "diff sub"},
+		"old.go": {Path: "old.go", Status: "D", Diff: "diff old"},
+	}
+
+	got := applyChanges("/p", snapshot, files, changes)
+	if len(got) != 3 {
+		t.Fatalf("files = %+v", got)
+	}
+	for _, f := range got {
+		if f.Diff != changes[f.RelPath].Diff || f.ChangeStatus != changes[f.RelPath].Status {
+			t.Errorf("%s: status=%s diff=%q", f.RelPath, f.ChangeStatus, f.Diff)
+		}
+	}
+	if got[2].RelPath != "sub" || got[2].Content != "" || got[2].Language != "diff" {
+		t.Errorf("快照中没有的文件应只输出 diff: %+v", got[2])
+	}
+
+	// 读取工作区时只补充删除的文件
+	files = []*scanner.FileInfo{{RelPath: "a.go"}}
+	if got := applyChanges("/p", nil, files, changes); len(got) != 2 {
+		t.Errorf("工作区 files = %d", len(got))
+	}
+}
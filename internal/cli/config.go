@@ -6,6 +6,7 @@ import (
 
 	"printcode2llm/configs"
 	"printcode2llm/internal/config"
+	"printcode2llm/internal/parallel"
 	"printcode2llm/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -76,9 +77,19 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	ui.PrintInfo("输出设置:")
 	ui.PrintStep("字符限制: %s", ui.FormatNumber(cfg.Output.MaxChars))
+	if cfg.Output.MaxTokens > 0 {
+		ui.PrintStep("Token 限制: %s", ui.FormatNumber(cfg.Output.MaxTokens))
+	}
+	ui.PrintStep("分词器: %s", getTokenizerName(cfg))
 	ui.PrintStep("压缩: %v (超级: %v)", cfg.Output.Compress, cfg.Output.UltraCompress)
+	ui.PrintStep("骨架模式: %v", cfg.Output.Skeleton)
+	ui.PrintStep("行号: %v", cfg.Output.LineNumbers)
+	ui.PrintStep("文件上限: %s", describeFileLimits(cfg))
+	ui.PrintStep("敏感信息遮盖: %v (发现即失败: %v)", cfg.Redact.Enabled, cfg.Redact.FailOnSecrets)
 	ui.PrintStep("分割模式: %s", cfg.Output.SplitMode)
 	ui.PrintStep("输出前缀: %s", cfg.Output.OutputPrefix)
+	ui.PrintStep("输出格式: %s", cfg.Output.Format)
+	ui.PrintStep("并发数: %d", parallel.Jobs(cfg.Jobs))
 
 	fmt.Println()
 	ui.PrintInfo("规则统计:")
@@ -89,6 +100,26 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	if len(cfg.CustomIgnore.Patterns) > 0 {
 		ui.PrintStep("自定义模式: %d 个", len(cfg.CustomIgnore.Patterns))
 	}
+	if len(cfg.CustomIgnore.Paths) > 0 {
+		ui.PrintStep("排除路径: %d 个", len(cfg.CustomIgnore.Paths))
+	}
+	if len(cfg.Include.Patterns) > 0 {
+		ui.PrintStep("包含模式: %d 个 (目录树: %s)", len(cfg.Include.Patterns), cfg.Include.Tree)
+	}
+	if len(cfg.SourceEncoding) > 0 {
+		ui.PrintStep("编码规则: %d 条", len(cfg.SourceEncoding))
+	}
 
 	return nil
-}
\ No newline at end of file
+}
+// describeFileLimits 文件大小与行数上限的说明
+func describeFileLimits(cfg *config.Config) string {
+	size := "不限大小"
+	if cfg.MaxFileSize > 0 {
+		size = ui.FormatBytes(cfg.MaxFileSize)
+	}
+	if cfg.MaxFileLines <= 0 {
+		return size
+	}
+	return fmt.Sprintf("%s, %d 行 (超出按 %s 节选)", size, cfg.MaxFileLines, cfg.Truncate)
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/charset"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainDir     string
+	explainConfig  string
+	explainExclude string
+	explainOnly    string
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain 路径...",
+	Short: "说明文件或目录为什么会被整理或被排除",
+	Long: `说明文件或目录为什么会被整理或被排除
+
+按扫描时的顺序检查: 上级目录、默认忽略、自定义排除、正则、.gitignore、包含模式、
+二进制扩展名、文件大小与内容，输出命中的规则及其来源。
+
+示例:
+  ptlm explain internal/app/main.go
+  ptlm explain -d ./project dist/ assets/logo.svg
+  ptlm explain --only "cmd/" internal/ui/color.go`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringVarP(&explainDir, "dir", "d", ".", "项目目录")
+	explainCmd.Flags().StringVarP(&explainConfig, "config", "f", "", "配置文件路径")
+	explainCmd.Flags().StringVar(&explainExclude, "exclude", "", "排除模式(逗号分隔)")
+	explainCmd.Flags().StringVar(&explainOnly, "only", "", "只整理匹配的路径(逗号分隔，支持 **)")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadFor(explainConfig, []string{explainDir})
+	if err != nil {
+		ui.PrintWarning("配置加载失败: %v", err)
+		cfg = config.Default()
+	}
+	for _, p := range strings.Split(explainExclude, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.CustomIgnore.Patterns = append(cfg.CustomIgnore.Patterns, p)
+		}
+	}
+	for _, p := range strings.Split(explainOnly, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.Include.Patterns = append(cfg.Include.Patterns, p)
+		}
+	}
+
+	ui.PrintHeader("路径说明")
+	ui.PrintInfo("项目目录: %s", explainDir)
+	ui.PrintBlank()
+
+	failed := 0
+	for _, arg := range args {
+		rel, err := projectRelPath(explainDir, arg)
+		if err == nil {
+			var exp *scanner.Explanation
+			if exp, err = scanner.Explain(explainDir, rel, cfg); err == nil {
+				printExplanation(exp, cfg)
+				continue
+			}
+		}
+		ui.PrintError("%s: %v", arg, err)
+		failed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个路径无法检查", failed)
+	}
+	return nil
+}
+
+// projectRelPath 把命令行中的路径（相对当前目录）转换为相对项目目录的路径
+func projectRelPath(projectDir, arg string) (string, error) {
+	absDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("不在项目目录 %s 中", projectDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func printExplanation(exp *scanner.Explanation, cfg *config.Config) {
+	name := exp.RelPath
+	if exp.IsDir {
+		name += "/"
+	}
+
+	if e := exp.Exclusion; e != nil {
+		ui.PrintWarning("%s: 不会被整理", name)
+		if e.RelPath != exp.RelPath {
+			ui.PrintStep("上级目录 %s/ 被排除", e.RelPath)
+		}
+		ui.PrintStep("原因: %s", e.Reason)
+		switch {
+		case e.Rule != "":
+			ui.PrintStep("规则: %s", e.Rule)
+		case e.Reason == scanner.ExcludeNotIncluded:
+			ui.PrintStep("规则: 不匹配任何包含模式 (%s)", strings.Join(cfg.Include.Patterns, ", "))
+		}
+		if e.Source != "" {
+			ui.PrintStep("来源: %s", e.Source)
+		}
+		ui.PrintBlank()
+		return
+	}
+
+	if exp.IsDir {
+		ui.PrintSuccess("%s: 会被遍历，其中的文件逐个按规则检查", name)
+		ui.PrintBlank()
+		return
+	}
+
+	file := exp.File
+	kind := "代码"
+	if !file.IsCode {
+		kind = "配置/文档"
+	}
+	ui.PrintSuccess("%s: 会被整理", name)
+	if file.LanguageSource != "" && file.LanguageSource != scanner.LanguageByExtension {
+		kind += "，识别方式: " + file.LanguageSource
+	}
+	ui.PrintStep("类型: %s (%s)", file.Language, kind)
+	encoding := file.Encoding
+	switch encoding {
+	case charset.UTF8:
+	case charset.UTF8BOM:
+		encoding += " (已去除 BOM)"
+	default:
+		encoding += " (已转换为 UTF-8)"
+	}
+	ui.PrintStep("大小: %s，%s 行，编码 %s", ui.FormatBytes(file.Size), ui.FormatNumber(file.LineCount), encoding)
+	if cfg.Output.Skeleton && file.IsCode && !scanner.MatchKeep(cfg.Output.SkeletonKeep, file.RelPath) {
+		ui.PrintStep("骨架模式: 只保留声明与签名")
+	} else if cfg.MaxFileLines > 0 && file.LineCount > cfg.MaxFileLines {
+		ui.PrintStep("超过 max_file_lines (%d 行)，按 %s 输出节选", cfg.MaxFileLines, cfg.Truncate)
+	}
+	ui.PrintBlank()
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/mcp"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var mcpConfig string
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp [项目目录]",
+	Short: "以 stdio 方式启动 MCP 服务，供智能体读取项目上下文",
+	Long: `以 stdio 方式启动 MCP (Model Context Protocol) 服务
+
+提供的工具:
+  list_files            列出会被整理的文件
+  project_tree          目录树
+  read_project_context  按预算分段后的第 N 段内容
+  file_outline          单个文件的声明与签名
+
+在智能体的 MCP 配置中添加:
+  {"command": "ptlm", "args": ["mcp", "/path/to/project"]}`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.Flags().StringVarP(&mcpConfig, "config", "f", "", "配置文件路径")
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	// 标准输出只用于协议消息
+	ui.SetOutput(os.Stderr)
+
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("目录不存在: %s", root)
+	}
+
+	cfg, err := config.LoadFor(mcpConfig, []string{root})
+	if err != nil {
+		ui.PrintWarning("配置加载失败: %v", err)
+		cfg = config.Default()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := mcp.New(mcp.Options{
+		Root:       root,
+		ConfigPath: mcpConfig,
+		Cache:      openCache(cfg),
+	})
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
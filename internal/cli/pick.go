@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/picker"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// interactive 生成前打开交互选择器
+var interactive bool
+
+// errPickCancelled 在选择器中取消，不再继续生成
+var errPickCancelled = errors.New("已取消选择")
+
+var pickCmd = &cobra.Command{
+	Use:   "pick [项目目录...]",
+	Short: "在终端中勾选要整理的文件后生成",
+	Long: `在终端中勾选要整理的文件后生成，等同于 ptlm -i
+
+扫描后以目录树显示各文件的大小与估算的 token 数，底部显示已选内容的 token 总数与分段数。
+
+按键:
+  ↑↓ / j k        移动
+  空格            选中或取消当前文件、目录
+  → / l           展开目录
+  ← / h           折叠目录或回到上级
+  a               全选或全部取消
+  s               把未选中的路径保存到配置中的 custom_ignore.paths
+  回车            按当前选择生成
+  q / Esc         取消
+
+其余参数与 ptlm 相同，例如:
+  ptlm pick .
+  ptlm pick -t 30000 --skeleton ./project`,
+	DisableFlagParsing: true,
+	RunE:               runPick,
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+}
+
+// runPick 用根命令的参数解析，再按交互模式执行
+func runPick(cmd *cobra.Command, args []string) error {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return cmd.Help()
+		}
+	}
+	if err := rootCmd.ParseFlags(args); err != nil {
+		return err
+	}
+	interactive = true
+	return runMain(rootCmd, rootCmd.Flags().Args())
+}
+
+// pickFiles 估算各文件的 token 数并打开选择器，返回选中的文件，以及追加了未选路径的配置，
+// 使目录树与文件清单一致
+func pickFiles(projectDir string, files []*scanner.FileInfo, cfg *config.Config, fc *cache.Cache) ([]*scanner.FileInfo, *config.Config, error) {
+	if len(files) == 0 {
+		return files, cfg, nil
+	}
+
+	ui.PrintStep("估算 token 数...")
+	estimates, err := generator.EstimateFiles(files, cfg, generator.Options{Cache: fc})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]picker.Item, len(files))
+	for i, file := range files {
+		items[i] = picker.Item{
+			Path:   filepath.ToSlash(file.RelPath),
+			Size:   file.Size,
+			Chars:  estimates[i].Chars,
+			Tokens: estimates[i].Tokens,
+		}
+	}
+
+	result, err := picker.Run(items, picker.Options{
+		Title: "选择要整理的文件: " + projectDir,
+		Parts: func(chars, tokens int) int {
+			return generator.EstimateParts(chars, tokens, cfg)
+		},
+		Save: func(excluded []string) (string, error) {
+			target := pickConfigFile(projectDir)
+			added, err := config.AppendIgnorePaths(target, excluded)
+			if err != nil {
+				return "", err
+			}
+			if added == 0 {
+				return fmt.Sprintf("%s 中已有这些排除规则", target), nil
+			}
+			return fmt.Sprintf("已向 %s 添加 %d 条排除规则", target, added), nil
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Cancelled {
+		return nil, nil, errPickCancelled
+	}
+
+	selected := make(map[string]bool, len(result.Selected))
+	for _, p := range result.Selected {
+		selected[p] = true
+	}
+	picked := make([]*scanner.FileInfo, 0, len(result.Selected))
+	for _, file := range files {
+		if selected[filepath.ToSlash(file.RelPath)] {
+			picked = append(picked, file)
+		}
+	}
+	ui.PrintSuccess("已选择 %d/%d 个文件", len(picked), len(files))
+
+	if len(result.Excluded) == 0 {
+		return picked, cfg, nil
+	}
+	copied := *cfg
+	copied.CustomIgnore.Paths = append(append([]string{}, cfg.CustomIgnore.Paths...), result.Excluded...)
+	return picked, &copied, nil
+}
+
+// pickConfigFile 保存规则的配置文件：当前生效的配置文件，没有时在项目目录下新建 .ptlm.yaml
+func pickConfigFile(projectDir string) string {
+	if path := config.FileFor(configPath, projectDirs); path != "" {
+		return path
+	}
+	if info, err := os.Stat(projectDir); err == nil && info.IsDir() {
+		return filepath.Join(projectDir, ".ptlm.yaml")
+	}
+	return ".ptlm.yaml"
+}
@@ -1,14 +1,21 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
 	"strings"
 
+	"printcode2llm/internal/cache"
 	"printcode2llm/internal/config"
 	"printcode2llm/internal/generator"
+	"printcode2llm/internal/gitrepo"
 	"printcode2llm/internal/output"
+	"printcode2llm/internal/redact"
 	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/tokenizer"
 	"printcode2llm/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -19,12 +26,29 @@ var (
 	projectDirs     []string
 	outputPrefix    string
 	maxChars        int
+	maxTokens       int
+	tokenizerName   string
 	compress        bool
 	ultraCompress   bool
+	skeletonMode    bool
+	keepFull        string
+	redactSecrets   bool
+	failOnSecrets   bool
+	jobs            int
+	useCache        bool
 	splitMode       string
+	outputFormat    string
+	partDelimiter   string
+	onlyPart        int
 	includeTree     bool
 	excludePatterns string
 	regexPatterns   string
+	onlyPatterns    string
+	maxFileSize     string
+	maxFileLines    int
+	truncateMode    string
+	reportExcluded  bool
+	lineNumbers     bool
 	configPath      string
 )
 
@@ -40,13 +64,34 @@ var rootCmd = &cobra.Command{
   ptlm ./project             整理指定目录
   ptlm ./p1 ./p2             整理多个项目
   ptlm -c 80000 .            限制每段字符数
+  ptlm -t 30000 .            限制每段 token 数
   ptlm -u .                  超级压缩模式
+  ptlm --skeleton --keep-full "cmd/*" .  骨架模式，cmd 下保留全文
+  ptlm --format xml .        输出 XML (另有 json/jsonl)
+  ptlm -o - . | pbcopy       输出到标准输出
+  ptlm -o - --part 2 .       只输出第 2 部分
+  ptlm --since main .        只整理相对 main 的变更
+  ptlm --staged --diff append .  已暂存的变更并附带 diff
+  ptlm repo.zip              整理压缩包(zip/tar/tar.gz)
+  ptlm --rev v1.2.0 .        整理指定 git 版本的内容
+  ptlm --only "cmd/,internal/scanner" .  只整理指定的目录
+  ptlm -i .                  先在终端中勾选文件再生成
+  ptlm --max-file-lines 500 .  超过 500 行的文件只保留开头与结尾
+  ptlm --line-numbers .      每行开头标注原文件中的行号
 
 管理命令:
+  ptlm unpack                从生成的文档还原文件
+  ptlm apply reply.md        将模型回复写回项目
   ptlm config init           生成配置文件
+  ptlm pick                  交互选择文件后生成
+  ptlm explain <路径>        查看文件为什么被包含或排除
+  ptlm serve                 启动本地 HTTP 接口
+  ptlm mcp                   启动 MCP 服务(stdio)
+  ptlm ask "问题" .          把代码和问题发送给大模型接口
   ptlm install               安装到系统
   ptlm uninstall             卸载
   ptlm version               查看版本`,
+	Args:          cobra.ArbitraryArgs,
 	RunE:          runMain,
 	SilenceErrors: true,
 	SilenceUsage:  true,
@@ -54,15 +99,33 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	rootCmd.Flags().StringSliceVarP(&projectDirs, "dir", "d", []string{}, "项目目录")
-	rootCmd.Flags().StringVarP(&outputPrefix, "output", "o", "", "输出文件前缀")
+	rootCmd.Flags().StringVarP(&outputPrefix, "output", "o", "", "输出文件前缀，- 表示输出到标准输出")
 	rootCmd.Flags().IntVarP(&maxChars, "chars", "c", 0, "每段最大字符数")
+	rootCmd.Flags().IntVarP(&maxTokens, "tokens", "t", 0, "每段最大 token 数(优先于字符数)；未指定词表时为估算值")
+	rootCmd.Flags().StringVar(&tokenizerName, "tokenizer", "", "分词器: heuristic/cl100k_base/o200k_base")
 	rootCmd.Flags().BoolVar(&compress, "compress", true, "压缩代码")
 	rootCmd.Flags().BoolVarP(&ultraCompress, "ultra-compress", "u", false, "超级压缩")
-	rootCmd.Flags().StringVarP(&splitMode, "split-mode", "s", "", "分割模式: char/file")
+	rootCmd.Flags().BoolVar(&skeletonMode, "skeleton", false, "骨架模式: 只保留声明、签名与文档注释")
+	rootCmd.Flags().StringVar(&keepFull, "keep-full", "", "骨架模式下保留全文的文件模式(逗号分隔)")
+	rootCmd.Flags().BoolVar(&redactSecrets, "redact", true, "遮盖密钥等敏感信息")
+	rootCmd.Flags().BoolVar(&failOnSecrets, "fail-on-secrets", false, "发现敏感信息时以错误退出(用于 CI)")
+	rootCmd.Flags().StringVarP(&splitMode, "split-mode", "s", "", "分割模式: char/file/balanced")
+	rootCmd.Flags().StringVar(&outputFormat, "format", "", "输出格式: markdown/xml/json/jsonl")
+	rootCmd.Flags().StringVar(&partDelimiter, "delimiter", "", "输出到标准输出时各部分之间的分隔符，支持 \\n 等转义")
+	rootCmd.Flags().IntVar(&onlyPart, "part", 0, "只输出第 N 部分")
 	rootCmd.Flags().BoolVar(&includeTree, "tree", true, "包含目录树")
 	rootCmd.Flags().StringVar(&excludePatterns, "exclude", "", "排除模式(逗号分隔)")
 	rootCmd.Flags().StringVar(&regexPatterns, "regex", "", "正则排除(逗号分隔)")
+	rootCmd.Flags().StringVar(&onlyPatterns, "only", "", "只整理匹配的路径(逗号分隔，支持 **)")
+	rootCmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "单个文件大小上限，如 512KB、2MB，-1 表示不限制")
+	rootCmd.Flags().IntVar(&maxFileLines, "max-file-lines", 0, "超过此行数的文件只输出节选")
+	rootCmd.Flags().StringVar(&truncateMode, "truncate", "", "节选方式: head/head_tail/skeleton")
+	rootCmd.Flags().BoolVar(&reportExcluded, "report-excluded", false, "在文档末尾列出被排除的路径及原因")
+	rootCmd.Flags().BoolVar(&lineNumbers, "line-numbers", false, "每行开头标注原文件中的行号(压缩与拆分后仍对应原文件)")
 	rootCmd.Flags().StringVarP(&configPath, "config", "f", "", "配置文件路径")
+	rootCmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "读取与压缩文件的并发数(默认 CPU 核心数)")
+	rootCmd.Flags().BoolVar(&useCache, "cache", true, "使用磁盘缓存，未变化的文件跳过检测、压缩与 token 计数")
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "生成前在终端中勾选文件")
 }
 
 func Execute() error {
@@ -77,6 +140,10 @@ func runMain(cmd *cobra.Command, args []string) error {
 		projectDirs = append(projectDirs, args...)
 	}
 
+	if len(projectDirs) == 0 && changeSpec.IsSet() {
+		projectDirs = []string{"."}
+	}
+
 	if len(projectDirs) == 0 {
 		ui.PrintWarning("未指定项目目录")
 		ui.PrintInfo("使用示例:")
@@ -100,9 +167,21 @@ func runMain(cmd *cobra.Command, args []string) error {
 	if outputPrefix != "" {
 		cfg.Output.OutputPrefix = outputPrefix
 	}
+	if cfg.Output.OutputPrefix == output.Stdout {
+		ui.SetOutput(os.Stderr)
+	}
+	if cmd.Flags().Changed("delimiter") {
+		cfg.Output.PartDelimiter = unescapeDelimiter(partDelimiter)
+	}
 	if maxChars > 0 {
 		cfg.Output.MaxChars = maxChars
 	}
+	if maxTokens > 0 {
+		cfg.Output.MaxTokens = maxTokens
+	}
+	if tokenizerName != "" {
+		cfg.Output.Tokenizer = tokenizerName
+	}
 	if cmd.Flags().Changed("compress") {
 		cfg.Output.Compress = compress
 	}
@@ -110,12 +189,60 @@ func runMain(cmd *cobra.Command, args []string) error {
 		cfg.Output.UltraCompress = true
 		cfg.Output.Compress = true
 	}
+	if cmd.Flags().Changed("skeleton") {
+		cfg.Output.Skeleton = skeletonMode
+	}
+	if keepFull != "" {
+		for _, p := range strings.Split(keepFull, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				cfg.Output.SkeletonKeep = append(cfg.Output.SkeletonKeep, p)
+			}
+		}
+	}
+	if cmd.Flags().Changed("redact") {
+		cfg.Redact.Enabled = redactSecrets
+	}
+	if failOnSecrets {
+		cfg.Redact.FailOnSecrets = true
+	}
 	if splitMode != "" {
 		cfg.Output.SplitMode = splitMode
 	}
+	if outputFormat != "" {
+		cfg.Output.Format = outputFormat
+	}
+	if err := generator.ValidateFormat(cfg.Output.Format); err != nil {
+		return err
+	}
 	if cmd.Flags().Changed("tree") {
 		cfg.Output.IncludeTree = includeTree
 	}
+	if cmd.Flags().Changed("report-excluded") {
+		cfg.Output.ReportExcluded = reportExcluded
+	}
+	if cmd.Flags().Changed("line-numbers") {
+		cfg.Output.LineNumbers = lineNumbers
+	}
+	if jobs > 0 {
+		cfg.Jobs = jobs
+	}
+	if cmd.Flags().Changed("cache") {
+		cfg.Cache = useCache
+	}
+
+	if err := changeSpec.Validate(); err != nil {
+		return err
+	}
+	if err := validateSource(); err != nil {
+		return err
+	}
+	if err := validateDiffMode(diffMode); err != nil {
+		return err
+	}
+	if diffMode != "" {
+		cfg.Output.DiffMode = diffMode
+	}
 
 	if excludePatterns != "" {
 		patterns := strings.Split(excludePatterns, ",")
@@ -137,16 +264,126 @@ func runMain(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if onlyPatterns != "" {
+		for _, p := range strings.Split(onlyPatterns, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				cfg.Include.Patterns = append(cfg.Include.Patterns, p)
+			}
+		}
+	}
+	if err := scanner.ValidateIncludeTree(cfg.Include.Tree); err != nil {
+		return err
+	}
+	if err := scanner.ValidateSourceEncoding(cfg.SourceEncoding); err != nil {
+		return err
+	}
+
+	if maxFileSize != "" {
+		size, err := parseSize(maxFileSize)
+		if err != nil {
+			return err
+		}
+		cfg.MaxFileSize = size
+	}
+	if maxFileLines > 0 {
+		cfg.MaxFileLines = maxFileLines
+	}
+	if truncateMode != "" {
+		cfg.Truncate = truncateMode
+	}
+	if err := generator.ValidateTruncate(cfg.Truncate); err != nil {
+		return err
+	}
+
 	ui.PrintHeader("PrintCode2LLM")
 	ui.PrintInfo("项目数量: %d", len(projectDirs))
-	ui.PrintInfo("字符限制: %s", ui.FormatNumber(cfg.Output.MaxChars))
+	if cfg.Output.MaxTokens > 0 {
+		ui.PrintInfo("Token 限制: %s (%s)", ui.FormatNumber(cfg.Output.MaxTokens), getTokenizerName(cfg))
+	} else {
+		ui.PrintInfo("字符限制: %s", ui.FormatNumber(cfg.Output.MaxChars))
+	}
 	ui.PrintInfo("压缩模式: %s", getCompressMode(cfg))
-	fmt.Println()
+	if cfg.Output.Skeleton {
+		if len(cfg.Output.SkeletonKeep) > 0 {
+			ui.PrintInfo("骨架模式: 开启 (保留全文: %s)", strings.Join(cfg.Output.SkeletonKeep, ", "))
+		} else {
+			ui.PrintInfo("骨架模式: 开启")
+		}
+	}
+	if cfg.Output.Format != "" && cfg.Output.Format != generator.FormatMarkdown {
+		ui.PrintInfo("输出格式: %s", cfg.Output.Format)
+	}
+	if changeSpec.IsSet() {
+		ui.PrintInfo("变更范围: %s", changeSpec.Describe())
+	}
+	if len(cfg.Include.Patterns) > 0 {
+		ui.PrintInfo("包含范围: %s", strings.Join(cfg.Include.Patterns, ", "))
+	}
+	if cfg.MaxFileLines > 0 {
+		ui.PrintInfo("行数上限: %d (%s)", cfg.MaxFileLines, cfg.Truncate)
+	}
+	ui.PrintBlank()
+
+	if cfg.Output.OutputPrefix != output.Stdout && onlyPart == 0 {
+		if err := output.CleanOldFiles(cfg.Output.OutputPrefix, generator.FormatExtension(cfg.Output.Format)); err != nil {
+			ui.PrintWarning("清理旧文件失败: %v", err)
+		}
+	}
+
+	allResults, err := generateProjects(cfg, projectDirs)
+	if errors.Is(err, errPickCancelled) {
+		ui.PrintInfo("已取消")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(allResults) == 0 {
+		ui.PrintWarning("没有成功处理任何项目")
+		return nil
+	}
+
+	ui.PrintSection("写入文件")
+	totalSize, err := output.WriteResults(allResults, cfg, output.Options{Part: onlyPart})
+	if err != nil {
+		return fmt.Errorf("写入失败: %w", err)
+	}
 
-	if err := output.CleanOldFiles(cfg.Output.OutputPrefix); err != nil {
-		ui.PrintWarning("清理旧文件失败: %v", err)
+	ui.PrintBlank()
+	ui.PrintSuccess("完成!")
+	ui.PrintInfo("总大小: %s", ui.FormatBytes(totalSize))
+
+	totalSegments := 0
+	for _, r := range allResults {
+		totalSegments += len(r.Segments)
+	}
+	if totalSegments > 1 && onlyPart == 0 {
+		ui.PrintBlank()
+		if cfg.Output.OutputPrefix == output.Stdout {
+			ui.PrintInfo("共 %d 个部分，请按顺序发送给大模型", totalSegments)
+		} else {
+			ui.PrintInfo("共 %d 个文件，请按顺序发送给大模型", totalSegments)
+		}
 	}
 
+	return nil
+}
+
+// generateProjects 依次扫描并生成各项目，单个项目失败时提示后跳过；
+// 开启 fail_on_secrets 且发现敏感信息时返回错误
+func generateProjects(cfg *config.Config, projectDirs []string) ([]*generator.Result, error) {
+	var redactor *redact.Redactor
+	if cfg.Redact.Enabled || cfg.Redact.FailOnSecrets {
+		var err error
+		if redactor, err = redact.New(cfg); err != nil {
+			return nil, err
+		}
+	}
+	secretCount := 0
+
+	fileCache := openCache(cfg)
+
 	allResults := make([]*generator.Result, 0)
 
 	for _, projectDir := range projectDirs {
@@ -157,53 +394,142 @@ func runMain(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
+		src, err := openSource(projectDir, cfg)
+		if err != nil {
+			ui.PrintError("读取失败: %v", err)
+			continue
+		}
+
+		var skipped []scanner.SkippedFile
+		var excluded []scanner.Exclusion
+		scanOpts := scanner.Options{
+			Cache:  fileCache,
+			OnSkip: func(s scanner.SkippedFile) { skipped = append(skipped, s) },
+		}
+		if cfg.Output.ReportExcluded {
+			scanOpts.OnExclude = func(e scanner.Exclusion) { excluded = append(excluded, e) }
+		}
+		var changes map[string]*gitrepo.Change
+		if changeSpec.IsSet() {
+			ui.PrintStep("读取 git 变更...")
+			changes, err = collectChanges(projectDir)
+			if err != nil {
+				ui.PrintError("读取变更失败: %v", err)
+				continue
+			}
+			ui.PrintSuccess("%s: %d 个文件", changeSpec.Describe(), len(changes))
+			scanOpts.Filter = changeFilter(changes)
+		}
+
 		ui.PrintStep("扫描文件...")
-		files, err := scanner.ScanDirectory(projectDir, cfg)
+		var files []*scanner.FileInfo
+		if src != nil {
+			files, err = scanner.ScanFS(src.FS, src.Root, cfg, scanOpts)
+		} else {
+			files, err = scanner.ScanDirectoryWithOptions(projectDir, cfg, scanOpts)
+		}
 		if err != nil {
 			ui.PrintError("扫描失败: %v", err)
 			continue
 		}
+		if changes != nil {
+			var snapshot fs.FS
+			if src != nil {
+				snapshot = src.FS
+			}
+			files = applyChanges(projectDir, snapshot, files, changes)
+		}
 		ui.PrintSuccess("找到 %d 个文件", len(files))
+		if len(skipped) > 0 {
+			ui.PrintWarning("跳过 %d 个过大或无法读取的文件，已列在文档开头", len(skipped))
+		}
+		if len(excluded) > 0 {
+			ui.PrintStep("排除 %d 个路径，已列在文档末尾", len(excluded))
+		}
+
+		if redactor != nil {
+			found := printRedactReports(redactor.Files(files))
+			secretCount += found
+			if found > 0 && cfg.Redact.FailOnSecrets {
+				ui.PrintBlank()
+				continue
+			}
+		}
+
+		projectCfg := cfg
+		if interactive {
+			files, projectCfg, err = pickFiles(projectDir, files, cfg, fileCache)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		ui.PrintStep("生成内容...")
-		result, err := generator.Generate(projectDir, files, cfg)
+		genOpts := generator.Options{Cache: fileCache, Skipped: skipped, Excluded: excluded}
+		projectPath := projectDir
+		if src != nil {
+			genOpts.FS = src.FS
+			projectPath = src.Root
+		}
+		result, err := generator.GenerateWithOptions(projectPath, files, projectCfg, genOpts)
 		if err != nil {
 			ui.PrintError("生成失败: %v", err)
 			continue
 		}
 
 		allResults = append(allResults, result)
+		for _, warning := range result.Warnings {
+			ui.PrintWarning("%s", warning)
+		}
 		ui.PrintSuccess("生成 %d 个分段", len(result.Segments))
-		fmt.Println()
+		ui.PrintBlank()
 	}
 
-	if len(allResults) == 0 {
-		ui.PrintWarning("没有成功处理任何项目")
-		return nil
+	if cfg.Redact.FailOnSecrets && secretCount > 0 {
+		return nil, fmt.Errorf("发现 %d 处疑似敏感信息，未生成文档", secretCount)
 	}
 
-	ui.PrintSection("写入文件")
-	totalSize, err := output.WriteResults(allResults, cfg)
-	if err != nil {
-		return fmt.Errorf("写入失败: %w", err)
+	if lookups := fileCache.Hits() + fileCache.Misses(); lookups > 0 {
+		ui.PrintInfo("缓存命中 %d/%d", fileCache.Hits(), lookups)
+		ui.PrintBlank()
 	}
 
-	fmt.Println()
-	ui.PrintSuccess("完成!")
-	ui.PrintInfo("总大小: %s", ui.FormatBytes(totalSize))
+	return allResults, nil
+}
 
-	totalSegments := 0
-	for _, r := range allResults {
-		totalSegments += len(r.Segments)
+// openCache 打开磁盘缓存，未启用或无法使用时返回 nil
+func openCache(cfg *config.Config) *cache.Cache {
+	if !cfg.Cache {
+		return nil
 	}
-	if totalSegments > 1 {
-		fmt.Println()
-		ui.PrintInfo("共 %d 个文件，请按顺序发送给大模型", totalSegments)
+	dir, err := cache.Dir()
+	if err == nil {
+		var fc *cache.Cache
+		if fc, err = cache.Open(dir); err == nil {
+			return fc
+		}
 	}
-
+	ui.PrintWarning("缓存不可用，本次不使用缓存: %v", err)
 	return nil
 }
 
+// printRedactReports 输出各文件的遮盖情况，返回遮盖的总数
+func printRedactReports(reports []*redact.Report) int {
+	total := 0
+	for _, report := range reports {
+		total += len(report.Findings)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	ui.PrintWarning("遮盖 %d 处疑似敏感信息 (%d 个文件):", total, len(reports))
+	for _, report := range reports {
+		ui.PrintStep("%s: %s", report.Path, report.Summary())
+	}
+	return total
+}
+
 func getCompressMode(cfg *config.Config) string {
 	if !cfg.Output.Compress {
 		return "不压缩"
@@ -212,4 +538,64 @@ func getCompressMode(cfg *config.Config) string {
 		return "超级压缩"
 	}
 	return "标准压缩"
-}
\ No newline at end of file
+}
+
+// getTokenizerName 分词器名称；heuristic 没有词表，注明 token 数只是估算
+func getTokenizerName(cfg *config.Config) string {
+	if cfg.Output.Tokenizer == "" || cfg.Output.Tokenizer == tokenizer.Heuristic {
+		return tokenizer.Heuristic + "，按字符估算"
+	}
+	return cfg.Output.Tokenizer
+}
+
+// StdoutRequested 命令行是否指定了 -o -；横幅在解析参数之前输出，需要提前判断
+func StdoutRequested(args []string) bool {
+	// mcp 的标准输出只用于协议消息，ask 的标准输出只有回答
+	if len(args) > 0 && (args[0] == "mcp" || args[0] == "ask") {
+		return true
+	}
+	for i, arg := range args {
+		switch arg {
+		case "-o", "--output":
+			if i+1 < len(args) && args[i+1] == output.Stdout {
+				return true
+			}
+		case "-o-", "-o=-", "--output=-":
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeDelimiter 解析分隔符中的 \n、\t 等转义
+func unescapeDelimiter(s string) string {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// parseSize 解析 512KB、2MB 形式的大小，不带单位时按字节，-1 表示不限制
+func parseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	if text == "-1" {
+		return -1, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if rest, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, multiplier = strings.TrimSpace(rest), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的文件大小: %s (例如 512KB、2MB，-1 表示不限制)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
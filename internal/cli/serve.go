@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/server"
+	"printcode2llm/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr   string
+	serveRoots  []string
+	serveConfig string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动本地 HTTP 接口，供编辑器插件等工具调用",
+	Long: `启动本地 HTTP 接口，供编辑器插件等工具调用
+
+接口:
+  GET  /api/health           版本信息与允许访问的目录
+  GET  /api/files?dir=...    扫描目录，返回文件列表
+  POST /api/generate         生成分段，{"dir": "...", "as": "json|markdown"}
+
+默认只监听 127.0.0.1，只允许访问 --root 指定的目录(默认为当前目录)。
+
+示例:
+  ptlm serve
+  ptlm serve --addr 127.0.0.1:9000 --root ~/work --root ~/oss`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "", "监听地址(默认 127.0.0.1:7878)")
+	serveCmd.Flags().StringArrayVar(&serveRoots, "root", nil, "允许访问的目录，可重复指定")
+	serveCmd.Flags().StringVarP(&serveConfig, "config", "f", "", "配置文件路径，所有请求都使用该配置")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadFor(serveConfig, nil)
+	if err != nil {
+		ui.PrintWarning("配置加载失败: %v", err)
+		cfg = config.Default()
+	}
+
+	addr := cfg.Serve.Addr
+	if serveAddr != "" {
+		addr = serveAddr
+	}
+	roots := append(cfg.Serve.Roots, serveRoots...)
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	srv, err := server.New(server.Options{
+		ConfigPath:  serveConfig,
+		Roots:       roots,
+		Cache:       openCache(cfg),
+		AllowRemote: !server.IsLoopbackAddr(addr),
+	})
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", addr, err)
+	}
+
+	ui.PrintHeader("ptlm serve")
+	ui.PrintInfo("地址: http://%s", listener.Addr())
+	for _, root := range srv.Roots() {
+		ui.PrintInfo("允许访问: %s", root)
+	}
+	if !server.IsLoopbackAddr(addr) {
+		ui.PrintWarning("%s 不是本机地址，局域网内的其他机器也可以读取上述目录中的代码", addr)
+	}
+	ui.PrintInfo("按 Ctrl+C 停止")
+
+	httpServer := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	ui.PrintSuccess("已停止")
+	return nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/source"
+	"printcode2llm/internal/ui"
+)
+
+var revision string
+
+func init() {
+	rootCmd.Flags().StringVar(&revision, "rev", "", "整理指定 git 版本(分支/标签/提交)的内容，不修改工作区")
+}
+
+// validateSource 检查 --rev 与变更范围是否冲突
+func validateSource() error {
+	if revision != "" && changeSpec.IsSet() {
+		return fmt.Errorf("--rev 不能与 --since、--staged、--uncommitted、--commit 同时使用")
+	}
+	return nil
+}
+
+// openSource 打开非本地目录的输入：指定 --rev 时读取该版本，参数是压缩包时读取压缩包，
+// --commit、--staged 读取该提交或暂存区中的内容；其余情况返回 nil。
+// 超过 max_file_size 的文件不载入内容，与扫描目录时一样跳过
+func openSource(projectDir string, cfg *config.Config) (*source.Source, error) {
+	if revision != "" {
+		ui.PrintStep("读取版本 %s...", revision)
+		return source.OpenRevision(projectDir, revision, cfg.MaxFileSize)
+	}
+	if source.IsArchive(projectDir) {
+		if changeSpec.IsSet() {
+			return nil, fmt.Errorf("压缩包不支持 --since、--staged、--uncommitted、--commit")
+		}
+		ui.PrintStep("读取压缩包...")
+		return source.OpenArchive(projectDir, cfg.MaxFileSize)
+	}
+	if changeSpec.Commit != "" || changeSpec.Staged {
+		return source.OpenSnapshot(projectDir, changeSpec, cfg.MaxFileSize)
+	}
+	return nil, nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"printcode2llm/internal/ui"
+	"printcode2llm/internal/unpack"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	unpackOutDir string
+	unpackVerify string
+	unpackForce  bool
+)
+
+var unpackCmd = &cobra.Command{
+	Use:   "unpack [文档...]",
+	Short: "从生成的 Markdown 文档还原文件",
+	Long: `从 ptlm 生成的 Markdown 文档还原项目文件
+
+按 "### N. 路径" 标题与代码块解析，自动拼接 "(续: 行 a-b)" 分段。
+未指定文档时读取当前目录下的 LLM_CODE*.md。
+
+示例:
+  ptlm unpack -d restored LLM_CODE_Part*.md
+  ptlm unpack --verify ./project    校验还原结果与源目录是否一致`,
+	RunE: runUnpack,
+}
+
+func init() {
+	rootCmd.AddCommand(unpackCmd)
+	unpackCmd.Flags().StringVarP(&unpackOutDir, "dir", "d", "unpacked", "还原到的目录")
+	unpackCmd.Flags().StringVar(&unpackVerify, "verify", "", "与指定源目录比对，不写入文件")
+	unpackCmd.Flags().BoolVar(&unpackForce, "force", false, "覆盖已存在的文件")
+}
+
+var partNumPattern = regexp.MustCompile(`_Part(\d+)_of_\d+`)
+
+func runUnpack(cmd *cobra.Command, args []string) error {
+	paths := args
+	if len(paths) == 0 {
+		matches, err := filepath.Glob("LLM_CODE*.md")
+		if err != nil {
+			return err
+		}
+		paths = matches
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("未找到要还原的文档")
+	}
+
+	// 按分段序号排序，未带序号的文件保持原顺序
+	sort.SliceStable(paths, func(i, j int) bool {
+		return partNumber(paths[i]) < partNumber(paths[j])
+	})
+
+	ui.PrintHeader("还原文件")
+
+	var docs []*unpack.Document
+	for _, path := range paths {
+		doc, err := unpack.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		ui.PrintStep("%s: %d 个片段", path, len(doc.Chunks))
+		docs = append(docs, doc)
+	}
+
+	files, warnings, err := unpack.Assemble(docs)
+	for _, warning := range warnings {
+		ui.PrintWarning("%s", warning)
+	}
+	if err != nil {
+		return err
+	}
+
+	if unpackVerify != "" {
+		mismatches, err := unpack.Verify(files, unpackVerify)
+		if err != nil {
+			return err
+		}
+		for _, m := range mismatches {
+			ui.PrintError("%s: %s", m.Path, m.Reason)
+		}
+		if len(mismatches) > 0 {
+			return fmt.Errorf("%d/%d 个文件与源目录不一致", len(mismatches), len(files))
+		}
+		fmt.Println()
+		ui.PrintSuccess("%d 个文件与 %s 完全一致", len(files), unpackVerify)
+		return nil
+	}
+
+	if err := unpack.Write(files, unpackOutDir, unpackForce); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	ui.PrintSuccess("已还原 %d 个文件到 %s", len(files), unpackOutDir)
+	return nil
+}
+
+func partNumber(path string) int {
+	m := partNumPattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
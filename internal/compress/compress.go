@@ -49,15 +49,50 @@ var pythonStyleCommentLanguages = map[string]bool{
 	"bash":   true,
 }
 
+// 正则只编译一次，压缩在多个 goroutine 中并行执行时共用
+var (
+	blockCommentPattern = regexp.MustCompile(`/\*[\s\S]*?\*/`)
+	blankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+	newlinesPattern     = regexp.MustCompile(`\n+`)
+	goPackagePattern    = regexp.MustCompile(`(package\s+\w+)\n+`)
+	goReturnPattern     = regexp.MustCompile(`\)\s*\{\s*return`)
+
+	standardReplacements = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`\n\s*\{`), " {"},
+		{regexp.MustCompile(`\}\s*\n\s*else`), "} else"},
+		{regexp.MustCompile(`\}\s*\n\s*catch`), "} catch"},
+		{regexp.MustCompile(`\}\s*\n\s*finally`), "} finally"},
+		{regexp.MustCompile(`\}\s*\n\s*elif`), "} elif"},
+		{regexp.MustCompile(`\}\s*\n\s*except`), "} except"},
+	}
+)
+
 func Compress(content, language string, ultraMode bool) string {
+	result, _ := CompressChecked(content, language, ultraMode)
+	return result
+}
+
+// CompressChecked 与 Compress 相同，另外校验 Go 代码的压缩结果能否重新解析；
+// 校验失败时返回原文与错误，调用方可据此给出警告
+func CompressChecked(content, language string, ultraMode bool) (string, error) {
 	if content == "" {
-		return content
+		return content, nil
 	}
 
 	language = strings.ToLower(language)
 
 	if !codeLanguages[language] {
-		return basicCompress(content)
+		return basicCompress(content), nil
+	}
+
+	if language == "go" {
+		// 无法解析（例如语法错误的文件）时回退到通用流程
+		if compressed, err := compressGo(content, ultraMode); err == nil {
+			return keepValidGo(content, compressed)
+		}
 	}
 
 	compressor := &Compressor{
@@ -66,7 +101,7 @@ func Compress(content, language string, ultraMode bool) string {
 		tokens:    make([]string, 0),
 	}
 
-	return compressor.Compress(content)
+	return compressor.Compress(content), nil
 }
 
 type Compressor struct {
@@ -289,8 +324,7 @@ func (c *Compressor) removeComments(content string) string {
 }
 
 func (c *Compressor) removeCStyleComments(content string) string {
-	re := regexp.MustCompile(`/\*[\s\S]*?\*/`)
-	content = re.ReplaceAllString(content, "")
+	content = blockCommentPattern.ReplaceAllString(content, "")
 
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
@@ -388,84 +422,74 @@ func (c *Compressor) compressSpaces(s string) string {
 }
 
 func (c *Compressor) standardCompress(content string) string {
-	replacements := []struct {
-		pattern     string
-		replacement string
-	}{
-		{`\n\s*\{`, " {"},
-		{`\}\s*\n\s*else`, "} else"},
-		{`\}\s*\n\s*catch`, "} catch"},
-		{`\}\s*\n\s*finally`, "} finally"},
-		{`\}\s*\n\s*elif`, "} elif"},
-		{`\}\s*\n\s*except`, "} except"},
+	for _, r := range standardReplacements {
+		content = r.pattern.ReplaceAllString(content, r.replacement)
 	}
 
-	for _, r := range replacements {
-		re := regexp.MustCompile(r.pattern)
-		content = re.ReplaceAllString(content, r.replacement)
-	}
-
-	content = regexp.MustCompile(`\n{3,}`).ReplaceAllString(content, "\n\n")
+	content = blankLinesPattern.ReplaceAllString(content, "\n\n")
 
 	return content
 }
 
+// ultraReplacements 按顺序替换，保证同样的输入总是得到同样的输出
+var ultraReplacements = []struct {
+	old, new string
+}{
+	{"\n{", "{"},
+	{"}\n", "}"},
+	{";\n", ";"},
+	{",\n", ","},
+	{"{\n", "{"},
+	{"\n}", "}"},
+	{"\n;", ";"},
+	{"\n,", ","},
+
+	{" {", "{"},
+	{"{ ", "{"},
+	{" }", "}"},
+	{"} ", "}"},
+	{" (", "("},
+	{"( ", "("},
+	{" )", ")"},
+	{") ", ")"},
+	{" [", "["},
+	{"[ ", "["},
+	{" ]", "]"},
+	{"] ", "]"},
+	{" ;", ";"},
+	{" ,", ","},
+	{", ", ","},
+
+	{" = ", "="},
+	{" == ", "=="},
+	{" != ", "!="},
+	{" === ", "==="},
+	{" !== ", "!=="},
+	{" += ", "+="},
+	{" -= ", "-="},
+	{" *= ", "*="},
+	{" /= ", "/="},
+
+	{" < ", "<"},
+	{" > ", ">"},
+	{" <= ", "<="},
+	{" >= ", ">="},
+
+	{" && ", "&&"},
+	{" || ", "||"},
+
+	{" => ", "=>"},
+	{" := ", ":="},
+}
+
 func (c *Compressor) ultraCompress(content string) string {
 	content = c.standardCompress(content)
 
-	replacements := map[string]string{
-		"\n{": "{",
-		"}\n": "}",
-		";\n": ";",
-		",\n": ",",
-		"{\n": "{",
-		"\n}": "}",
-		"\n;": ";",
-		"\n,": ",",
-
-		" {":  "{",
-		"{ ":  "{",
-		" }":  "}",
-		"} ":  "}",
-		" (":  "(",
-		"( ":  "(",
-		" )":  ")",
-		") ":  ")",
-		" [":  "[",
-		"[ ":  "[",
-		" ]":  "]",
-		"] ":  "]",
-		" ;":  ";",
-		" ,":  ",",
-		", ":  ",",
-
-		" = ":   "=",
-		" == ":  "==",
-		" != ":  "!=",
-		" === ": "===",
-		" !== ": "!==",
-		" += ":  "+=",
-		" -= ":  "-=",
-		" *= ":  "*=",
-		" /= ":  "/=",
-
-		" < ":  "<",
-		" > ":  ">",
-		" <= ": "<=",
-		" >= ": ">=",
-
-		" && ": "&&",
-		" || ": "||",
-
-		" => ": "=>",
-		" := ": ":=",
-	}
-
 	for i := 0; i < 3; i++ {
 		changed := false
-		for old, new := range replacements {
-			if strings.Contains(content, old) {
-				content = strings.ReplaceAll(content, old, new)
+		for _, r := range ultraReplacements {
+			if strings.Contains(content, r.old) {
+				content = strings.ReplaceAll(content, r.old, r.new)
 				changed = true
 			}
 		}
@@ -481,7 +505,7 @@ func (c *Compressor) ultraCompress(content string) string {
 		content = c.ultraCompressGo(content)
 	}
 
-	content = regexp.MustCompile(`\n+`).ReplaceAllString(content, "\n")
+	content = newlinesPattern.ReplaceAllString(content, "\n")
 
 	return content
 }
@@ -516,8 +540,8 @@ func (c *Compressor) ultraCompressJS(content string) string {
 }
 
 func (c *Compressor) ultraCompressGo(content string) string {
-	content = regexp.MustCompile(`(package\s+\w+)\n+`).ReplaceAllString(content, "$1\n")
-	content = regexp.MustCompile(`\)\s*\{\s*return`).ReplaceAllString(content, "){return")
+	content = goPackagePattern.ReplaceAllString(content, "$1\n")
+	content = goReturnPattern.ReplaceAllString(content, "){return")
 	return content
 }
 
@@ -543,7 +567,7 @@ func (c *Compressor) finalCleanup(content string) string {
 		content += "\n"
 	}
 
-	content = regexp.MustCompile(`\n{3,}`).ReplaceAllString(content, "\n\n")
+	content = blankLinesPattern.ReplaceAllString(content, "\n\n")
 
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
@@ -553,10 +577,3 @@ func (c *Compressor) finalCleanup(content string) string {
 
 	return content
 }
-
-func EstimateTokens(content string) int {
-	words := strings.Fields(content)
-	charCount := len(content)
-	tokenEstimate := (len(words) + charCount/4) / 2
-	return tokenEstimate
-}
\ No newline at end of file
@@ -0,0 +1,238 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// compressGo 基于语法树压缩 Go 代码
+//
+// 注释在语法树中丢弃（编译指令与 cgo 前导注释除外），再以紧凑格式重新输出。
+// 标准模式去掉缩进与空行，深度模式按词法单元重新拼接，只保留必要的空格与换行。
+// 源码无法解析时返回错误，由调用方回退到通用的正则流程
+func compressGo(content string, ultraMode bool) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", err
+	}
+
+	// Comments 非 nil 时 printer 只输出其中的注释，节点上的 Doc 不再单独输出
+	file.Comments = keptComments(file)
+
+	var buf bytes.Buffer
+	printerConfig := printer.Config{Mode: printer.RawFormat, Tabwidth: 8}
+	if err := printerConfig.Fprint(&buf, fset, file); err != nil {
+		return "", err
+	}
+
+	src := buf.Bytes()
+	if ultraMode {
+		return joinGoTokens(src), nil
+	}
+	return strings.TrimSpace(compactGoLines(src)), nil
+}
+
+// verifyGo 校验压缩结果仍是合法的 Go 代码
+func verifyGo(content string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), "", content, parser.SkipObjectResolution)
+	return err
+}
+
+// keepValidGo 压缩结果无法重新解析时保留原文并返回错误
+func keepValidGo(content, compressed string) (string, error) {
+	if err := verifyGo(compressed); err != nil {
+		return content, fmt.Errorf("压缩结果不是合法的 Go 代码，已保留原文: %w", err)
+	}
+	return compressed, nil
+}
+
+// keptComments 需要保留的注释：//go: 等编译指令、构建约束，以及 import "C" 之前的 cgo 前导代码
+func keptComments(file *ast.File) []*ast.CommentGroup {
+	preamble := map[*ast.CommentGroup]bool{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if imp := spec.(*ast.ImportSpec); imp.Path.Value == `"C"` {
+				preamble[gen.Doc] = true
+				preamble[imp.Doc] = true
+			}
+		}
+	}
+
+	kept := make([]*ast.CommentGroup, 0)
+	for _, group := range file.Comments {
+		if preamble[group] {
+			kept = append(kept, group)
+			continue
+		}
+
+		var directives []*ast.Comment
+		for _, comment := range group.List {
+			if isDirective(comment.Text) {
+				directives = append(directives, comment)
+			}
+		}
+		if len(directives) > 0 {
+			kept = append(kept, &ast.CommentGroup{List: directives})
+		}
+	}
+	return kept
+}
+
+func isDirective(text string) bool {
+	for _, prefix := range []string{"//go:", "// +build", "//export ", "//extern ", "//sys "} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// literalSpans 字符串、字符字面量与注释所占的字节区间，压缩时其中的空白保持原样
+func literalSpans(src []byte) [][2]int {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var spans [][2]int
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.STRING || tok == token.CHAR || tok == token.COMMENT {
+			start := file.Offset(pos)
+			spans = append(spans, [2]int{start, start + len(lit)})
+		}
+	}
+	return spans
+}
+
+// compactGoLines 去掉缩进与空行，行内对齐用的制表符换成一个空格
+func compactGoLines(src []byte) string {
+	spans := literalSpans(src)
+
+	var builder strings.Builder
+	builder.Grow(len(src))
+	lineStart, prevSpace := true, false
+	for i := 0; i < len(src); i++ {
+		if len(spans) > 0 && i == spans[0][0] {
+			builder.Write(src[i:spans[0][1]])
+			i = spans[0][1] - 1
+			spans = spans[1:]
+			lineStart, prevSpace = false, false
+			continue
+		}
+
+		switch ch := src[i]; ch {
+		case '\n':
+			if !lineStart {
+				builder.WriteByte('\n')
+			}
+			lineStart, prevSpace = true, false
+		case ' ', '\t':
+			if !lineStart && !prevSpace {
+				builder.WriteByte(' ')
+				prevSpace = true
+			}
+		default:
+			builder.WriteByte(ch)
+			lineStart, prevSpace = false, false
+		}
+	}
+	return builder.String()
+}
+
+// joinGoTokens 按词法单元重新拼接：自动插入分号处换行，其余位置只在两个单元会粘连时加空格
+func joinGoTokens(src []byte) string {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var builder strings.Builder
+	builder.Grow(len(src))
+	prev := ""
+	newline := func() {
+		if builder.Len() > 0 && prev != "" {
+			builder.WriteByte('\n')
+		}
+		prev = ""
+	}
+
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		switch {
+		case tok == token.COMMENT:
+			// 保留下来的注释都是指令，单独占一行
+			newline()
+			builder.WriteString(lit)
+			builder.WriteByte('\n')
+		case tok == token.SEMICOLON && lit == "\n":
+			newline()
+		default:
+			text := lit
+			if text == "" {
+				text = tok.String()
+			}
+			if needSpace(prev, text) {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(text)
+			prev = text
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// goOperators 所有运算符与注释起始符，用于判断两个单元直接相连时是否会被识别成更长的运算符
+var goOperators = func() []string {
+	ops := []string{"//", "/*"}
+	for tok := token.ADD; tok <= token.TILDE; tok++ {
+		if tok.IsOperator() {
+			ops = append(ops, tok.String())
+		}
+	}
+	return ops
+}()
+
+func needSpace(prev, next string) bool {
+	if prev == "" || next == "" {
+		return false
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if isWordRune(last) && (isWordRune(first) || first == '.' && unicode.IsDigit(last)) {
+		return true
+	}
+
+	joined := prev + string(first)
+	for _, op := range goOperators {
+		if strings.HasPrefix(op, joined) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,103 @@
+package compress
+
+import (
+	"strings"
+	"testing"
+)
+
+// goSource 带有各类需要保留的注释
+const goSource = `//go:build linux
+
+// Package a 文档注释
+package a
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+//go:generate stringer -type=T
+
+// F 说明
+//
+//export F
+func F(x int) int {
+	// 注释
+	y := x - -x // 行尾
+
+	return y
+}
+
+//go:embed a.txt
+var s string
+`
+
+func TestCompressGoKeepsDirectives(t *testing.T) {
+	for _, ultra := range []bool{false, true} {
+		got, err := CompressChecked(goSource, "go", ultra)
+		if err != nil {
+			t.Fatalf("ultra=%v: %v", ultra, err)
+		}
+		for _, want := range []string{"//go:build linux", "#include <stdio.h>", "//go:generate stringer -type=T", "//export F", "//go:embed a.txt", "- -x"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("ultra=%v: 缺少 %q:\n%s", ultra, want, got)
+			}
+		}
+		for _, removed := range []string{"文档注释", "说明", "注释", "行尾"} {
+			if strings.Contains(got, removed) {
+				t.Errorf("ultra=%v: 应删除注释 %q:\n%s", ultra, removed, got)
+			}
+		}
+		if err := verifyGo(got); err != nil {
+			t.Errorf("ultra=%v: 结果无法解析: %v", ultra, err)
+		}
+	}
+}
+
+func TestCompressGo(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		ultra bool
+		want  string
+	}{
+		{"标准模式去掉缩进与空行", "package a\n\nfunc f() {\n\n\tif true {\n\t\treturn\n\t}\n}\n", false, "package a\nfunc f() {\nif true {\nreturn\n}\n}"},
+		{"字符串中的空白保持原样", "package a\n\nvar s = `a\n\n\t b`\n", false, "package a\nvar s = `a\n\n\t b`"},
+		{"深度模式只保留必要的空格", "package a\n\nvar x = 1 + +2\n\nfunc f(a, b int) int { return a &^ b }\n", true, "package a\nvar x=1+ +2\nfunc f(a,b int)int{return a&^b}"},
+		{"深度模式保留自动分号处的换行", "package a\n\nfunc f() {\n\tx := 1\n\tx++\n\t_ = x\n}\n", true, "package a\nfunc f(){x:=1\nx++\n_=x\n}"},
+	}
+
+	for _, tt := range tests {
+		got, err := CompressChecked(tt.src, "go", tt.ultra)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompressGoFallback(t *testing.T) {
+	// 无法解析的文件回退到通用流程，仍然去掉注释，不报错
+	src := "package a\n\nfunc f( {\n\t// 注释\n\treturn\n}\n"
+	got, err := CompressChecked(src, "go", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "注释") || !strings.Contains(got, "func f(") {
+		t.Errorf("通用流程结果: %q", got)
+	}
+
+	// 压缩结果无法重新解析时保留原文并返回错误
+	original := "package a\n\nvar x = 1\n"
+	got, err = keepValidGo(original, "package a\nvar x = ")
+	if err == nil || got != original {
+		t.Errorf("校验失败时 got=%q err=%v", got, err)
+	}
+	got, err = keepValidGo(original, "package a\nvar x = 1")
+	if err != nil || got != "package a\nvar x = 1" {
+		t.Errorf("校验通过时 got=%q err=%v", got, err)
+	}
+}
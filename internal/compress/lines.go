@@ -0,0 +1,366 @@
+package compress
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CompressLines 与 CompressChecked 相同，同时返回结果中每一行对应的原文行号，无法对应的行为 0；
+// 对齐时跳过压缩会删除的注释，见 MapLines
+func CompressLines(content, language string, ultraMode bool) (string, []int, error) {
+	compressed, err := CompressChecked(content, language, ultraMode)
+	if compressed == content {
+		return compressed, MapLines(content, compressed), err
+	}
+	return compressed, mapLines(content, compressed, commentSpans(content, strings.ToLower(language)), nil), err
+}
+
+// MapLines 返回 output 每一行在 original 中开始的行号（从 1 起），无法对应的行为 0
+//
+// 压缩只删除注释与空白、合并或拆开行，不改变代码的先后顺序，因此忽略空白后按顺序对齐：
+// 先在原文中查找整行，找不到（例如行内的注释被删除）时按字符依次匹配，取最短的匹配区间。
+// ignore 中的片段不是原文内容（例如骨架的占位符），对齐时跳过
+func MapLines(original, output string, ignore ...string) []int {
+	return mapLines(original, output, nil, ignore)
+}
+
+// mapLines 同 MapLines，skip 为原文中压缩时删除的注释区间（按位置排序），优先在去掉这些区间的内容中对齐
+func mapLines(original, output string, skip [][2]int, ignore []string) []int {
+	lines := strings.Split(output, "\n")
+	numbers := make([]int, len(lines))
+	// 末尾换行之后的空串不是原文中的一行
+	total := strings.Count(original, "\n") + 1
+	if strings.HasSuffix(original, "\n") {
+		total--
+	}
+	if output == original {
+		for i := 0; i < total; i++ {
+			numbers[i] = i + 1
+		}
+		return numbers
+	}
+
+	a := &aligner{full: newAlignView(original, nil), skip: skip}
+	if len(skip) > 0 {
+		a.code = newAlignView(original, skip)
+	}
+	lineStarts := []int{0}
+	for i := 0; i < len(original); i++ {
+		if original[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	lineAt := func(offset int) int {
+		return sort.SearchInts(lineStarts, offset+1)
+	}
+
+	cursor := 0
+	for i, line := range lines {
+		// 保留下来的空行对应上一个匹配之后的空行
+		if strings.TrimSpace(line) == "" {
+			if next := lineAt(cursor) + 1; cursor > 0 && next <= total && isBlankLine(original, lineStarts, next) {
+				numbers[i] = next
+				cursor = lineStarts[next-1]
+			}
+			continue
+		}
+		for _, part := range splitIgnored(line, ignore) {
+			s := []byte(strings.Join(strings.Fields(part), ""))
+			if len(s) == 0 {
+				continue
+			}
+			start, end, ok := a.locate(s, cursor)
+			if !ok {
+				continue
+			}
+			if numbers[i] == 0 {
+				numbers[i] = lineAt(start)
+			}
+			cursor = end
+		}
+	}
+	return numbers
+}
+
+// isBlankLine 原文第 n 行（从 1 起）是否只有空白
+func isBlankLine(original string, lineStarts []int, n int) bool {
+	end := len(original)
+	if n < len(lineStarts) {
+		end = lineStarts[n]
+	}
+	return strings.TrimSpace(original[lineStarts[n-1]:end]) == ""
+}
+
+// alignView 去掉空白（以及 skip 区间）后的原文，offsets 为每个字节在原文中的位置
+type alignView struct {
+	text    []byte
+	offsets []int
+}
+
+func newAlignView(s string, skip [][2]int) *alignView {
+	v := &alignView{text: make([]byte, 0, len(s)), offsets: make([]int, 0, len(s))}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		for len(skip) > 0 && skip[0][1] <= i {
+			skip = skip[1:]
+		}
+		if !unicode.IsSpace(r) && (len(skip) == 0 || skip[0][0] > i) {
+			v.text = append(v.text, s[i:i+size]...)
+			for j := 0; j < size; j++ {
+				v.offsets = append(v.offsets, i+j)
+			}
+		}
+		i += size
+	}
+	return v
+}
+
+// splitIgnored 按 ignore 中的片段拆开一行
+func splitIgnored(line string, ignore []string) []string {
+	parts := []string{line}
+	for _, piece := range ignore {
+		if piece == "" {
+			continue
+		}
+		var next []string
+		for _, part := range parts {
+			next = append(next, strings.Split(part, piece)...)
+		}
+		parts = next
+	}
+	return parts
+}
+
+// aligner 在原文中依次查找输出的各行
+type aligner struct {
+	full *alignView
+	code *alignView // 去掉 skip 区间后的原文，没有 skip 时为 nil
+	skip [][2]int
+}
+
+// locate 从原文位置 from 开始查找 s，返回匹配区间在原文中的位置 [start, end)。
+// 先找连续出现的位置，都找不到时再按字符依次匹配；有 skip 时优先在去掉注释的内容中查找，
+// 除非在完整原文中更早出现且恰好从某个注释开始，说明这个注释实际上保留了下来
+func (a *aligner) locate(s []byte, from int) (int, int, bool) {
+	fullStart, fullEnd, fullOK := a.full.find(indexMatch, s, from)
+	if a.code != nil {
+		start, end, ok := a.code.find(indexMatch, s, from)
+		if ok && !(fullOK && fullStart < start && a.commentAt(fullStart)) {
+			return start, end, true
+		}
+	}
+	if fullOK {
+		return fullStart, fullEnd, true
+	}
+
+	if a.code != nil {
+		if start, end, ok := a.code.find(subsequenceMatch, s, from); ok {
+			return start, end, true
+		}
+	}
+	return a.full.find(subsequenceMatch, s, from)
+}
+
+// commentAt offset 是否为某个 skip 区间的开头
+func (a *aligner) commentAt(offset int) bool {
+	i := sort.Search(len(a.skip), func(i int) bool {
+		return a.skip[i][0] >= offset
+	})
+	return i < len(a.skip) && a.skip[i][0] == offset
+}
+
+// find 用 match 从原文位置 from 开始查找，返回原文中的区间
+func (v *alignView) find(match func(text, s []byte, from int) (int, int, bool), s []byte, from int) (int, int, bool) {
+	start, end, ok := match(v.text, s, sort.SearchInts(v.offsets, from))
+	if !ok {
+		return 0, 0, false
+	}
+	return v.offsets[start], v.offsets[end-1] + 1, true
+}
+
+// indexMatch s 连续出现的位置
+func indexMatch(text, s []byte, from int) (int, int, bool) {
+	if i := bytes.Index(text[from:], s); i >= 0 {
+		return from + i, from + i + len(s), true
+	}
+	return 0, 0, false
+}
+
+// subsequenceMatch 向前逐个匹配得到最早的结束位置，再从结束位置向后匹配得到最晚的开始位置，即最短的匹配区间
+func subsequenceMatch(text, s []byte, from int) (int, int, bool) {
+	end := from
+	for _, b := range s {
+		i := bytes.IndexByte(text[end:], b)
+		if i < 0 {
+			return 0, 0, false
+		}
+		end += i + 1
+	}
+	start := end
+	for k := len(s) - 1; k >= 0; k-- {
+		start = bytes.LastIndexByte(text[from:start], s[k]) + from
+	}
+	return start, end, true
+}
+
+// commentSpans 压缩时会删除的注释在原文中的字节区间，与各语言的压缩流程对应
+func commentSpans(content, language string) [][2]int {
+	switch {
+	case language == "go":
+		return goCommentSpans(content)
+	case cStyleCommentLanguages[language]:
+		return cStyleCommentSpans(content)
+	case pythonStyleCommentLanguages[language]:
+		return pythonCommentSpans(content, language == "python")
+	}
+	return nil
+}
+
+// goCommentSpans keptComments 以外的注释；无法解析时压缩回退到正则流程，按 C 风格注释处理
+func goCommentSpans(content string) [][2]int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return cStyleCommentSpans(content)
+	}
+
+	kept := map[token.Pos]bool{}
+	for _, group := range keptComments(file) {
+		for _, comment := range group.List {
+			kept[comment.Pos()] = true
+		}
+	}
+	var spans [][2]int
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !kept[comment.Pos()] {
+				start := fset.Position(comment.Pos()).Offset
+				spans = append(spans, [2]int{start, start + len(comment.Text)})
+			}
+		}
+	}
+	return spans
+}
+
+// cStyleCommentSpans 块注释与不在字符串中的行注释，判断方式与 removeCStyleComments 相同
+func cStyleCommentSpans(content string) [][2]int {
+	blocks := blockCommentPattern.FindAllStringIndex(content, -1)
+	c := &Compressor{}
+
+	var spans [][2]int
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		start, end := offset, offset+len(strings.TrimRight(line, "\n"))
+		offset += len(line)
+
+		for len(blocks) > 0 && blocks[0][1] <= start {
+			spans = append(spans, [2]int{blocks[0][0], blocks[0][1]})
+			blocks = blocks[1:]
+		}
+		// 行注释之前的块注释已经删除，只在块注释之后查找
+		from := start
+		for _, b := range blocks {
+			if b[0] >= end {
+				break
+			}
+			if b[1] > from {
+				from = b[1]
+			}
+		}
+		if from >= end {
+			continue
+		}
+		// 字符串已被替换为占位符，取第一个不在字符串中的 //
+		for i := from; i+1 < end; i++ {
+			if content[i:i+2] == "//" && !c.isInString(content[start:i]) {
+				spans = append(spans, [2]int{i, end})
+				break
+			}
+		}
+	}
+	for _, b := range blocks {
+		spans = append(spans, [2]int{b[0], b[1]})
+	}
+	return mergeSpans(spans)
+}
+
+// pythonCommentSpans # 开头的注释，Python 的三引号字符串中不查找
+func pythonCommentSpans(content string, tripleQuotes bool) [][2]int {
+	var protected [][2]int
+	if tripleQuotes {
+		protected = tripleQuoteSpans(content)
+	}
+	c := &Compressor{}
+
+	var spans [][2]int
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		start, end := offset, offset+len(strings.TrimRight(line, "\n"))
+		offset += len(line)
+
+		for i := start; i < end; i++ {
+			if content[i] != '#' || inSpans(protected, i) {
+				continue
+			}
+			if !c.isInString(content[start:i]) {
+				spans = append(spans, [2]int{i, end})
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// tripleQuoteSpans 三引号字符串的区间，与 protectTripleQuotes 的处理顺序相同
+func tripleQuoteSpans(content string) [][2]int {
+	var spans [][2]int
+	for _, quote := range []string{`"""`, `'''`} {
+		for i := 0; i+3 <= len(content); {
+			if content[i:i+3] != quote || inSpans(spans, i) {
+				i++
+				continue
+			}
+			end := strings.Index(content[i+3:], quote)
+			if end < 0 {
+				spans = append(spans, [2]int{i, len(content)})
+				break
+			}
+			spans = append(spans, [2]int{i, i + 3 + end + 3})
+			i += 3 + end + 3
+		}
+	}
+	return mergeSpans(spans)
+}
+
+func inSpans(spans [][2]int, i int) bool {
+	for _, span := range spans {
+		if i >= span[0] && i < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSpans 按起始位置排序并合并重叠的区间
+func mergeSpans(spans [][2]int) [][2]int {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i][0] < spans[j][0]
+	})
+	var merged [][2]int
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span[0] <= merged[n-1][1] {
+			if span[1] > merged[n-1][1] {
+				merged[n-1][1] = span[1]
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
@@ -0,0 +1,91 @@
+package compress
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompressLinesGo(t *testing.T) {
+	tests := []struct {
+		ultra bool
+		want  []int
+	}{
+		// 每个输出行对应的原文行，见 goSource
+		{false, []int{1, 4, 6, 7, 8, 9, 11, 15, 16, 18, 20, 21, 23, 24}},
+		// 深度模式把函数体第一行并入签名所在的行，按行首对齐
+		{true, []int{1, 4, 6, 7, 8, 9, 11, 15, 16, 20, 21, 23, 24}},
+	}
+
+	for _, tt := range tests {
+		got, numbers, err := CompressLines(goSource, "go", tt.ultra)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(numbers) != strings.Count(got, "\n")+1 {
+			t.Fatalf("ultra=%v: %d 行输出 %d 个行号", tt.ultra, strings.Count(got, "\n")+1, len(numbers))
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint(tt.want) {
+			t.Errorf("ultra=%v: numbers = %v, want %v", tt.ultra, numbers, tt.want)
+		}
+	}
+}
+
+func TestCompressLinesOtherLanguages(t *testing.T) {
+	tests := []struct {
+		language string
+		src      string
+		want     []int
+	}{
+		{"javascript", "// 说明\nfunction f() {\n\n  /* 块\n  注释 */\n  return 1;\n}\n", []int{2, 6, 7}},
+		{"python", "# 说明\ndef f():\n\n    \"\"\"文档\"\"\"\n    return 1\n", []int{2, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		got, numbers, err := CompressLines(tt.src, tt.language, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: %q numbers = %v, want %v", tt.language, got, numbers, tt.want)
+		}
+	}
+}
+
+func TestMapLinesSkeleton(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		output   string
+		ignore   string
+		want     []int
+	}{
+		{
+			"Go 骨架",
+			"package a\n\n// F 说明\nfunc F(x int) int {\n\treturn x\n}\n\nfunc G() {\n\tprintln()\n}\n",
+			"package a\n\n// F 说明\nfunc F(x int) int { /* ... */ }\n\nfunc G() { /* ... */ }\n",
+			"/* ... */",
+			[]int{1, 2, 3, 4, 7, 8, 0},
+		},
+		{
+			"Python 骨架，占位语句不对应原文",
+			"import os\n\n\n@dec\ndef f(a):\n    return a\n",
+			"import os\n\n\n@dec\ndef f(a):\n    ...\n",
+			"...",
+			[]int{1, 2, 3, 4, 5, 0, 0},
+		},
+		{
+			"内容相同时逐行对应",
+			"a\nb\n",
+			"a\nb\n",
+			"",
+			[]int{1, 2, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := MapLines(tt.original, tt.output, tt.ignore); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
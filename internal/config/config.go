@@ -11,8 +11,13 @@ import (
 
 type Config = configs.Config
 type CustomIgnore = configs.CustomIgnore
+type EncodingRule = configs.EncodingRule
+type Include = configs.Include
 type Output = configs.Output
+type Redact = configs.Redact
 type Prompts = configs.Prompts
+type Serve = configs.Serve
+type Ask = configs.Ask
 
 var userConfigPath string
 var targetDirs []string
@@ -26,8 +31,14 @@ func SetTargetDirs(dirs []string) {
 }
 
 func Load() (*Config, error) {
-	if userConfigPath != "" {
-		return LoadFrom(userConfigPath)
+	return LoadFor(userConfigPath, targetDirs)
+}
+
+// LoadFor 按查找顺序加载配置：configPath、当前目录的 .ptlm.yaml、各目标目录的 .ptlm.yaml、内置默认值；
+// 不读写包级变量，可在多个 goroutine 中使用
+func LoadFor(configPath string, dirs []string) (*Config, error) {
+	if configPath != "" {
+		return LoadFrom(configPath)
 	}
 
 	if _, err := os.Stat(".ptlm.yaml"); err == nil {
@@ -37,7 +48,7 @@ func Load() (*Config, error) {
 		}
 	}
 
-	for _, dir := range targetDirs {
+	for _, dir := range dirs {
 		absDir, err := filepath.Abs(dir)
 		if err != nil {
 			continue
@@ -67,7 +78,8 @@ func LoadFrom(path string) (*Config, error) {
 		return nil, err
 	}
 
-	var cfg Config
+	// 未在文件中出现的开关保持默认开启
+	cfg := Config{RespectGitignore: true, Cache: true, Redact: Redact{Enabled: true}}
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
@@ -88,13 +100,19 @@ func LoadFrom(path string) (*Config, error) {
 func Default() *Config {
 	cfg := &Config{
 		LanguageMap:       make(map[string]string),
+		FilenameMap:       make(map[string]string),
 		DefaultIgnore:     []string{},
 		BinaryExtensions:  []string{},
 		NonCodeExtensions: []string{},
+		RespectGitignore:  true,
+		Cache:             true,
+		MaxFileSize:       10 * 1024 * 1024,
+		Truncate:          "head_tail",
 		CustomIgnore: CustomIgnore{
 			Patterns: []string{},
 			Regex:    []string{},
 		},
+		Include: Include{Tree: "prune"},
 		Output: Output{
 			MaxChars:      50000,
 			Compress:      true,
@@ -102,12 +120,19 @@ func Default() *Config {
 			SplitMode:     "char",
 			IncludeTree:   true,
 			OutputPrefix:  "LLM_CODE",
+			Format:        "markdown",
+			PartDelimiter: "\n",
 		},
+		Redact: Redact{Enabled: true},
+		Serve:  Serve{Addr: "127.0.0.1:7878"},
+		Ask:    Ask{Provider: "openai", MaxTokens: 4096, TranscriptPrefix: "LLM_ASK", ContextWindow: 128000},
 		Prompts: Prompts{
 			SectionInfo:         "项目概况",
 			SectionTree:         "目录结构",
 			SectionCode:         "源码清单",
 			SectionStats:        "统计信息",
+			SectionSkipped:      "跳过的文件",
+			SectionExcluded:     "排除的路径",
 			CompressNotice:      "代码已压缩，建议格式化后阅读。",
 			UltraCompressNotice: "代码深度压缩，必须格式化后阅读。",
 			ContinueNotice:      "内容未完，请查看后续部分。",
@@ -163,6 +188,16 @@ func Default() *Config {
 		".gradle": "gradle",
 	}
 
+	cfg.FilenameMap = map[string]string{
+		"Dockerfile": "dockerfile", "Containerfile": "dockerfile", "Dockerfile.*": "dockerfile", "*.dockerfile": "dockerfile",
+		"Makefile": "makefile", "makefile": "makefile", "GNUmakefile": "makefile", "*.mk": "makefile",
+		"CMakeLists.txt": "cmake", "*.cmake": "cmake",
+		"Jenkinsfile": "groovy",
+		"Vagrantfile": "ruby", "Gemfile": "ruby", "Rakefile": "ruby", "Podfile": "ruby", "Brewfile": "ruby", "Fastfile": "ruby", "*.gemspec": "ruby",
+		".bashrc": "shell", ".bash_profile": "shell", ".bash_aliases": "shell", ".bash_logout": "shell", ".profile": "shell", ".zshrc": "shell", ".zprofile": "shell", ".zshenv": "shell",
+		"BUILD": "starlark", "BUILD.bazel": "starlark", "WORKSPACE": "starlark", "*.bzl": "starlark",
+	}
+
 	cfg.DefaultIgnore = []string{
 		"node_modules", "vendor", "venv", ".venv", "env", ".env",
 		"__pycache__", ".pytest_cache", ".mypy_cache",
@@ -175,7 +210,7 @@ func Default() *Config {
 		"*.log", "logs", "*.tmp", "*.temp", "*.bak", "*.swp", "*.swo",
 		"package-lock.json", "yarn.lock", "pnpm-lock.yaml",
 		"Gemfile.lock", "Cargo.lock", "go.sum", "composer.lock",
-		"LLM_CODE*.md",
+		"LLM_CODE*.md", "LLM_CODE*.xml", "LLM_CODE*.json", "LLM_CODE*.jsonl", "LLM_ASK*.md",
 		"*.min.js", "*.min.css", "*.map",
 		"assets", "static", "public/assets",
 	}
@@ -230,6 +265,15 @@ func merge(base, override *Config) {
 		}
 	}
 
+	if len(override.FilenameMap) > 0 {
+		if base.FilenameMap == nil {
+			base.FilenameMap = make(map[string]string)
+		}
+		for k, v := range override.FilenameMap {
+			base.FilenameMap[k] = v
+		}
+	}
+
 	if len(override.DefaultIgnore) > 0 {
 		base.DefaultIgnore = append(base.DefaultIgnore, override.DefaultIgnore...)
 	}
@@ -242,6 +286,26 @@ func merge(base, override *Config) {
 		base.NonCodeExtensions = append(base.NonCodeExtensions, override.NonCodeExtensions...)
 	}
 
+	base.RespectGitignore = override.RespectGitignore
+
+	if override.Jobs > 0 {
+		base.Jobs = override.Jobs
+	}
+	base.Cache = override.Cache
+
+	if override.MaxFileSize != 0 {
+		base.MaxFileSize = override.MaxFileSize
+	}
+	if override.MaxFileLines > 0 {
+		base.MaxFileLines = override.MaxFileLines
+	}
+	if override.Truncate != "" {
+		base.Truncate = override.Truncate
+	}
+	if len(override.SourceEncoding) > 0 {
+		base.SourceEncoding = append(base.SourceEncoding, override.SourceEncoding...)
+	}
+
 	if len(override.CustomIgnore.Patterns) > 0 {
 		base.CustomIgnore.Patterns = append(base.CustomIgnore.Patterns, override.CustomIgnore.Patterns...)
 	}
@@ -250,19 +314,96 @@ func merge(base, override *Config) {
 		base.CustomIgnore.Regex = append(base.CustomIgnore.Regex, override.CustomIgnore.Regex...)
 	}
 
+	if len(override.CustomIgnore.Paths) > 0 {
+		base.CustomIgnore.Paths = append(base.CustomIgnore.Paths, override.CustomIgnore.Paths...)
+	}
+
+	if len(override.Include.Patterns) > 0 {
+		base.Include.Patterns = append(base.Include.Patterns, override.Include.Patterns...)
+	}
+	if override.Include.Tree != "" {
+		base.Include.Tree = override.Include.Tree
+	}
+
 	if override.Output.MaxChars > 0 {
 		base.Output.MaxChars = override.Output.MaxChars
 	}
+	if override.Output.MaxTokens > 0 {
+		base.Output.MaxTokens = override.Output.MaxTokens
+	}
+	if override.Output.Tokenizer != "" {
+		base.Output.Tokenizer = override.Output.Tokenizer
+	}
+	if override.Output.TokenizerFile != "" {
+		base.Output.TokenizerFile = override.Output.TokenizerFile
+	}
 	if override.Output.SplitMode != "" {
 		base.Output.SplitMode = override.Output.SplitMode
 	}
 	if override.Output.OutputPrefix != "" {
 		base.Output.OutputPrefix = override.Output.OutputPrefix
 	}
+	if override.Output.Format != "" {
+		base.Output.Format = override.Output.Format
+	}
+	if override.Output.PartDelimiter != "" {
+		base.Output.PartDelimiter = override.Output.PartDelimiter
+	}
+	if override.Output.DiffMode != "" {
+		base.Output.DiffMode = override.Output.DiffMode
+	}
 
 	base.Output.Compress = override.Output.Compress
 	base.Output.UltraCompress = override.Output.UltraCompress
 	base.Output.IncludeTree = override.Output.IncludeTree
+	base.Output.Skeleton = override.Output.Skeleton
+	base.Output.ReportExcluded = override.Output.ReportExcluded
+	base.Output.LineNumbers = override.Output.LineNumbers
+
+	if len(override.Output.SkeletonKeep) > 0 {
+		base.Output.SkeletonKeep = append(base.Output.SkeletonKeep, override.Output.SkeletonKeep...)
+	}
+
+	base.Redact.Enabled = override.Redact.Enabled
+	base.Redact.FailOnSecrets = override.Redact.FailOnSecrets
+	if len(override.Redact.AllowPaths) > 0 {
+		base.Redact.AllowPaths = append(base.Redact.AllowPaths, override.Redact.AllowPaths...)
+	}
+	if len(override.Redact.AllowValues) > 0 {
+		base.Redact.AllowValues = append(base.Redact.AllowValues, override.Redact.AllowValues...)
+	}
+
+	if override.Serve.Addr != "" {
+		base.Serve.Addr = override.Serve.Addr
+	}
+	if len(override.Serve.Roots) > 0 {
+		base.Serve.Roots = append(base.Serve.Roots, override.Serve.Roots...)
+	}
+
+	if override.Ask.Provider != "" {
+		base.Ask.Provider = override.Ask.Provider
+	}
+	if override.Ask.BaseURL != "" {
+		base.Ask.BaseURL = override.Ask.BaseURL
+	}
+	if override.Ask.Model != "" {
+		base.Ask.Model = override.Ask.Model
+	}
+	if override.Ask.APIKeyEnv != "" {
+		base.Ask.APIKeyEnv = override.Ask.APIKeyEnv
+	}
+	if override.Ask.MaxTokens > 0 {
+		base.Ask.MaxTokens = override.Ask.MaxTokens
+	}
+	if override.Ask.System != "" {
+		base.Ask.System = override.Ask.System
+	}
+	if override.Ask.TranscriptPrefix != "" {
+		base.Ask.TranscriptPrefix = override.Ask.TranscriptPrefix
+	}
+	if override.Ask.ContextWindow != 0 {
+		base.Ask.ContextWindow = override.Ask.ContextWindow
+	}
 
 	if override.Prompts.HeaderPrompt != "" {
 		base.Prompts.HeaderPrompt = override.Prompts.HeaderPrompt
@@ -279,4 +420,10 @@ func merge(base, override *Config) {
 	if override.Prompts.SectionStats != "" {
 		base.Prompts.SectionStats = override.Prompts.SectionStats
 	}
+	if override.Prompts.SectionSkipped != "" {
+		base.Prompts.SectionSkipped = override.Prompts.SectionSkipped
+	}
+	if override.Prompts.SectionExcluded != "" {
+		base.Prompts.SectionExcluded = override.Prompts.SectionExcluded
+	}
 }
\ No newline at end of file
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileFor 返回 LoadFor 会读取的配置文件路径，都不存在时返回空字符串
+func FileFor(configPath string, dirs []string) string {
+	if configPath != "" {
+		return configPath
+	}
+	if _, err := os.Stat(".ptlm.yaml"); err == nil {
+		return ".ptlm.yaml"
+	}
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		cfgPath := filepath.Join(absDir, ".ptlm.yaml")
+		if _, err := os.Stat(cfgPath); err == nil {
+			return cfgPath
+		}
+	}
+	return ""
+}
+
+// AppendIgnorePaths 把路径追加到配置文件的 custom_ignore.paths，已有的路径不重复添加；
+// 文件不存在时新建。按节点修改，保留原有的注释与其他设置，返回新增的数量
+func AppendIgnorePaths(path string, paths []string) (int, error) {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return 0, fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return 0, fmt.Errorf("%s 的顶层不是映射", path)
+	}
+
+	ignore, err := mappingValue(root, "custom_ignore", yaml.MappingNode)
+	if err != nil {
+		return 0, err
+	}
+	list, err := mappingValue(ignore, "paths", yaml.SequenceNode)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[string]bool, len(list.Content))
+	for _, item := range list.Content {
+		existing[item.Value] = true
+	}
+	added := 0
+	for _, p := range paths {
+		if existing[p] {
+			continue
+		}
+		existing[p] = true
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p, Style: yaml.DoubleQuotedStyle})
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return 0, err
+	}
+	if err := enc.Close(); err != nil {
+		return 0, err
+	}
+	return added, os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// mappingValue 取映射中 key 对应的节点，不存在或为空时按 kind 创建
+func mappingValue(m *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, error) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		value := m.Content[i+1]
+		if value.Kind == yaml.ScalarNode && (value.Tag == "!!null" || value.Value == "") {
+			*value = yaml.Node{Kind: kind}
+		}
+		if value.Kind != kind {
+			return nil, fmt.Errorf("配置项 %s 的类型不正确", key)
+		}
+		return value, nil
+	}
+
+	value := &yaml.Node{Kind: kind}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendIgnorePaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ptlm.yaml")
+	original := "# 项目配置\ncustom_ignore:\n  patterns:\n    - \"*.bak\"\n  paths:\n    - \"docs/\"\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := AppendIgnorePaths(path, []string{"docs/", "config.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("added = %d, want 1", added)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# 项目配置") {
+		t.Errorf("注释丢失:\n%s", data)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cfg.CustomIgnore.Paths, ",") != "docs/,config.yaml" || strings.Join(cfg.CustomIgnore.Patterns, ",") != "*.bak" {
+		t.Errorf("paths=%v patterns=%v", cfg.CustomIgnore.Paths, cfg.CustomIgnore.Patterns)
+	}
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/parallel"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/tokenizer"
+)
+
+// Estimate 单个文件经过骨架提取与压缩后的大小
+type Estimate struct {
+	Chars  int
+	Tokens int
+}
+
+// EstimateFiles 按与生成相同的规则处理各文件并计数，不组装分段；结果与 files 一一对应
+func EstimateFiles(files []*scanner.FileInfo, cfg *config.Config, opts Options) ([]Estimate, error) {
+	tok, err := tokenizer.Load(cfg.Output.Tokenizer, cfg.Output.TokenizerFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载分词器失败: %w", err)
+	}
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	estimates := make([]Estimate, len(files))
+	progress := parallel.NewCounter(len(files), opts.Progress)
+	err = parallel.ForEachContext(ctx, len(files), cfg.Jobs, func(i int) {
+		file := files[i]
+		p := prepareCached(file, cfg, tok, opts.Cache)
+		estimates[i] = Estimate{Chars: len(p.content), Tokens: p.tokens}
+		if !p.diffOnly && file.Diff != "" && cfg.Output.DiffMode == "append" {
+			estimates[i].Chars += len(file.Diff)
+			estimates[i].Tokens += tok.Count(file.Diff)
+		}
+		progress.Done()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return estimates, nil
+}
+
+// EstimateParts 按每段限制粗略估算分段数；设置了 max_tokens 时按 token 计算，否则按字符计算
+func EstimateParts(chars, tokens int, cfg *config.Config) int {
+	total, limit := chars, cfg.Output.MaxChars
+	if cfg.Output.MaxTokens > 0 {
+		total, limit = tokens, cfg.Output.MaxTokens
+	}
+	if total == 0 {
+		return 0
+	}
+	if limit <= 0 {
+		return 1
+	}
+	return (total + limit - 1) / limit
+}
@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"printcode2llm/internal/config"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatXML      = "xml"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+)
+
+// ValidateFormat 检查输出格式名称
+func ValidateFormat(name string) error {
+	switch name {
+	case "", FormatMarkdown, FormatXML, FormatJSON, FormatJSONL:
+		return nil
+	}
+	return fmt.Errorf("无效的输出格式: %s (可选: %s/%s/%s/%s)", name, FormatMarkdown, FormatXML, FormatJSON, FormatJSONL)
+}
+
+// FormatExtension 输出格式对应的文件扩展名
+func FormatExtension(name string) string {
+	switch name {
+	case FormatXML:
+		return ".xml"
+	case FormatJSON:
+		return ".json"
+	case FormatJSONL:
+		return ".jsonl"
+	}
+	return ".md"
+}
+
+// document 渲染各分段时共用的项目信息
+type document struct {
+	projectName string
+	result      *Result
+	tree        string // 不含项目名的目录树，未启用时为空
+	time        time.Time
+	cfg         *config.Config
+}
+
+// segmentParts 一个分段的组成部分，最后一个分段在统计完成后带上 footer 重新拼接
+type segmentParts struct {
+	header  string
+	listing string
+	blocks  []string
+	notice  string
+	footer  string
+}
+
+// format 输出格式；分段逻辑与格式无关，只通过这些方法计量和渲染内容
+type format interface {
+	// header 分段开头，partNum 从 1 开始，total 在分段完成前未知时为 0
+	header(doc *document, partNum, total int) string
+	// listing 多部分时本段的文件清单，不支持时返回空
+	listing(chunks []*Chunk) string
+	// listingReserve 为文件清单标题预留的开销，不支持清单时为 0
+	listingReserve() int
+	listingEntry(block *fileBlock) string
+	fileBlock(chunk *Chunk) string
+	// encodeLine 单行内容（含换行）在输出中的形式，用于估算可以放入的行数
+	encodeLine(line string) string
+	continueNotice() string
+	footer(doc *document, segments []*Segment) string
+	join(parts *segmentParts) string
+}
+
+func newFormat(name string, cfg *config.Config) format {
+	switch name {
+	case FormatXML:
+		return &xmlFormat{}
+	case FormatJSON:
+		return &jsonFormat{}
+	case FormatJSONL:
+		return &jsonlFormat{}
+	}
+	return &markdownFormat{cfg: cfg}
+}
+
+// markdownFormat 默认的 Markdown 输出
+type markdownFormat struct {
+	cfg *config.Config
+}
+
+func (f *markdownFormat) header(doc *document, partNum, total int) string {
+	if partNum > 1 {
+		return generateContinuationHeader(doc.projectName, partNum, f.cfg)
+	}
+
+	header := generateHeaderTemplate(doc, f.cfg)
+	if doc.tree != "" {
+		header += generateTreeSection(doc.projectName, doc.tree, f.cfg)
+	}
+	header += generateSkippedSection(doc.result, f.cfg)
+	return header + fmt.Sprintf("## %s\n\n", f.cfg.Prompts.SectionCode)
+}
+
+func (f *markdownFormat) listing(chunks []*Chunk) string {
+	return buildFileListing(chunks)
+}
+
+func (f *markdownFormat) listingReserve() int {
+	return listingHeaderReserve
+}
+
+func (f *markdownFormat) listingEntry(block *fileBlock) string {
+	return listingEntry(block.fileNum, block.file.RelPath, block.suffix)
+}
+
+func (f *markdownFormat) fileBlock(chunk *Chunk) string {
+	return buildFileBlockContent(chunk, f.cfg)
+}
+
+func (f *markdownFormat) encodeLine(line string) string {
+	return line + "\n"
+}
+
+func (f *markdownFormat) continueNotice() string {
+	return generateContinueNotice(f.cfg)
+}
+
+func (f *markdownFormat) footer(doc *document, segments []*Segment) string {
+	return generateFooter(doc.result, segments, f.cfg)
+}
+
+func (f *markdownFormat) join(parts *segmentParts) string {
+	var builder strings.Builder
+	builder.WriteString(parts.header)
+	builder.WriteString(parts.listing)
+	for _, block := range parts.blocks {
+		builder.WriteString(block)
+	}
+	builder.WriteString(parts.notice)
+	builder.WriteString(parts.footer)
+	return builder.String()
+}
+
+// compressMode 压缩模式描述，未压缩时为空
+func compressMode(cfg *config.Config) string {
+	if !cfg.Output.Compress {
+		return ""
+	}
+	if cfg.Output.UltraCompress {
+		return "深度"
+	}
+	return "标准"
+}
+
+// chunkKind 片段类型，diff 片段为 "diff"，骨架为 "skeleton"，节选为 "excerpt"，正文为空
+func chunkKind(chunk *Chunk) string {
+	switch chunk.Suffix {
+	case diffSuffix:
+		return "diff"
+	case skeletonSuffix:
+		return "skeleton"
+	case truncatedSuffix:
+		return "excerpt"
+	}
+	return ""
+}
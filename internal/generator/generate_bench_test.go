@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/charset"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+)
+
+// benchFiles 生成 dirs*filesPerDir 个内存中的 Go 文件，每个约 lines 行，含注释与空行
+func benchFiles(dirs, filesPerDir, lines int) []*scanner.FileInfo {
+	var files []*scanner.FileInfo
+	for d := 0; d < dirs; d++ {
+		pkg := fmt.Sprintf("pkg%02d", d)
+		for f := 0; f < filesPerDir; f++ {
+			var b strings.Builder
+			fmt.Fprintf(&b, "// Package %s is generated for benchmarks.\npackage %s\n\nimport \"fmt\"\n\n", pkg, pkg)
+			for i := 0; b.Len() < lines*32; i++ {
+				fmt.Fprintf(&b, "// F%d_%d prints its argument.\n// It has a doc comment.\nfunc F%d_%d(x int) int {\n\t// body comment\n\tif x > %d {\n\t\tfmt.Println(\"value\", x)\n\t}\n\n\treturn x * %d\n}\n\n", f, i, f, i, i, i)
+			}
+			content := b.String()
+			relPath := fmt.Sprintf("%s/file%03d.go", pkg, f)
+			files = append(files, &scanner.FileInfo{
+				Path:      "/bench/" + relPath,
+				RelPath:   relPath,
+				Language:  "go",
+				Content:   content,
+				IsCode:    true,
+				LineCount: scanner.CountLines(content),
+				Size:      int64(len(content)),
+				Encoding:  charset.UTF8,
+			})
+		}
+	}
+	return files
+}
+
+// benchJobs 串行与使用全部核心两种并发数，单核机器上额外测 4 个 worker
+func benchJobs() []int {
+	n := runtime.NumCPU()
+	if n == 1 {
+		n = 4
+	}
+	return []int{1, n}
+}
+
+func BenchmarkGenerateWithOptions(b *testing.B) {
+	files := benchFiles(20, 20, 200)
+	dir := b.TempDir()
+
+	for _, jobs := range benchJobs() {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			cfg := config.Default()
+			cfg.Jobs = jobs
+			cfg.Output.IncludeTree = false
+			for i := 0; i < b.N; i++ {
+				result, err := GenerateWithOptions(dir, files, cfg, Options{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if result.FileCount != len(files) {
+					b.Fatalf("generated %d files, want %d", result.FileCount, len(files))
+				}
+			}
+		})
+	}
+}
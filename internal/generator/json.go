@@ -0,0 +1,295 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonInfo 项目信息，对应 Markdown 头部的项目信息列表
+type jsonInfo struct {
+	Time         string `json:"time"`
+	Files        int    `json:"files"`
+	CodeFiles    int    `json:"code_files"`
+	ConfigFiles  int    `json:"config_files"`
+	ChangedFiles int    `json:"changed_files,omitempty"`
+	Skeleton     int    `json:"skeleton_files,omitempty"`
+	Truncated    int    `json:"truncated_files,omitempty"`
+	Lines        int    `json:"lines"`
+	Chars        int    `json:"chars"`
+	Tokens       int    `json:"tokens"`
+	Tokenizer    string `json:"tokenizer"`
+	Estimated    bool   `json:"tokens_estimated,omitempty"` // tokens 是没有词表时的估算值
+	Compress     string `json:"compress,omitempty"`
+	LineNumbers  bool   `json:"line_numbers,omitempty"` // content 每行开头带有原文件中的行号
+}
+
+// jsonStats 统计信息，对应 Markdown 末尾的统计表
+type jsonStats struct {
+	Files       int            `json:"files"`
+	CodeFiles   int            `json:"code_files"`
+	ConfigFiles int            `json:"config_files"`
+	Lines       int            `json:"lines"`
+	Chars       int            `json:"chars"`
+	Tokens      int            `json:"tokens"`
+	Tokenizer   string         `json:"tokenizer"`
+	Estimated   bool           `json:"tokens_estimated,omitempty"`
+	Parts       int            `json:"parts"`
+	PartTokens  []int          `json:"part_tokens,omitempty"`
+	Excluded    []jsonExcluded `json:"excluded,omitempty"`
+}
+
+// jsonExcluded 被排除的路径，开启 report_excluded 时出现在统计信息中
+type jsonExcluded struct {
+	Path   string `json:"path"`
+	Dir    bool   `json:"dir,omitempty"`
+	Reason string `json:"reason"`
+	Rule   string `json:"rule,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// jsonFile 一个文件片段，行号与 Markdown 标题中的行号一致
+type jsonFile struct {
+	Type       string `json:"type,omitempty"`
+	Index      int    `json:"index"`
+	Path       string `json:"path"`
+	Language   string `json:"language,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Status     string `json:"status,omitempty"`
+	Encoding   string `json:"encoding,omitempty"` // 原始编码，UTF-8 时省略
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	TotalLines int    `json:"total_lines"`
+	Content    string `json:"content"`
+}
+
+// jsonProject 分段开头的项目记录，提示词、项目信息与目录树只出现在第一部分
+type jsonProject struct {
+	Type       string        `json:"type,omitempty"`
+	Project    string        `json:"project"`
+	Part       int           `json:"part"`
+	TotalParts int           `json:"total_parts"`
+	Prompt     string        `json:"prompt,omitempty"`
+	Info       *jsonInfo     `json:"info,omitempty"`
+	Tree       string        `json:"tree,omitempty"`
+	Skipped    []jsonSkipped `json:"skipped,omitempty"`
+}
+
+// jsonSkipped 未包含内容的文件
+type jsonSkipped struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+}
+
+func newJSONProject(doc *document, partNum, total int) *jsonProject {
+	project := &jsonProject{Project: doc.projectName, Part: partNum, TotalParts: total}
+	if partNum > 1 {
+		return project
+	}
+
+	result := doc.result
+	project.Prompt = doc.cfg.Prompts.HeaderPrompt
+	project.Info = &jsonInfo{
+		Time:         doc.time.Format("2006-01-02 15:04:05"),
+		Files:        result.FileCount,
+		CodeFiles:    result.CodeFiles,
+		ConfigFiles:  result.ConfigFiles,
+		ChangedFiles: result.ChangedFiles,
+		Skeleton:     result.SkeletonFiles,
+		Truncated:    result.TruncatedFiles,
+		Lines:        result.TotalLines,
+		Chars:        result.TotalChars,
+		Tokens:       result.TotalTokens,
+		Tokenizer:    result.Tokenizer,
+		Estimated:    result.TokensEstimated,
+		Compress:     compressMode(doc.cfg),
+		LineNumbers:  doc.cfg.Output.LineNumbers,
+	}
+	if doc.tree != "" {
+		project.Tree = doc.projectName + "/\n" + doc.tree
+	}
+	for _, s := range result.Skipped {
+		project.Skipped = append(project.Skipped, jsonSkipped{Path: s.RelPath, Size: s.Size, Reason: skipReason(s, doc.cfg)})
+	}
+	return project
+}
+
+func newJSONFile(chunk *Chunk) *jsonFile {
+	return &jsonFile{
+		Index:      chunk.FileNum,
+		Path:       chunk.File.RelPath,
+		Language:   chunk.Language,
+		Kind:       chunkKind(chunk),
+		Status:     chunk.File.ChangeStatus,
+		Encoding:   sourceEncoding(chunk.File),
+		StartLine:  chunk.StartLine,
+		EndLine:    chunk.EndLine,
+		TotalLines: chunk.TotalLines,
+		Content:    strings.Join(chunk.DisplayLines(), "\n"),
+	}
+}
+
+func newJSONStats(result *Result, segments []*Segment) *jsonStats {
+	stats := &jsonStats{
+		Files:       result.FileCount,
+		CodeFiles:   result.CodeFiles,
+		ConfigFiles: result.ConfigFiles,
+		Lines:       result.TotalLines,
+		Chars:       result.TotalChars,
+		Tokens:      result.TotalTokens,
+		Tokenizer:   result.Tokenizer,
+		Estimated:   result.TokensEstimated,
+		Parts:       len(segments),
+	}
+	if len(segments) > 1 {
+		for _, seg := range segments {
+			stats.PartTokens = append(stats.PartTokens, seg.TokenCount)
+		}
+	}
+	for _, e := range result.Excluded {
+		stats.Excluded = append(stats.Excluded, jsonExcluded{Path: e.RelPath, Dir: e.IsDir, Reason: e.Reason, Rule: e.Rule, Source: e.Source})
+	}
+	return stats
+}
+
+// marshalJSON 紧凑编码，不转义 HTML 字符，避免代码中的 < > & 变成 \u003c 等
+func marshalJSON(v interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		panic(fmt.Sprintf("编码 JSON 失败: %v", err))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// encodeJSONLine 单行内容在 JSON 字符串中的形式，包括转义后的换行
+func encodeJSONLine(line string) string {
+	quoted := marshalJSON(line)
+	return quoted[1:len(quoted)-1] + `\n`
+}
+
+// jsonFormat 每个分段是一个 JSON 对象，文件片段位于 files 数组中
+type jsonFormat struct{}
+
+func (f *jsonFormat) header(doc *document, partNum, total int) string {
+	project := newJSONProject(doc, partNum, total)
+
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	builder.WriteString(fmt.Sprintf("  \"project\": %s,\n", marshalJSON(project.Project)))
+	builder.WriteString(fmt.Sprintf("  \"part\": %d,\n", project.Part))
+	builder.WriteString(fmt.Sprintf("  \"total_parts\": %d,\n", project.TotalParts))
+	if project.Prompt != "" {
+		builder.WriteString(fmt.Sprintf("  \"prompt\": %s,\n", marshalJSON(project.Prompt)))
+	}
+	if project.Info != nil {
+		builder.WriteString(fmt.Sprintf("  \"info\": %s,\n", marshalJSON(project.Info)))
+	}
+	if project.Tree != "" {
+		builder.WriteString(fmt.Sprintf("  \"tree\": %s,\n", marshalJSON(project.Tree)))
+	}
+	if len(project.Skipped) > 0 {
+		builder.WriteString(fmt.Sprintf("  \"skipped\": %s,\n", marshalJSON(project.Skipped)))
+	}
+	builder.WriteString("  \"files\": [\n")
+	return builder.String()
+}
+
+func (f *jsonFormat) listing(chunks []*Chunk) string {
+	return ""
+}
+
+func (f *jsonFormat) listingReserve() int {
+	return 0
+}
+
+func (f *jsonFormat) listingEntry(block *fileBlock) string {
+	return ""
+}
+
+// fileBlock 带上数组元素之间的 ",\n"，分段时计入开销；最后一个元素的逗号在 join 时去掉
+func (f *jsonFormat) fileBlock(chunk *Chunk) string {
+	return "    " + marshalJSON(newJSONFile(chunk)) + ",\n"
+}
+
+func (f *jsonFormat) encodeLine(line string) string {
+	return encodeJSONLine(line)
+}
+
+func (f *jsonFormat) continueNotice() string {
+	return ",\n  \"continued\": true"
+}
+
+func (f *jsonFormat) footer(doc *document, segments []*Segment) string {
+	return ",\n  \"stats\": " + marshalJSON(newJSONStats(doc.result, segments))
+}
+
+func (f *jsonFormat) join(parts *segmentParts) string {
+	var builder strings.Builder
+	builder.WriteString(parts.header)
+	blocks := strings.Join(parts.blocks, "")
+	if blocks != "" {
+		builder.WriteString(strings.TrimSuffix(blocks, ",\n") + "\n")
+	}
+	builder.WriteString("  ]")
+	builder.WriteString(parts.notice)
+	builder.WriteString(parts.footer)
+	builder.WriteString("\n}\n")
+	return builder.String()
+}
+
+// jsonlFormat 每行一条记录：开头的 project 记录、每个文件片段一条 file 记录、最后的 stats 记录
+type jsonlFormat struct{}
+
+func (f *jsonlFormat) header(doc *document, partNum, total int) string {
+	project := newJSONProject(doc, partNum, total)
+	project.Type = "project"
+	return marshalJSON(project) + "\n"
+}
+
+func (f *jsonlFormat) listing(chunks []*Chunk) string {
+	return ""
+}
+
+func (f *jsonlFormat) listingReserve() int {
+	return 0
+}
+
+func (f *jsonlFormat) listingEntry(block *fileBlock) string {
+	return ""
+}
+
+func (f *jsonlFormat) fileBlock(chunk *Chunk) string {
+	file := newJSONFile(chunk)
+	file.Type = "file"
+	return marshalJSON(file) + "\n"
+}
+
+func (f *jsonlFormat) encodeLine(line string) string {
+	return encodeJSONLine(line)
+}
+
+func (f *jsonlFormat) continueNotice() string {
+	return ""
+}
+
+func (f *jsonlFormat) footer(doc *document, segments []*Segment) string {
+	stats := struct {
+		Type string `json:"type"`
+		*jsonStats
+	}{"stats", newJSONStats(doc.result, segments)}
+	return marshalJSON(stats) + "\n"
+}
+
+func (f *jsonlFormat) join(parts *segmentParts) string {
+	var builder strings.Builder
+	builder.WriteString(parts.header)
+	for _, block := range parts.blocks {
+		builder.WriteString(block)
+	}
+	builder.WriteString(parts.footer)
+	return builder.String()
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lineNumberSeparator 行号与代码之间的分隔，例如 " 12| fmt.Println()"
+const lineNumberSeparator = "|"
+
+// numberWidth 行号的显示宽度，取最大行号的位数
+func numberWidth(numbers []int) int {
+	max := 0
+	for _, n := range numbers {
+		if n > max {
+			max = n
+		}
+	}
+	return len(strconv.Itoa(max))
+}
+
+// numberLine 在行首加上右对齐的行号；没有对应的原文行时以空白代替行号，
+// 这样的空行（例如文件末尾的换行）保持为空行，有行号的空行不带尾随空格
+func numberLine(line string, number, width int) string {
+	prefix := strings.Repeat(" ", width)
+	if number > 0 {
+		prefix = fmt.Sprintf("%*d", width, number)
+	}
+	switch {
+	case line == "" && number == 0:
+		return ""
+	case line == "":
+		return prefix + lineNumberSeparator
+	}
+	return prefix + lineNumberSeparator + " " + line
+}
+
+// DisplayLines 输出时的各行，开启 line_numbers 时带有原文件中的行号
+func (c *Chunk) DisplayLines() []string {
+	if c.LineNumbers == nil {
+		return c.Lines
+	}
+	lines := make([]string, len(c.Lines))
+	for i, line := range c.Lines {
+		lines[i] = numberLine(line, c.LineNumbers[i], c.numberWidth)
+	}
+	return lines
+}
+
+// StripLineNumbers 去掉 numberLine 加上的行号；只有除空行外每一行都带有同样宽度的行号前缀、
+// 且至少一行有数字时才视为带行号的内容，否则原样返回 false
+func StripLineNumbers(lines []string) ([]string, bool) {
+	width := -1
+	numbered := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, lineNumberSeparator)
+		if i < 0 || (width >= 0 && i != width) {
+			return lines, false
+		}
+		number := strings.TrimLeft(line[:i], " ")
+		for _, r := range number {
+			if r < '0' || r > '9' {
+				return lines, false
+			}
+		}
+		if rest := line[i+len(lineNumberSeparator):]; rest != "" && rest[0] != ' ' {
+			return lines, false
+		}
+		width = i
+		numbered = numbered || number != ""
+	}
+	if !numbered || width == 0 {
+		return lines, false
+	}
+
+	stripped := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimRight(line, "\r") == "" {
+			stripped[i] = line
+			continue
+		}
+		rest := line[width+len(lineNumberSeparator):]
+		stripped[i] = strings.TrimPrefix(rest, " ")
+	}
+	return stripped, true
+}
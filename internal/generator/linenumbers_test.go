@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/redact"
+	"printcode2llm/internal/scanner"
+)
+
+// pemSource 第 3-8 行是私钥，遮盖后合并为一行；func main 在原文第 11 行。
+// 首尾两行拼接而成，本文件自身不会被识别为私钥
+const pemSource = `package main
+
+const key = ` + "`" + `-----BEGIN RSA ` + `PRIVATE KEY-----
+MIIEowIBAAKCAQEAx4UbaDzY5g8H1gYqEZj1gM0vHhZxkV3yH5q3pVBrQp2d8Jd3
+w0bLxLnP6D0Yc2qkH0yR8pQp6mLzjS5V6sW3cXyE3yI0dQJbN5K7kR3jHk7bY2pL
+lbQn1W2p3zW6cF2gYx7eBv8vQb4nR9tE5pS1dF7aL3mW0hJ6iS2qT8rU4vN1oK9c
+qM3zS7tX2eB5vL0hG8rQ4kJ1fW6cP9yA3nH5uD2sR8tI0mK7vZ4xB1eQ6jL3oN9p
+-----END RSA ` + `PRIVATE KEY-----` + "`" + `
+
+// main 入口
+func main() {}
+`
+
+func TestLineNumbersAfterMultilineRedaction(t *testing.T) {
+	cfg := config.Default()
+	cfg.Output.LineNumbers = true
+	file := &scanner.FileInfo{RelPath: "main.go", Path: "/p/main.go", Language: "go", IsCode: true, Content: pemSource, LineCount: scanner.CountLines(pemSource)}
+
+	r, err := redact.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports := r.Files([]*scanner.FileInfo{file}); len(reports) != 1 || !strings.Contains(file.Content, "[REDACTED:private-key:1]") {
+		t.Fatalf("私钥未被遮盖: %q", file.Content)
+	}
+
+	for _, compress := range []bool{false, true} {
+		cfg.Output.Compress = compress
+		result, err := Generate("/p", []*scanner.FileInfo{file}, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := result.Segments[0].Content
+		if !regexp.MustCompile(`(?m)^\s*11\| func main\(\) \{\}$`).MatchString(content) {
+			t.Errorf("compress=%v: func main 应标为第 11 行:\n%s", compress, content)
+		}
+		if !regexp.MustCompile(`(?m)^\s*3\| const key = .\[REDACTED`).MatchString(content) {
+			t.Errorf("compress=%v: 遮盖的私钥应标为第 3 行:\n%s", compress, content)
+		}
+	}
+
+	// 节选的行号与省略说明同样按原文计算
+	cfg.Output.Compress = false
+	cfg.MaxFileLines = 2
+	result, err := Generate("/p", []*scanner.FileInfo{file}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := result.Segments[0].Content
+	if !strings.Contains(content, "此处省略原文第 2-10 行") || !regexp.MustCompile(`(?m)^\s*11\| func main`).MatchString(content) {
+		t.Errorf("节选:\n%s", content)
+	}
+}
+
+func TestLineNumbersAfterSkeleton(t *testing.T) {
+	cfg := config.Default()
+	cfg.Output.LineNumbers = true
+	cfg.Output.Skeleton = true
+	src := "package a\n\n// F 说明\nfunc F(x int) int {\n\treturn x\n}\n\n// G 说明\nfunc G() {\n\tprintln()\n}\n"
+	file := &scanner.FileInfo{RelPath: "a.go", Path: "/p/a.go", Language: "go", IsCode: true, Content: src, LineCount: scanner.CountLines(src)}
+
+	result, err := Generate("/p", []*scanner.FileInfo{file}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := result.Segments[0].Content
+	for _, pattern := range []string{`(?m)^4\| func F\(x int\) int \{`, `(?m)^8\| // G 说明$`, `(?m)^9\| func G\(\) \{`} {
+		if !regexp.MustCompile(pattern).MatchString(content) {
+			t.Errorf("缺少 %s:\n%s", pattern, content)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+)
+
+// generateSkippedSection 列出过大或无法读取的文件，没有时为空
+func generateSkippedSection(result *Result, cfg *config.Config) string {
+	if len(result.Skipped) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("## %s\n\n", skippedSection(cfg)))
+	builder.WriteString("| 文件 | 大小 | 原因 |\n")
+	builder.WriteString("|------|------|------|\n")
+	for _, s := range result.Skipped {
+		builder.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", s.RelPath, formatSize(s.Size), skipReason(s, cfg)))
+	}
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
+// generateExcludedSection 文档末尾的排除项附录，未开启或没有排除项时为空
+func generateExcludedSection(result *Result, cfg *config.Config) string {
+	if len(result.Excluded) == 0 {
+		return ""
+	}
+
+	title := cfg.Prompts.SectionExcluded
+	if title == "" {
+		title = "排除的路径"
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("## %s\n\n", title))
+	builder.WriteString("| 路径 | 原因 | 规则 |\n")
+	builder.WriteString("|------|------|------|\n")
+	for _, e := range result.Excluded {
+		name := e.RelPath
+		if e.IsDir {
+			name += "/"
+		}
+		builder.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", name, e.Reason, exclusionRule(e)))
+	}
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
+// skippedSection 跳过文件一节的标题
+func skippedSection(cfg *config.Config) string {
+	if cfg.Prompts.SectionSkipped != "" {
+		return cfg.Prompts.SectionSkipped
+	}
+	return "跳过的文件"
+}
+
+// exclusionRule 排除项命中的规则与来源，用于附录
+func exclusionRule(e scanner.Exclusion) string {
+	rule := ""
+	if e.Rule != "" {
+		rule = "`" + strings.ReplaceAll(e.Rule, "`", "'") + "`"
+	}
+	if e.Source == "" {
+		return rule
+	}
+	if rule == "" {
+		return e.Source
+	}
+	return rule + " (" + e.Source + ")"
+}
+
+// skipReason 跳过原因，超过大小限制时附上限制值
+func skipReason(s scanner.SkippedFile, cfg *config.Config) string {
+	if s.Reason == scanner.SkipTooLarge && cfg.MaxFileSize > 0 {
+		return fmt.Sprintf("%s (%s)", s.Reason, formatSize(cfg.MaxFileSize))
+	}
+	return s.Reason
+}
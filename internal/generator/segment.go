@@ -1,22 +1,59 @@
 package generator
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"printcode2llm/internal/cache"
 	"printcode2llm/internal/compress"
 	"printcode2llm/internal/config"
+	"printcode2llm/internal/parallel"
 	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/skeleton"
+	"printcode2llm/internal/tokenizer"
 )
 
 type Segment struct {
 	Content   string
 	PartNum   int
 	TotalPart int
-	CharCount int
-	FileRange string
+	CharCount  int
+	TokenCount int
+	FileRange  string
+	Chunks    []*Chunk
+
+	parts *segmentParts
+}
+
+// Chunk 分段中的一个文件片段，行号基于（压缩后的）文件内容
+type Chunk struct {
+	FileNum     int
+	File        *scanner.FileInfo
+	Language    string
+	Suffix      string // 标题后缀，例如 diff 片段的 " (diff)"
+	Lines       []string
+	LineNumbers []int // 开启 line_numbers 时 Lines 各行在原文件中的行号，0 表示没有对应的行
+	StartLine   int
+	EndLine     int
+	TotalLines  int
+
+	// numberWidth 行号的显示宽度，同一文件的各片段一致
+	numberWidth int
+}
+
+// IsStart 是否从文件第一行开始
+func (c *Chunk) IsStart() bool {
+	return c.StartLine == 1
+}
+
+// IsComplete 是否包含文件全部内容
+func (c *Chunk) IsComplete() bool {
+	return c.StartLine == 1 && c.EndLine >= c.TotalLines
 }
 
 type Result struct {
@@ -26,27 +63,190 @@ type Result struct {
 	FileCount   int
 	TotalLines  int
 	TotalChars  int
+	TotalTokens int
+	Tokenizer   string
 	CodeFiles   int
 	ConfigFiles int
+	// ChangedFiles 变更模式下的文件数
+	ChangedFiles int
+	// SkeletonFiles 只输出骨架的文件数
+	SkeletonFiles int
+	// TruncatedFiles 超过 max_file_lines、只输出节选的文件数
+	TruncatedFiles int
+	// Skipped 过大或无法读取、未包含内容的文件
+	Skipped []scanner.SkippedFile
+	// Excluded 开启 report_excluded 时列在文档末尾的排除项
+	Excluded []scanner.Exclusion
+	// Warnings 生成过程中的警告，例如压缩结果校验失败
+	Warnings []string
+	// TokensEstimated token 数是没有词表时的估算值，输出中标注为“约”
+	TokensEstimated bool
 }
 
 type fileBlock struct {
+	index     int
 	fileNum   int
 	file      *scanner.FileInfo
+	language  string
+	suffix    string
 	content   string
 	lines     []string
 	startLine int
 	endLine   int
+	// numbers 各行在原文件中的行号，不显示行号时为 nil
+	numbers     []int
+	numberWidth int
+}
+
+// key 区分同一文件的正文与 diff 片段
+func (b *fileBlock) key() string {
+	return fmt.Sprintf("%d%s", b.fileNum, b.suffix)
+}
+
+// diffSuffix diff 片段标题后缀，解析时据此跳过
+const diffSuffix = " (diff)"
+
+// skeletonSuffix 骨架片段标题后缀，内容不是完整文件，还原时跳过
+const skeletonSuffix = " (骨架)"
+
+// useSkeleton 骨架模式下代码文件是否只输出骨架，skeleton_keep 中的文件保留全文
+func useSkeleton(file *scanner.FileInfo, cfg *config.Config) bool {
+	return cfg.Output.Skeleton && file.IsCode && !scanner.MatchKeep(cfg.Output.SkeletonKeep, file.RelPath)
+}
+
+// preparedFile 单个文件处理后的正文
+type preparedFile struct {
+	content  string
+	language string
+	suffix   string
+	tokens   int
+	// warning 处理过程中的警告，不含文件路径
+	warning string
+	// diffOnly 正文即 diff，不再追加 diff 片段
+	diffOnly bool
+	// lineNumbers 开启 line_numbers 时正文各行在原文件中的行号
+	lineNumbers []int
+}
+
+// prepareFormat 处理结果缓存的格式版本，加入缓存键
+//
+// 本地构建的 version.Version 总是 "dev"，不能依赖版本号让旧条目失效：修改压缩、骨架、截断、
+// 行号或敏感信息遮盖的逻辑，以及 cachedFile 的结构时，都需要递增
+const prepareFormat = "2"
+
+// cachedFile 缓存中保存的处理结果，同样内容的文件可以共用
+type cachedFile struct {
+	Content     string `json:"content"`
+	Suffix      string `json:"suffix,omitempty"`
+	Tokens      int    `json:"tokens"`
+	Warning     string `json:"warning,omitempty"`
+	LineNumbers []int  `json:"line_numbers,omitempty"`
+}
+
+// prepareCached 与 prepareFile 相同，结果按内容与影响输出的选项缓存
+func prepareCached(file *scanner.FileInfo, cfg *config.Config, tok tokenizer.Tokenizer, fc *cache.Cache) preparedFile {
+	if fc == nil || file.ChangeStatus == "D" || (file.Diff != "" && cfg.Output.DiffMode == "only") {
+		return prepareFile(file, cfg, tok)
+	}
+
+	key := cache.Key("prepare", prepareFormat, file.Content, file.Language, strconv.FormatBool(file.IsCode),
+		strconv.FormatBool(useSkeleton(file, cfg)), strconv.FormatBool(cfg.Output.Compress),
+		strconv.FormatBool(cfg.Output.UltraCompress), tok.Name(), cfg.Output.TokenizerFile,
+		strconv.Itoa(cfg.MaxFileLines), cfg.Truncate, strconv.FormatBool(cfg.Output.LineNumbers), fmt.Sprint(file.LineMap))
+	var entry cachedFile
+	if !fc.Get(key, &entry) {
+		p := prepareFile(file, cfg, tok)
+		entry = cachedFile{Content: p.content, Suffix: p.suffix, Tokens: p.tokens, Warning: p.warning, LineNumbers: p.lineNumbers}
+		fc.Put(key, entry)
+	}
+
+	return preparedFile{content: entry.Content, language: file.Language, suffix: entry.Suffix, tokens: entry.Tokens, warning: entry.Warning, lineNumbers: entry.LineNumbers}
+}
+
+// prepareFile 按配置生成文件的输出正文，可在多个 goroutine 中并行调用
+func prepareFile(file *scanner.FileInfo, cfg *config.Config, tok tokenizer.Tokenizer) preparedFile {
+	// 已删除的文件或只输出 diff 时，用 diff 代替正文
+	if file.ChangeStatus == "D" || (file.Diff != "" && cfg.Output.DiffMode == "only") {
+		return preparedFile{content: file.Diff, language: "diff", suffix: diffSuffix, tokens: tok.Count(file.Diff), diffOnly: true}
+	}
+
+	p := preparedFile{content: file.Content, language: file.Language}
+	if useSkeleton(file, cfg) {
+		if outline, ok := skeleton.Extract(p.content, file.Language); ok {
+			p.content, p.suffix = outline, skeletonSuffix
+		}
+	}
+	// 节选保留原文，省略说明中的行号与原文件一致
+	if p.suffix == "" && needsTruncate(file, cfg) {
+		p.content, p.suffix, p.lineNumbers = truncateFile(file, cfg)
+	}
+	// 骨架需要保留文档注释，不再压缩
+	if p.suffix == "" && cfg.Output.Compress && file.IsCode {
+		var compressed string
+		var err error
+		if cfg.Output.LineNumbers {
+			compressed, p.lineNumbers, err = compress.CompressLines(p.content, file.Language, cfg.Output.UltraCompress)
+		} else {
+			compressed, err = compress.CompressChecked(p.content, file.Language, cfg.Output.UltraCompress)
+		}
+		if err != nil {
+			p.warning = err.Error()
+		}
+		p.content = compressed
+	}
+	// 节选与压缩的行号已在处理时记录，骨架与未处理的正文按内容与原文对齐
+	// 遮盖敏感信息可能把多行合并为一行，压缩与对齐得到的行号再按 LineMap 换算为原文件中的行号，节选已在处理时换算
+	if !cfg.Output.LineNumbers {
+		p.lineNumbers = nil
+	} else if p.lineNumbers == nil {
+		p.lineNumbers = toOriginal(file.LineMap, compress.MapLines(file.Content, p.content, skeleton.Placeholder, skeleton.PythonPlaceholder))
+	} else if p.suffix == "" {
+		p.lineNumbers = toOriginal(file.LineMap, p.lineNumbers)
+	}
+	p.tokens = tok.Count(p.content)
+	return p
+}
+
+// Options 生成选项
+type Options struct {
+	// Cache 非空时按内容缓存骨架提取、压缩与 token 计数的结果
+	Cache *cache.Cache
+	// Context 非空时取消后尽快停止并返回 ctx.Err()
+	Context context.Context
+	// Progress 非空时每处理完一个文件回调一次
+	Progress func(done, total int)
+	// FS 非空时从这里生成目录树，projectDir 只用于显示项目名称与路径
+	FS fs.FS
+	// Skipped 扫描时跳过的文件，列在文档开头
+	Skipped []scanner.SkippedFile
+	// Excluded 扫描时排除的路径，开启 report_excluded 时列在文档末尾
+	Excluded []scanner.Exclusion
 }
 
 func Generate(projectDir string, files []*scanner.FileInfo, cfg *config.Config) (*Result, error) {
+	return GenerateWithOptions(projectDir, files, cfg, Options{})
+}
+
+// GenerateWithOptions 按选项生成
+func GenerateWithOptions(projectDir string, files []*scanner.FileInfo, cfg *config.Config, opts Options) (*Result, error) {
 	projectName := filepath.Base(projectDir)
 
+	tok, err := tokenizer.Load(cfg.Output.Tokenizer, cfg.Output.TokenizerFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载分词器失败: %w", err)
+	}
+
 	result := &Result{
 		ProjectName: projectName,
 		ProjectPath: projectDir,
 		Segments:    make([]*Segment, 0),
 		FileCount:   len(files),
+		Tokenizer:   tokenizer.Describe(tok),
+		Skipped:     opts.Skipped,
+	}
+	result.TokensEstimated = tokenizer.Estimated(tok)
+	if cfg.Output.ReportExcluded {
+		result.Excluded = opts.Excluded
 	}
 
 	for _, file := range files {
@@ -57,224 +257,111 @@ func Generate(projectDir string, files []*scanner.FileInfo, cfg *config.Config)
 		} else {
 			result.ConfigFiles++
 		}
+		if file.ChangeStatus != "" {
+			result.ChangedFiles++
+		}
+	}
+
+	// 骨架提取、压缩与 token 计数互不依赖，并行处理后按文件顺序组装
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	prepared := make([]preparedFile, len(files))
+	progress := parallel.NewCounter(len(files), opts.Progress)
+	err = parallel.ForEachContext(ctx, len(files), cfg.Jobs, func(i int) {
+		prepared[i] = prepareCached(files[i], cfg, tok, opts.Cache)
+		progress.Done()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	var allBlocks []fileBlock
-	for i, file := range files {
-		content := file.Content
-		if cfg.Output.Compress && file.IsCode {
-			content = compress.Compress(content, file.Language, cfg.Output.UltraCompress)
-		}
+	addBlock := func(fileNum int, file *scanner.FileInfo, content, language, suffix string, tokens int, numbers []int) {
+		result.TotalTokens += tokens
 
 		lines := strings.Split(content, "\n")
-		allBlocks = append(allBlocks, fileBlock{
-			fileNum:   i + 1,
+		block := fileBlock{
+			index:     len(allBlocks),
+			fileNum:   fileNum,
 			file:      file,
+			language:  language,
+			suffix:    suffix,
 			content:   content,
 			lines:     lines,
 			startLine: 1,
 			endLine:   len(lines),
-		})
-	}
-
-	headerTemplate := generateHeaderTemplate(projectName, result, cfg)
-	treeSection := ""
-	if cfg.Output.IncludeTree {
-		tree, err := GenerateTree(projectDir, cfg)
-		if err == nil {
-			treeSection = generateTreeSection(projectName, tree, cfg)
 		}
-	}
-
-	firstPartHeader := headerTemplate + treeSection + fmt.Sprintf("## %s\n\n", cfg.Prompts.SectionCode)
-	contHeader := func(partNum int) string {
-		return generateContinuationHeader(projectName, partNum, cfg)
-	}
-
-	maxChars := cfg.Output.MaxChars
-	segments := splitBlocksIntoSegments(allBlocks, maxChars, firstPartHeader, contHeader, cfg)
-
-	totalParts := len(segments)
-	for i, seg := range segments {
-		seg.PartNum = i + 1
-		seg.TotalPart = totalParts
-
-		if i == totalParts-1 {
-			seg.Content += generateFooter(result, totalParts, cfg)
+		if len(numbers) == len(lines) {
+			block.numbers, block.numberWidth = numbers, numberWidth(numbers)
 		}
+		allBlocks = append(allBlocks, block)
 	}
 
-	result.Segments = segments
-	return result, nil
-}
-
-func splitBlocksIntoSegments(blocks []fileBlock, maxChars int, firstHeader string, contHeader func(int) string, cfg *config.Config) []*Segment {
-	var segments []*Segment
-	var currentBuilder strings.Builder
-	currentChars := 0
-	partNum := 1
-
-	header := firstHeader
-	currentBuilder.WriteString(header)
-	currentChars = len(header)
-
-	blockIdx := 0
-	lineIdx := 0
-
-	for blockIdx < len(blocks) {
-		block := blocks[blockIdx]
-		file := block.file
-		fileNum := block.fileNum
-		lines := block.lines
-
-		for lineIdx < len(lines) {
-			remainingLines := lines[lineIdx:]
-			startLine := lineIdx + 1
-
-			blockContent := buildFileBlockContent(fileNum, file, remainingLines, startLine, startLine+len(remainingLines)-1, lineIdx == 0, cfg)
-			blockLen := len(blockContent)
-
-			if currentChars+blockLen <= maxChars {
-				currentBuilder.WriteString(blockContent)
-				currentChars += blockLen
-				lineIdx = len(lines)
-			} else {
-				availableChars := maxChars - currentChars
-				if availableChars < 500 {
-					segments = append(segments, &Segment{
-						Content:   currentBuilder.String() + generateContinueNotice(cfg),
-						CharCount: currentChars,
-					})
-
-					partNum++
-					currentBuilder.Reset()
-					header = contHeader(partNum)
-					currentBuilder.WriteString(header)
-					currentChars = len(header)
-					continue
-				}
-
-				linesForThisPart, charsUsed := fitLinesIntoChars(fileNum, file, remainingLines, startLine, availableChars, lineIdx == 0, cfg)
-
-				if linesForThisPart == 0 {
-					segments = append(segments, &Segment{
-						Content:   currentBuilder.String() + generateContinueNotice(cfg),
-						CharCount: currentChars,
-					})
-
-					partNum++
-					currentBuilder.Reset()
-					header = contHeader(partNum)
-					currentBuilder.WriteString(header)
-					currentChars = len(header)
-					continue
-				}
-
-				partialContent := buildFileBlockContent(fileNum, file, remainingLines[:linesForThisPart], startLine, startLine+linesForThisPart-1, lineIdx == 0, cfg)
-				currentBuilder.WriteString(partialContent)
-				currentChars += charsUsed
-
-				segments = append(segments, &Segment{
-					Content:   currentBuilder.String() + generateContinueNotice(cfg),
-					CharCount: currentChars,
-				})
-
-				partNum++
-				currentBuilder.Reset()
-				header = contHeader(partNum)
-				currentBuilder.WriteString(header)
-				currentChars = len(header)
-
-				lineIdx += linesForThisPart
-			}
+	for i, file := range files {
+		p := prepared[i]
+		if p.warning != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", file.RelPath, p.warning))
 		}
+		switch p.suffix {
+		case skeletonSuffix:
+			result.SkeletonFiles++
+		case truncatedSuffix:
+			result.TruncatedFiles++
+		}
+		addBlock(i+1, file, p.content, p.language, p.suffix, p.tokens, p.lineNumbers)
 
-		blockIdx++
-		lineIdx = 0
-	}
-
-	if currentChars > len(contHeader(partNum)) {
-		segments = append(segments, &Segment{
-			Content:   currentBuilder.String(),
-			CharCount: currentChars,
-		})
-	}
-
-	return segments
-}
-
-func fitLinesIntoChars(fileNum int, file *scanner.FileInfo, lines []string, startLine, availableChars int, isStart bool, cfg *config.Config) (int, int) {
-	if len(lines) == 0 {
-		return 0, 0
-	}
-
-	headerSize := estimateBlockHeaderSize(fileNum, file, startLine, startLine, isStart, cfg)
-	footerSize := 10
-
-	usableChars := availableChars - headerSize - footerSize
-	if usableChars <= 0 {
-		return 0, 0
-	}
-
-	charCount := 0
-	lineCount := 0
-
-	for i, line := range lines {
-		lineLen := len(line) + 1
-		if charCount+lineLen > usableChars {
-			break
+		// diff 自带行号信息，不再标注
+		if !p.diffOnly && file.Diff != "" && cfg.Output.DiffMode == "append" {
+			addBlock(i+1, file, file.Diff, "diff", diffSuffix, tok.Count(file.Diff), nil)
 		}
-		charCount += lineLen
-		lineCount = i + 1
 	}
 
-	if lineCount == 0 && len(lines) > 0 {
-		lineCount = 1
-		charCount = len(lines[0]) + 1
+	doc := &document{
+		projectName: projectName,
+		result:      result,
+		time:        time.Now(),
+		cfg:         cfg,
 	}
-
-	totalChars := headerSize + charCount + footerSize
-	return lineCount, totalChars
-}
-
-func estimateBlockHeaderSize(fileNum int, file *scanner.FileInfo, startLine, endLine int, isStart bool, cfg *config.Config) int {
-	var builder strings.Builder
-
-	if isStart {
-		builder.WriteString(fmt.Sprintf("### %d. %s\n\n", fileNum, file.RelPath))
-	} else {
-		builder.WriteString(fmt.Sprintf("### %d. %s (续: 行 %d-%d)\n\n", fileNum, file.RelPath, startLine, endLine))
+	if cfg.Output.IncludeTree {
+		var tree string
+		var err error
+		if opts.FS != nil {
+			tree, err = GenerateTreeFS(opts.FS, cfg, changeMarks(files))
+		} else {
+			tree, err = GenerateTreeWithMarks(projectDir, cfg, changeMarks(files))
+		}
+		if err == nil {
+			doc.tree = tree
+		}
 	}
 
-	builder.WriteString(fmt.Sprintf("```%s\n", file.Language))
+	f := newFormat(cfg.Output.Format, cfg)
+	segments := splitBlocksIntoSegments(allBlocks, newBudget(cfg, tok), f, doc, cfg)
 
-	return builder.Len()
-}
-
-func buildFileBlockContent(fileNum int, file *scanner.FileInfo, lines []string, startLine, endLine int, isStart bool, cfg *config.Config) string {
-	var builder strings.Builder
+	totalParts := len(segments)
+	for i, seg := range segments {
+		seg.PartNum = i + 1
+		seg.TotalPart = totalParts
 
-	if isStart {
-		if startLine == 1 && endLine >= len(strings.Split(file.Content, "\n")) {
-			builder.WriteString(fmt.Sprintf("### %d. %s\n\n", fileNum, file.RelPath))
-		} else {
-			builder.WriteString(fmt.Sprintf("### %d. %s (行 %d-%d)\n\n", fileNum, file.RelPath, startLine, endLine))
-		}
-	} else {
-		builder.WriteString(fmt.Sprintf("### %d. %s (续: 行 %d-%d)\n\n", fileNum, file.RelPath, startLine, endLine))
+		seg.TokenCount = tok.Count(seg.Content)
 	}
 
-	builder.WriteString(fmt.Sprintf("```%s\n", file.Language))
-	builder.WriteString(strings.Join(lines, "\n"))
-	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
-		builder.WriteString("\n")
+	if totalParts > 0 {
+		last := segments[totalParts-1]
+		last.parts.footer = f.footer(doc, segments)
+		last.Content = f.join(last.parts)
+		last.CharCount = len(last.Content)
+		last.TokenCount = tok.Count(last.Content)
 	}
-	builder.WriteString("```\n\n")
 
-	return builder.String()
+	result.Segments = segments
+	return result, nil
 }
 
-func generateHeaderTemplate(projectName string, result *Result, cfg *config.Config) string {
+func generateHeaderTemplate(doc *document, cfg *config.Config) string {
+	projectName, result := doc.projectName, doc.result
 	var builder strings.Builder
 
 	builder.WriteString(fmt.Sprintf("# %s\n\n", projectName))
@@ -286,18 +373,31 @@ func generateHeaderTemplate(projectName string, result *Result, cfg *config.Conf
 
 	builder.WriteString(fmt.Sprintf("## %s\n\n", cfg.Prompts.SectionInfo))
 	builder.WriteString(fmt.Sprintf("- **项目**: %s\n", projectName))
-	builder.WriteString(fmt.Sprintf("- **时间**: %s\n", time.Now().Format("2006-01-02 15:04:05")))
+	builder.WriteString(fmt.Sprintf("- **时间**: %s\n", doc.time.Format("2006-01-02 15:04:05")))
 	builder.WriteString(fmt.Sprintf("- **文件**: %d (代码: %d, 配置: %d)\n", result.FileCount, result.CodeFiles, result.ConfigFiles))
 	builder.WriteString(fmt.Sprintf("- **行数**: %s\n", formatNumber(result.TotalLines)))
 	builder.WriteString(fmt.Sprintf("- **字符**: %s\n", formatNumber(result.TotalChars)))
+	builder.WriteString(fmt.Sprintf("- **Token**: %s (%s)\n", formatTokens(result, result.TotalTokens), result.Tokenizer))
+	if result.ChangedFiles > 0 {
+		builder.WriteString(fmt.Sprintf("- **变更**: %d 个文件，目录树中以 [A]/[M]/[R] 标记\n", result.ChangedFiles))
+	}
 
-	if cfg.Output.Compress {
-		mode := "标准"
-		if cfg.Output.UltraCompress {
-			mode = "深度"
-		}
+	if mode := compressMode(cfg); mode != "" {
 		builder.WriteString(fmt.Sprintf("- **压缩**: %s\n", mode))
 	}
+	if result.SkeletonFiles > 0 {
+		builder.WriteString(fmt.Sprintf("- **骨架**: %d 个文件只保留声明与签名，函数体以 `%s` 代替，标题标有%s\n", result.SkeletonFiles, skeleton.Placeholder, skeletonSuffix))
+	}
+	if result.TruncatedFiles > 0 {
+		builder.WriteString(fmt.Sprintf("- **节选**: %d 个文件超过 %d 行，%s，标题标有%s，省略处以 ⋯ 注明原文行号\n",
+			result.TruncatedFiles, cfg.MaxFileLines, truncateDescription(cfg.Truncate), truncatedSuffix))
+	}
+	if cfg.Output.LineNumbers {
+		builder.WriteString(fmt.Sprintf("- **行号**: 代码行开头的 `N%s ` 是原文件中的行号，不属于代码内容；压缩、节选与拆分后仍对应原文件\n", lineNumberSeparator))
+	}
+	if len(result.Skipped) > 0 {
+		builder.WriteString(fmt.Sprintf("- **跳过**: %d 个文件未包含内容，见「%s」\n", len(result.Skipped), skippedSection(cfg)))
+	}
 
 	builder.WriteString("\n")
 
@@ -334,7 +434,9 @@ func generateContinueNotice(cfg *config.Config) string {
 	return "\n---\n\n> 📋 内容续下一部分\n\n"
 }
 
-func generateFooter(result *Result, totalParts int, cfg *config.Config) string {
+func generateFooter(result *Result, segments []*Segment, cfg *config.Config) string {
+	totalParts := len(segments)
+
 	var builder strings.Builder
 
 	builder.WriteString("\n---\n\n")
@@ -348,12 +450,17 @@ func generateFooter(result *Result, totalParts int, cfg *config.Config) string {
 	builder.WriteString(fmt.Sprintf("| 配置文件 | %d |\n", result.ConfigFiles))
 	builder.WriteString(fmt.Sprintf("| 总行数 | %s |\n", formatNumber(result.TotalLines)))
 	builder.WriteString(fmt.Sprintf("| 总字符 | %s |\n", formatNumber(result.TotalChars)))
+	builder.WriteString(fmt.Sprintf("| 总 Token (%s) | %s |\n", result.Tokenizer, formatTokens(result, result.TotalTokens)))
 
 	if totalParts > 1 {
 		builder.WriteString(fmt.Sprintf("| 分段数 | %d |\n", totalParts))
+		for _, seg := range segments {
+			builder.WriteString(fmt.Sprintf("| 第 %d 部分 Token | %s |\n", seg.PartNum, formatTokens(result, seg.TokenCount)))
+		}
 	}
 
 	builder.WriteString("\n")
+	builder.WriteString(generateExcludedSection(result, cfg))
 
 	return builder.String()
 }
@@ -385,4 +492,23 @@ func formatNumber(n int) string {
 		result = append(result, byte(ch))
 	}
 	return string(result)
-}
\ No newline at end of file
+}
+
+// formatTokens 格式化 token 数，估算值前加“约”
+func formatTokens(result *Result, n int) string {
+	if result.TokensEstimated {
+		return "约 " + formatNumber(n)
+	}
+	return formatNumber(n)
+}
+
+// changeMarks 变更模式下目录树中的标记
+func changeMarks(files []*scanner.FileInfo) map[string]string {
+	marks := make(map[string]string)
+	for _, file := range files {
+		if file.ChangeStatus != "" && file.ChangeStatus != "D" {
+			marks[file.RelPath] = file.ChangeStatus
+		}
+	}
+	return marks
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+)
+
+// timeLine 头部的生成时间，比较两次生成的结果时去掉
+var timeLine = regexp.MustCompile(`(?m)^- \*\*时间\*\*: .*$`)
+
+func TestPrepareCachedMatchesUncached(t *testing.T) {
+	fc, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := benchFiles(2, 3, 40)
+	cfg := config.Default()
+	cfg.Output.LineNumbers = true
+	dir := t.TempDir()
+
+	want, err := GenerateWithOptions(dir, files, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 2; run++ {
+		got, err := GenerateWithOptions(dir, files, cfg, Options{Cache: fc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Segments) != len(want.Segments) || got.TotalTokens != want.TotalTokens {
+			t.Fatalf("第 %d 次: %d 段 %d token, want %d 段 %d token", run+1, len(got.Segments), got.TotalTokens, len(want.Segments), want.TotalTokens)
+		}
+		for i := range want.Segments {
+			if timeLine.ReplaceAllString(got.Segments[i].Content, "") != timeLine.ReplaceAllString(want.Segments[i].Content, "") {
+				t.Fatalf("第 %d 次: 第 %d 段内容不同", run+1, i+1)
+			}
+		}
+	}
+	if fc.Hits() != len(files) || fc.Misses() != len(files) {
+		t.Errorf("hits=%d misses=%d, want %d/%d", fc.Hits(), fc.Misses(), len(files), len(files))
+	}
+}
+
+func TestFooterFitsBudget(t *testing.T) {
+	var excluded []scanner.Exclusion
+	for i := 0; i < 60; i++ {
+		excluded = append(excluded, scanner.Exclusion{RelPath: fmt.Sprintf("vendor/lib%02d", i), IsDir: true, Reason: "忽略规则", Rule: "vendor/", Source: "ignore_dirs"})
+	}
+
+	for _, format := range []string{FormatMarkdown, FormatXML, FormatJSON, FormatJSONL} {
+		for _, maxTokens := range []int{0, 2500} {
+			// 不同的文件大小让最后一部分剩余的空间各不相同，其中总有接近用满的情况
+			for lines := 20; lines <= 80; lines += 7 {
+				cfg := config.Default()
+				cfg.Output.Format = format
+				cfg.Output.Compress = false
+				cfg.Output.IncludeTree = false
+				cfg.Output.ReportExcluded = true
+				cfg.Output.MaxChars = 8000
+				cfg.Output.MaxTokens = maxTokens
+
+				result, err := GenerateWithOptions(t.TempDir(), benchFiles(1, 6, lines), cfg, Options{Excluded: excluded})
+				if err != nil {
+					t.Fatal(err)
+				}
+				last := result.Segments[len(result.Segments)-1]
+				if !strings.Contains(last.Content, "vendor/lib59") {
+					t.Errorf("%s lines=%d: 最后一部分缺少排除项附录", format, lines)
+				}
+				for _, seg := range result.Segments {
+					size, limit := seg.CharCount, cfg.Output.MaxChars
+					if maxTokens > 0 {
+						size, limit = seg.TokenCount, maxTokens
+					}
+					if size > limit {
+						t.Errorf("%s max_tokens=%d lines=%d: 第 %d/%d 部分 %d 超过限制 %d", format, maxTokens, lines, seg.PartNum, seg.TotalPart, size, limit)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/tokenizer"
+)
+
+const (
+	// listingHeaderReserve 为文件清单标题预留的长度
+	listingHeaderReserve = 48
+	// listingRangeReserve 为清单中 "(续: 行 a-b)" 预留的长度
+	listingRangeReserve = 32
+)
+
+// budget 分段预算，按字符或 token 计量
+type budget struct {
+	limit   int
+	measure func(string) int
+	// minSplit 剩余空间小于该值时不再拆分文件，直接换到下一部分
+	minSplit int
+}
+
+// newBudget 设置了 max_tokens 时按 token 计量，否则按字符计量
+func newBudget(cfg *config.Config, tok tokenizer.Tokenizer) budget {
+	if cfg.Output.MaxTokens > 0 {
+		return budget{limit: cfg.Output.MaxTokens, measure: tok.Count, minSplit: 125}
+	}
+	return budget{limit: cfg.Output.MaxChars, measure: func(s string) int { return len(s) }, minSplit: 500}
+}
+
+// segmentDraft 尚未渲染的分段
+type segmentDraft struct {
+	chunks []*Chunk
+	blocks []string
+	used   int
+	listed map[string]bool
+	// footerOnly 只放结尾统计与附录的部分，没有文件片段也要保留
+	footerOnly bool
+}
+
+// segmentWriter 负责把文件片段装入分段，并在结束时统一渲染
+type segmentWriter struct {
+	budget
+	format format
+	doc    *document
+	drafts []*segmentDraft
+	cur    *segmentDraft
+}
+
+func newSegmentWriter(b budget, f format, doc *document) *segmentWriter {
+	w := &segmentWriter{
+		budget: b,
+		format: f,
+		doc:    doc,
+	}
+	w.start()
+	return w
+}
+
+func (w *segmentWriter) start() {
+	w.cur = &segmentDraft{
+		used:   w.overhead(len(w.drafts) + 1),
+		listed: make(map[string]bool),
+	}
+	w.drafts = append(w.drafts, w.cur)
+}
+
+// overhead 第 partNum 部分除文件片段外的固定开销：头部、清单标题、续段提示与结尾
+func (w *segmentWriter) overhead(partNum int) int {
+	parts := &segmentParts{
+		header: w.format.header(w.doc, partNum, 0),
+		notice: w.format.continueNotice(),
+	}
+	return w.measure(w.format.join(parts)) + w.format.listingReserve()
+}
+
+// empty 当前分段是否还没有任何内容
+func (w *segmentWriter) empty() bool {
+	return len(w.cur.chunks) == 0
+}
+
+// remaining 当前分段剩余可用空间
+func (w *segmentWriter) remaining() int {
+	return w.limit - w.cur.used
+}
+
+// capacity 一个新的续段可以容纳的内容大小
+func (w *segmentWriter) capacity() int {
+	return w.limit - w.overhead(len(w.drafts)+1)
+}
+
+// next 结束当前分段并开始新的分段，空分段会被复用
+func (w *segmentWriter) next() {
+	if w.empty() {
+		return
+	}
+	w.start()
+}
+
+// entryCost 文件首次出现在当前分段时清单条目的开销
+func (w *segmentWriter) entryCost(block *fileBlock) int {
+	if w.cur.listed[block.key()] {
+		return 0
+	}
+	entry := w.format.listingEntry(block)
+	if entry == "" {
+		return 0
+	}
+	return w.measure(entry) + listingRangeReserve
+}
+
+// chunkCost 片段在当前分段中的总开销
+func (w *segmentWriter) chunkCost(block *fileBlock, from, to int) int {
+	return w.measure(w.format.fileBlock(newChunk(block, from, to))) + w.entryCost(block)
+}
+
+func (w *segmentWriter) add(block *fileBlock, from, to int) {
+	chunk := newChunk(block, from, to)
+	content := w.format.fileBlock(chunk)
+
+	w.cur.used += w.measure(content) + w.entryCost(block)
+	w.cur.listed[block.key()] = true
+	w.cur.chunks = append(w.cur.chunks, chunk)
+	w.cur.blocks = append(w.cur.blocks, content)
+}
+
+// writeSplit 按行拆分写入文件，从 from 行（0 起）开始，放不下时换段
+func (w *segmentWriter) writeSplit(block *fileBlock, from int) {
+	lines := block.lines
+
+	for from < len(lines) {
+		if cost := w.chunkCost(block, from, len(lines)); cost <= w.remaining() {
+			w.add(block, from, len(lines))
+			return
+		}
+
+		available := w.remaining() - w.entryCost(block)
+		if available < w.minSplit && !w.empty() {
+			w.next()
+			continue
+		}
+
+		count := w.fitLines(block, from, available)
+		if count == 0 {
+			if w.empty() {
+				count = 1
+			} else {
+				w.next()
+				continue
+			}
+		}
+
+		w.add(block, from, from+count)
+		w.next()
+		from += count
+	}
+}
+
+// reserveFooter 最后一部分结尾还要加上统计与排除项附录，放不下时为其单独开始一个部分；
+// 各部分的 token 数要等分段完成后才知道，这里按每部分用满预算估算
+func (w *segmentWriter) reserveFooter() {
+	if w.empty() || w.footerCost(len(w.drafts)) <= w.remaining() {
+		return
+	}
+	w.start()
+	w.cur.footerOnly = true
+}
+
+// footerCost 共 parts 个部分时结尾的开销
+func (w *segmentWriter) footerCost(parts int) int {
+	segments := make([]*Segment, parts)
+	for i := range segments {
+		segments[i] = &Segment{PartNum: i + 1, TokenCount: w.limit}
+	}
+	return w.measure(w.format.footer(w.doc, segments))
+}
+
+// finish 渲染全部分段，多于一个分段时在头部列出本段包含的文件
+func (w *segmentWriter) finish() []*Segment {
+	drafts := w.drafts
+	if last := drafts[len(drafts)-1]; len(drafts) > 1 && len(last.chunks) == 0 && !last.footerOnly {
+		drafts = drafts[:len(drafts)-1]
+	}
+
+	segments := make([]*Segment, 0, len(drafts))
+	for i, draft := range drafts {
+		parts := &segmentParts{
+			header: w.format.header(w.doc, i+1, len(drafts)),
+			blocks: draft.blocks,
+		}
+		if len(drafts) > 1 && len(draft.chunks) > 0 {
+			parts.listing = w.format.listing(draft.chunks)
+		}
+		if i < len(drafts)-1 {
+			parts.notice = w.format.continueNotice()
+		}
+
+		content := w.format.join(parts)
+		segments = append(segments, &Segment{
+			Content:   content,
+			CharCount: len(content),
+			FileRange: fileRange(draft.chunks),
+			Chunks:    draft.chunks,
+			parts:     parts,
+		})
+	}
+
+	return segments
+}
+
+func newChunk(block *fileBlock, from, to int) *Chunk {
+	chunk := &Chunk{
+		FileNum:     block.fileNum,
+		File:        block.file,
+		Language:    block.language,
+		Suffix:      block.suffix,
+		Lines:       block.lines[from:to],
+		StartLine:   from + 1,
+		EndLine:     to,
+		TotalLines:  len(block.lines),
+		numberWidth: block.numberWidth,
+	}
+	if block.numbers != nil {
+		chunk.LineNumbers = block.numbers[from:to]
+	}
+	return chunk
+}
+
+func splitBlocksIntoSegments(blocks []fileBlock, b budget, f format, doc *document, cfg *config.Config) []*Segment {
+	w := newSegmentWriter(b, f, doc)
+
+	switch cfg.Output.SplitMode {
+	case "file":
+		splitByFile(w, blocks)
+	case "balanced":
+		splitBalanced(w, blocks)
+	default:
+		splitByChar(w, blocks)
+	}
+	w.reserveFooter()
+
+	return w.finish()
+}
+
+// splitByChar 按字符数填满每一部分，文件可在任意行处拆开
+func splitByChar(w *segmentWriter, blocks []fileBlock) {
+	for i := range blocks {
+		w.writeSplit(&blocks[i], 0)
+	}
+}
+
+// splitByFile 以文件为单位装入分段，只有单个文件超出限制时才拆分
+func splitByFile(w *segmentWriter, blocks []fileBlock) {
+	for i := range blocks {
+		block := &blocks[i]
+		cost := w.chunkCost(block, 0, len(block.lines))
+
+		if cost <= w.remaining() {
+			w.add(block, 0, len(block.lines))
+			continue
+		}
+
+		if !w.empty() && cost <= w.capacity() {
+			w.next()
+			w.add(block, 0, len(block.lines))
+			continue
+		}
+
+		w.next()
+		w.writeSplit(block, 0)
+	}
+}
+
+// packItem 装箱单元：一个目录下的若干文件或单个文件
+type packItem struct {
+	blocks []*fileBlock
+	cost   int
+}
+
+// packBin 一个分段内装入的文件
+type packBin struct {
+	blocks []*fileBlock
+	free   int
+}
+
+// splitBalanced 在尽量少的分段数下，把同一目录的文件放在一起
+func splitBalanced(w *segmentWriter, blocks []fileBlock) {
+	firstCap := w.remaining()
+	contCap := w.limit - w.overhead(len(blocks)+1)
+
+	costs := make(map[*fileBlock]int, len(blocks))
+	var fitting, oversized []*fileBlock
+	for i := range blocks {
+		block := &blocks[i]
+		cost := w.chunkCost(block, 0, len(block.lines))
+		costs[block] = cost
+		if cost > contCap && cost > firstCap {
+			oversized = append(oversized, block)
+		} else {
+			fitting = append(fitting, block)
+		}
+	}
+
+	items := groupByDirectory(fitting, costs, contCap, 0)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].cost > items[j].cost
+	})
+
+	// 首次适应递减：第一个分段带有项目头和目录树，容量单独计算
+	var bins []*packBin
+	for _, item := range items {
+		placed := false
+		for _, bin := range bins {
+			if item.cost <= bin.free {
+				bin.blocks = append(bin.blocks, item.blocks...)
+				bin.free -= item.cost
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		capacity := contCap
+		if len(bins) == 0 {
+			capacity = firstCap
+		}
+		if item.cost > capacity {
+			capacity = contCap
+		}
+		bins = append(bins, &packBin{blocks: item.blocks, free: capacity - item.cost})
+	}
+
+	// 第一个分段保持在最前，其余单元按包含的最小文件序号排序，尽量贴近目录顺序
+	type unit struct {
+		key   int
+		bin   *packBin
+		block *fileBlock
+	}
+	var units []unit
+	for _, bin := range bins {
+		sort.Slice(bin.blocks, func(i, j int) bool {
+			return bin.blocks[i].index < bin.blocks[j].index
+		})
+		units = append(units, unit{key: bin.blocks[0].index, bin: bin})
+	}
+	for _, block := range oversized {
+		units = append(units, unit{key: block.index, block: block})
+	}
+	if len(units) > 1 {
+		rest := units[1:]
+		if len(bins) == 0 {
+			rest = units
+		}
+		sort.SliceStable(rest, func(i, j int) bool {
+			return rest[i].key < rest[j].key
+		})
+	}
+
+	for _, u := range units {
+		w.next()
+		if u.block != nil {
+			w.writeSplit(u.block, 0)
+			continue
+		}
+		for _, block := range u.bin.blocks {
+			if w.chunkCost(block, 0, len(block.lines)) <= w.remaining() {
+				w.add(block, 0, len(block.lines))
+			} else {
+				w.writeSplit(block, 0)
+			}
+		}
+	}
+}
+
+// groupByDirectory 按目录层级分组，整组放不进一个分段时再向下一层拆分
+func groupByDirectory(blocks []*fileBlock, costs map[*fileBlock]int, capacity, depth int) []packItem {
+	groups := make(map[string][]*fileBlock)
+	var keys []string
+	var items []packItem
+
+	for _, block := range blocks {
+		parts := strings.Split(path.Dir(block.file.RelPath), "/")
+		if parts[0] == "." || depth >= len(parts) {
+			items = append(items, packItem{blocks: []*fileBlock{block}, cost: costs[block]})
+			continue
+		}
+
+		key := parts[depth]
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], block)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		total := 0
+		for _, block := range group {
+			total += costs[block]
+		}
+
+		if total <= capacity {
+			items = append(items, packItem{blocks: group, cost: total})
+		} else {
+			items = append(items, groupByDirectory(group, costs, capacity, depth+1)...)
+		}
+	}
+
+	return items
+}
+
+// fitLines 计算从 from 行开始能放入 available 预算的行数
+func (w *segmentWriter) fitLines(block *fileBlock, from, available int) int {
+	lines := block.lines[from:]
+	if len(lines) == 0 {
+		return 0
+	}
+
+	usable := available - w.blockOverhead(block, from)
+	if usable <= 0 {
+		return 0
+	}
+
+	used := 0
+	lineCount := 0
+
+	for i, line := range lines {
+		if block.numbers != nil {
+			line = numberLine(line, block.numbers[from+i], block.numberWidth)
+		}
+		lineLen := w.measure(w.format.encodeLine(line))
+		if used+lineLen > usable {
+			break
+		}
+		used += lineLen
+		lineCount = i + 1
+	}
+
+	// 按行累加只是估算，超出时逐行回退
+	for lineCount > 0 && w.chunkCost(block, from, from+lineCount) > available+w.entryCost(block) {
+		lineCount--
+	}
+
+	return lineCount
+}
+
+// blockOverhead 不含内容的片段开销，行号按最大值估算，保证实际标题不会更长
+func (w *segmentWriter) blockOverhead(block *fileBlock, from int) int {
+	total := len(block.lines)
+	chunk := newChunk(block, from, from)
+	chunk.StartLine = total
+	chunk.EndLine = total
+	chunk.TotalLines = total + 1
+	if from == 0 {
+		chunk.StartLine = 1
+	}
+	return w.measure(w.format.fileBlock(chunk))
+}
+
+func buildFileBlockContent(chunk *Chunk, cfg *config.Config) string {
+	var builder strings.Builder
+
+	fence := CodeFence(chunk.Lines)
+
+	builder.WriteString("### " + chunkTitle(chunk) + "\n\n")
+	for _, note := range chunkNotes(chunk) {
+		builder.WriteString("> " + note + "\n\n")
+	}
+	lines := chunk.DisplayLines()
+	builder.WriteString(fence + chunk.Language + "\n")
+	builder.WriteString(strings.Join(lines, "\n"))
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString(fence + "\n\n")
+
+	return builder.String()
+}
+
+func listingEntry(fileNum int, relPath, rangeLabel string) string {
+	return fmt.Sprintf("> - %d. `%s`%s\n", fileNum, relPath, rangeLabel)
+}
+
+// buildFileListing 生成分段头部的文件清单
+func buildFileListing(chunks []*Chunk) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("> 本部分包含 %d 个文件:\n>\n", len(chunks)))
+	for _, chunk := range chunks {
+		builder.WriteString(listingEntry(chunk.FileNum, chunk.File.RelPath, chunk.Suffix+chunkRangeLabel(chunk)))
+	}
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
+// fileRange 将片段的文件序号压缩为区间描述，例如 "1-3, 7"
+func fileRange(chunks []*Chunk) string {
+	var nums []int
+	seen := make(map[int]bool)
+	for _, chunk := range chunks {
+		if !seen[chunk.FileNum] {
+			seen[chunk.FileNum] = true
+			nums = append(nums, chunk.FileNum)
+		}
+	}
+	sort.Ints(nums)
+
+	var parts []string
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprintf("%d", nums[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", nums[i], nums[j]))
+		}
+		i = j + 1
+	}
+
+	return strings.Join(parts, ", ")
+}
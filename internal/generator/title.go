@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"printcode2llm/internal/charset"
+	"printcode2llm/internal/scanner"
+)
+
+// ChunkTitle 从文件标题行解析出的信息，与 chunkTitle 的格式对应
+type ChunkTitle struct {
+	FileNum   int
+	Path      string
+	Diff      bool
+	Skeleton  bool
+	Truncated bool
+	StartLine int // 完整文件时为 0
+	EndLine   int
+	Continued bool
+}
+
+var chunkTitlePattern = regexp.MustCompile(`^### (\d+)\. (.+?)(` + regexp.QuoteMeta(diffSuffix) + `|` + regexp.QuoteMeta(skeletonSuffix) + `|` + regexp.QuoteMeta(truncatedSuffix) + `)?(?: \((续: )?行 (\d+)-(\d+)\))?$`)
+
+// chunkTitle 片段标题，例如 "3. main.go (续: 行 101-200)"
+func chunkTitle(chunk *Chunk) string {
+	return fmt.Sprintf("%d. %s%s%s", chunk.FileNum, chunk.File.RelPath, chunk.Suffix, chunkRangeLabel(chunk))
+}
+
+func chunkRangeLabel(chunk *Chunk) string {
+	switch {
+	case chunk.IsComplete():
+		return ""
+	case chunk.IsStart():
+		return fmt.Sprintf(" (行 %d-%d)", chunk.StartLine, chunk.EndLine)
+	default:
+		return fmt.Sprintf(" (续: 行 %d-%d)", chunk.StartLine, chunk.EndLine)
+	}
+}
+
+// chunkNotes 标题与代码块之间的说明行，例如原始编码；只出现在文件的第一个片段
+func chunkNotes(chunk *Chunk) []string {
+	var notes []string
+	if chunk.StartLine <= 1 {
+		if note := encodingNote(sourceEncoding(chunk.File)); note != "" {
+			notes = append(notes, "原始编码: "+note)
+		}
+	}
+	return notes
+}
+
+// sourceEncoding 需要在元信息中注明的原始编码，UTF-8 或未知时为空
+func sourceEncoding(file *scanner.FileInfo) string {
+	if file.Encoding == charset.UTF8 {
+		return ""
+	}
+	return file.Encoding
+}
+
+// encodingNote 原始编码的说明，UTF-8 或未知时为空
+func encodingNote(encoding string) string {
+	switch encoding {
+	case "", charset.UTF8:
+		return ""
+	case charset.UTF8BOM:
+		return encoding + "，已去除 BOM"
+	}
+	return encoding + "，已转换为 UTF-8"
+}
+
+// IsChunkNote 判断是否是标题与代码块之间的说明行 ("> ..." 形式)
+func IsChunkNote(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "> ")
+}
+
+// ParseChunkTitle 解析 "### 3. path (续: 行 a-b)" 形式的标题行
+func ParseChunkTitle(line string) (*ChunkTitle, bool) {
+	m := chunkTitlePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return nil, false
+	}
+
+	title := &ChunkTitle{
+		Path:      m[2],
+		Diff:      m[3] == diffSuffix,
+		Skeleton:  m[3] == skeletonSuffix,
+		Truncated: m[3] == truncatedSuffix,
+		Continued: m[4] != "",
+	}
+	title.FileNum, _ = strconv.Atoi(m[1])
+	if m[5] != "" {
+		title.StartLine, _ = strconv.Atoi(m[5])
+		title.EndLine, _ = strconv.Atoi(m[6])
+	}
+
+	return title, true
+}
+
+// CodeFence 返回比内容中任何行首反引号串都长的围栏，保证代码块不会被内容提前截断
+func CodeFence(lines []string) string {
+	longest := 0
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, "`"))
+		if n > longest {
+			longest = n
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// ParseFenceOpen 解析代码块开头，返回围栏长度与语言
+func ParseFenceOpen(line string) (int, string, bool) {
+	line = strings.TrimRight(line, "\r")
+	n := len(line) - len(strings.TrimLeft(line, "`"))
+	if n < 3 {
+		return 0, "", false
+	}
+	lang := strings.TrimSpace(line[n:])
+	if strings.Contains(lang, "`") {
+		return 0, "", false
+	}
+	return n, lang, true
+}
+
+// IsFenceClose 判断是否是长度不小于 size 的结束围栏
+func IsFenceClose(line string, size int) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= size && strings.Trim(line, "`") == ""
+}
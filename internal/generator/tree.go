@@ -1,7 +1,9 @@
 package generator
 
 import (
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -12,77 +14,146 @@ import (
 
 // GenerateTree 生成目录树
 func GenerateTree(dir string, cfg *config.Config) (string, error) {
+	return GenerateTreeWithMarks(dir, cfg, nil)
+}
+
+// GenerateTreeWithMarks 生成目录树，marks 中的文件（相对路径）会在名称后附加标记
+func GenerateTreeWithMarks(dir string, cfg *config.Config, marks map[string]string) (string, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", err
 	}
 
+	return generateTree(os.DirFS(absDir), scanner.NewIgnoreChecker(absDir, cfg), cfg, marks)
+}
+
+// GenerateTreeFS 生成 fs.FS 的目录树，忽略规则只读取 fsys 中的 .gitignore
+func GenerateTreeFS(fsys fs.FS, cfg *config.Config, marks map[string]string) (string, error) {
+	return generateTree(fsys, scanner.NewIgnoreCheckerFS(fsys, cfg), cfg, marks)
+}
+
+func generateTree(fsys fs.FS, ignoreChecker *scanner.IgnoreChecker, cfg *config.Config, marks map[string]string) (string, error) {
 	var builder strings.Builder
-	ignoreChecker := scanner.NewIgnoreChecker(cfg)
+	walker := &treeWalker{
+		fsys:          fsys,
+		builder:       &builder,
+		ignoreChecker: ignoreChecker,
+		marks:         marks,
+		collapse:      cfg.Include.Tree == "collapse",
+	}
 
-	err = generateTreeRecursive(absDir, "", &builder, ignoreChecker, true)
+	nodes, hidden, err := walker.collect(".")
 	if err != nil {
 		return "", err
 	}
+	walker.render(".", nodes, hidden, "", true)
 
 	return builder.String(), nil
 }
 
-func generateTreeRecursive(dir, prefix string, builder *strings.Builder, ignoreChecker *scanner.IgnoreChecker, isRoot bool) error {
-	entries, err := os.ReadDir(dir)
+type treeWalker struct {
+	fsys          fs.FS
+	builder       *strings.Builder
+	ignoreChecker *scanner.IgnoreChecker
+	marks         map[string]string
+	// collapse 不在包含模式范围内的条目折叠为 …，否则不显示
+	collapse bool
+}
+
+// treeNode 目录树中的一个条目
+type treeNode struct {
+	name     string
+	isDir    bool
+	children []*treeNode
+	// hidden 不在包含模式范围内的子条目数
+	hidden int
+}
+
+// collect 读取 dir（相对 fsys 根目录、以 / 分隔）下需要显示的条目，返回条目与未包含的条目数；
+// 设置了包含模式时，没有可显示内容的目录也算作未包含
+func (t *treeWalker) collect(dir string) ([]*treeNode, int, error) {
+	entries, err := fs.ReadDir(t.fsys, dir)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
-	// 过滤和排序
-	var validEntries []os.DirEntry
+	var nodes []*treeNode
+	hidden := 0
 	for _, entry := range entries {
-		path := filepath.Join(dir, entry.Name())
-		if !ignoreChecker.ShouldIgnore(path, entry.IsDir()) {
-			validEntries = append(validEntries, entry)
+		entryPath := path.Join(dir, entry.Name())
+		if t.ignoreChecker.Ignored(entryPath, entry.IsDir()) {
+			continue
+		}
+		if !t.ignoreChecker.Included(entryPath, entry.IsDir()) {
+			hidden++
+			continue
 		}
+
+		node := &treeNode{name: entry.Name(), isDir: entry.IsDir()}
+		if node.isDir {
+			if node.children, node.hidden, err = t.collect(entryPath); err != nil {
+				return nil, 0, err
+			}
+			if len(node.children) == 0 && t.ignoreChecker.HasIncludes() {
+				hidden++
+				continue
+			}
+		}
+		nodes = append(nodes, node)
 	}
 
-	sort.Slice(validEntries, func(i, j int) bool {
-		if validEntries[i].IsDir() != validEntries[j].IsDir() {
-			return validEntries[i].IsDir()
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].isDir != nodes[j].isDir {
+			return nodes[i].isDir
 		}
-		return validEntries[i].Name() < validEntries[j].Name()
+		return nodes[i].name < nodes[j].name
 	})
 
-	for i, entry := range validEntries {
-		isLast := i == len(validEntries)-1
+	return nodes, hidden, nil
+}
+
+// render 输出 dir 下的条目，折叠模式下未包含的条目以一行 … 表示
+func (t *treeWalker) render(dir string, nodes []*treeNode, hidden int, prefix string, isRoot bool) {
+	total := len(nodes)
+	if t.collapse && hidden > 0 {
+		total++
+	}
+
+	for i := 0; i < total; i++ {
+		isLast := i == total-1
 		connector := "├── "
 		if isLast {
 			connector = "└── "
 		}
 
-		name := entry.Name()
-		if entry.IsDir() {
-			name += "/"
+		if !isRoot || i > 0 {
+			t.builder.WriteString(prefix)
+		}
+		t.builder.WriteString(connector)
+		if i == len(nodes) {
+			t.builder.WriteString("…\n")
+			continue
 		}
 
-		if !isRoot || i > 0 {
-			builder.WriteString(prefix)
+		node := nodes[i]
+		name := node.name
+		if node.isDir {
+			name += "/"
+		}
+		t.builder.WriteString(name)
+		if mark := t.marks[path.Join(dir, node.name)]; mark != "" && !node.isDir {
+			t.builder.WriteString("  [" + mark + "]")
 		}
-		builder.WriteString(connector)
-		builder.WriteString(name)
-		builder.WriteString("\n")
+		t.builder.WriteString("\n")
 
-		if entry.IsDir() {
+		if node.isDir {
 			nextPrefix := prefix
 			if isLast {
 				nextPrefix += "    "
 			} else {
 				nextPrefix += "│   "
 			}
-
-			subDir := filepath.Join(dir, entry.Name())
-			if err := generateTreeRecursive(subDir, nextPrefix, builder, ignoreChecker, false); err != nil {
-				return err
-			}
+			t.render(path.Join(dir, node.name), node.children, node.hidden, nextPrefix, false)
 		}
 	}
-
-	return nil
 }
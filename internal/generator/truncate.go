@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/skeleton"
+)
+
+// 超过 max_file_lines 的文件的截断方式
+const (
+	TruncateHead     = "head"
+	TruncateHeadTail = "head_tail"
+	TruncateSkeleton = "skeleton"
+)
+
+// truncatedSuffix 节选片段标题后缀，内容不是完整文件，还原时跳过
+const truncatedSuffix = " (节选)"
+
+// ValidateTruncate 检查截断方式名称
+func ValidateTruncate(name string) error {
+	switch name {
+	case "", TruncateHead, TruncateHeadTail, TruncateSkeleton:
+		return nil
+	}
+	return fmt.Errorf("无效的截断方式: %s (可选: %s/%s/%s)", name, TruncateHead, TruncateHeadTail, TruncateSkeleton)
+}
+
+// needsTruncate 文件是否超过 max_file_lines
+func needsTruncate(file *scanner.FileInfo, cfg *config.Config) bool {
+	return cfg.MaxFileLines > 0 && file.LineCount > cfg.MaxFileLines
+}
+
+// truncateFile 按配置的方式缩减过长的文件，返回内容、标题后缀与节选各行在原文中的行号；
+// skeleton 方式无法提取骨架或骨架仍超过行数限制时按 head_tail 处理，骨架与未缩减时行号为 nil
+func truncateFile(file *scanner.FileInfo, cfg *config.Config) (string, string, []int) {
+	if cfg.Truncate == TruncateSkeleton {
+		if outline, ok := skeleton.Extract(file.Content, file.Language); ok && scanner.CountLines(outline) <= cfg.MaxFileLines {
+			return outline, skeletonSuffix, nil
+		}
+	}
+
+	strategy := cfg.Truncate
+	if strategy != TruncateHead {
+		strategy = TruncateHeadTail
+	}
+	if excerpt, numbers, ok := truncateLines(file.Content, cfg.MaxFileLines, strategy, file.LineMap); ok {
+		return excerpt, truncatedSuffix, numbers
+	}
+	return file.Content, "", nil
+}
+
+// truncateLines 只保留 limit 行，省略处插入一行说明（原文行号与行数）；未超过时返回 false。
+// 同时返回保留的各行在原文中的行号，省略说明为 0；lineMap 见 scanner.FileInfo.LineMap
+func truncateLines(content string, limit int, strategy string, lineMap []int) (string, []int, bool) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	total := len(lines)
+	if limit <= 0 || total <= limit {
+		return content, nil, false
+	}
+
+	var kept []string
+	var numbers []int
+	keep := func(from, to int) {
+		kept = append(kept, lines[from:to]...)
+		for i := from; i < to; i++ {
+			numbers = append(numbers, originalLine(lineMap, i+1))
+		}
+	}
+	if strategy == TruncateHead {
+		keep(0, limit)
+		kept = append(kept, elisionNote(originalLine(lineMap, limit+1), originalEnd(lineMap, total), originalEnd(lineMap, total)))
+		numbers = append(numbers, 0)
+	} else {
+		head := (limit + 1) / 2
+		tail := limit - head
+		keep(0, head)
+		kept = append(kept, elisionNote(originalLine(lineMap, head+1), originalEnd(lineMap, total-tail), originalEnd(lineMap, total)))
+		numbers = append(numbers, 0)
+		keep(total-tail, total)
+	}
+
+	return strings.Join(kept, "\n"), numbers, true
+}
+
+// originalLine 正文第 n 行在原文中的行号；遮盖敏感信息合并了多行时按 lineMap 换算，否则即为 n
+func originalLine(lineMap []int, n int) int {
+	if lineMap == nil || n <= 0 {
+		return n
+	}
+	if n > len(lineMap) {
+		n = len(lineMap)
+	}
+	return lineMap[n-1]
+}
+
+// originalEnd 正文第 n 行在原文中的最后一行，被遮盖的多行内容合并为一行时大于 originalLine
+func originalEnd(lineMap []int, n int) int {
+	if lineMap == nil {
+		return n
+	}
+	return originalLine(lineMap, n+1) - 1
+}
+
+// toOriginal 把相对正文的行号换算为原文中的行号，0 保持不变
+func toOriginal(lineMap, numbers []int) []int {
+	if lineMap == nil {
+		return numbers
+	}
+	result := make([]int, len(numbers))
+	for i, n := range numbers {
+		result[i] = originalLine(lineMap, n)
+	}
+	return result
+}
+
+// elisionNote 省略说明，行号为原文件中的行号
+func elisionNote(from, to, total int) string {
+	return fmt.Sprintf("⋯ 此处省略原文第 %d-%d 行，共 %d 行（全文 %d 行）⋯", from, to, to-from+1, total)
+}
+
+// truncateDescription 截断方式的说明
+func truncateDescription(strategy string) string {
+	switch strategy {
+	case TruncateHead:
+		return "只保留开头"
+	case TruncateSkeleton:
+		return "无法只保留骨架的保留开头与结尾"
+	}
+	return "保留开头与结尾"
+}
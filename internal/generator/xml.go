@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"printcode2llm/internal/skeleton"
+)
+
+// xmlFormat Anthropic 文档风格的 XML 输出:
+//
+//	<document index="1"><source>path</source><document_content>...</document_content></document>
+//
+// 内容中的 & < > 均转义，XML 不允许的控制字符替换为 U+FFFD
+type xmlFormat struct{}
+
+func (f *xmlFormat) header(doc *document, partNum, total int) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("<project name=\"%s\" part=\"%d\">\n", xmlAttr(doc.projectName), partNum))
+
+	if partNum == 1 {
+		result := doc.result
+		if doc.cfg.Prompts.HeaderPrompt != "" {
+			builder.WriteString("<instructions>\n")
+			builder.WriteString(xmlEscape(doc.cfg.Prompts.HeaderPrompt))
+			builder.WriteString("\n</instructions>\n")
+		}
+
+		builder.WriteString("<project_info>\n")
+		builder.WriteString(fmt.Sprintf("<time>%s</time>\n", doc.time.Format("2006-01-02 15:04:05")))
+		builder.WriteString(fmt.Sprintf("<files code=\"%d\" config=\"%d\">%d</files>\n", result.CodeFiles, result.ConfigFiles, result.FileCount))
+		builder.WriteString(fmt.Sprintf("<lines>%d</lines>\n", result.TotalLines))
+		builder.WriteString(fmt.Sprintf("<chars>%d</chars>\n", result.TotalChars))
+		builder.WriteString(fmt.Sprintf("<tokens tokenizer=\"%s\"%s>%d</tokens>\n", xmlAttr(result.Tokenizer), xmlEstimated(result), result.TotalTokens))
+		if result.ChangedFiles > 0 {
+			builder.WriteString(fmt.Sprintf("<changed>%d</changed>\n", result.ChangedFiles))
+		}
+		if mode := compressMode(doc.cfg); mode != "" {
+			builder.WriteString(fmt.Sprintf("<compress>%s</compress>\n", mode))
+		}
+		if result.SkeletonFiles > 0 {
+			builder.WriteString(fmt.Sprintf("<skeleton placeholder=\"%s\">%d</skeleton>\n", xmlAttr(skeleton.Placeholder), result.SkeletonFiles))
+		}
+		if result.TruncatedFiles > 0 {
+			builder.WriteString(fmt.Sprintf("<truncated max_lines=\"%d\">%d</truncated>\n", doc.cfg.MaxFileLines, result.TruncatedFiles))
+		}
+		if doc.cfg.Output.LineNumbers {
+			builder.WriteString(fmt.Sprintf("<line_numbers separator=\"%s\">每行开头是原文件中的行号，不属于代码内容</line_numbers>\n", lineNumberSeparator))
+		}
+		builder.WriteString("</project_info>\n")
+
+		if doc.tree != "" {
+			builder.WriteString("<project_tree>\n")
+			builder.WriteString(xmlEscape(doc.projectName + "/\n" + doc.tree))
+			builder.WriteString("</project_tree>\n")
+		}
+		if len(result.Skipped) > 0 {
+			builder.WriteString("<skipped_files>\n")
+			for _, s := range result.Skipped {
+				builder.WriteString(fmt.Sprintf("<file path=\"%s\" size=\"%d\">%s</file>\n", xmlAttr(s.RelPath), s.Size, xmlEscape(skipReason(s, doc.cfg))))
+			}
+			builder.WriteString("</skipped_files>\n")
+		}
+	}
+
+	builder.WriteString("<documents>\n")
+	return builder.String()
+}
+
+func (f *xmlFormat) listing(chunks []*Chunk) string {
+	return ""
+}
+
+func (f *xmlFormat) listingReserve() int {
+	return 0
+}
+
+func (f *xmlFormat) listingEntry(block *fileBlock) string {
+	return ""
+}
+
+func (f *xmlFormat) fileBlock(chunk *Chunk) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("<document index=\"%d\">\n", chunk.FileNum))
+	builder.WriteString(fmt.Sprintf("<source>%s</source>\n", xmlEscape(chunk.File.RelPath)))
+	if chunk.Language != "" {
+		builder.WriteString(fmt.Sprintf("<language>%s</language>\n", xmlEscape(chunk.Language)))
+	}
+	if kind := chunkKind(chunk); kind != "" {
+		builder.WriteString(fmt.Sprintf("<kind>%s</kind>\n", kind))
+	}
+	if chunk.File.ChangeStatus != "" {
+		builder.WriteString(fmt.Sprintf("<status>%s</status>\n", chunk.File.ChangeStatus))
+	}
+	if encoding := sourceEncoding(chunk.File); encoding != "" {
+		builder.WriteString(fmt.Sprintf("<encoding>%s</encoding>\n", xmlEscape(encoding)))
+	}
+	if !chunk.IsComplete() {
+		builder.WriteString(fmt.Sprintf("<lines start=\"%d\" end=\"%d\" total=\"%d\"/>\n", chunk.StartLine, chunk.EndLine, chunk.TotalLines))
+	}
+
+	builder.WriteString("<document_content>\n")
+	for _, line := range chunk.DisplayLines() {
+		builder.WriteString(f.encodeLine(line))
+	}
+	builder.WriteString("</document_content>\n")
+	builder.WriteString("</document>\n")
+
+	return builder.String()
+}
+
+func (f *xmlFormat) encodeLine(line string) string {
+	return xmlEscape(line) + "\n"
+}
+
+func (f *xmlFormat) continueNotice() string {
+	return "<notice>内容续下一部分</notice>\n"
+}
+
+func (f *xmlFormat) footer(doc *document, segments []*Segment) string {
+	result := doc.result
+	var builder strings.Builder
+
+	builder.WriteString("<stats>\n")
+	builder.WriteString(fmt.Sprintf("<files code=\"%d\" config=\"%d\">%d</files>\n", result.CodeFiles, result.ConfigFiles, result.FileCount))
+	builder.WriteString(fmt.Sprintf("<lines>%d</lines>\n", result.TotalLines))
+	builder.WriteString(fmt.Sprintf("<chars>%d</chars>\n", result.TotalChars))
+	builder.WriteString(fmt.Sprintf("<tokens tokenizer=\"%s\"%s>%d</tokens>\n", xmlAttr(result.Tokenizer), xmlEstimated(result), result.TotalTokens))
+	if len(segments) > 1 {
+		builder.WriteString(fmt.Sprintf("<parts count=\"%d\">\n", len(segments)))
+		for _, seg := range segments {
+			builder.WriteString(fmt.Sprintf("<part index=\"%d\" tokens=\"%d\"/>\n", seg.PartNum, seg.TokenCount))
+		}
+		builder.WriteString("</parts>\n")
+	}
+	builder.WriteString("</stats>\n")
+	if len(result.Excluded) > 0 {
+		builder.WriteString("<excluded_paths>\n")
+		for _, e := range result.Excluded {
+			builder.WriteString(fmt.Sprintf("<path dir=\"%t\" reason=\"%s\" rule=\"%s\" source=\"%s\">%s</path>\n",
+				e.IsDir, xmlAttr(e.Reason), xmlAttr(e.Rule), xmlAttr(e.Source), xmlEscape(e.RelPath)))
+		}
+		builder.WriteString("</excluded_paths>\n")
+	}
+
+	return builder.String()
+}
+
+func (f *xmlFormat) join(parts *segmentParts) string {
+	var builder strings.Builder
+	builder.WriteString(parts.header)
+	for _, block := range parts.blocks {
+		builder.WriteString(block)
+	}
+	builder.WriteString("</documents>\n")
+	builder.WriteString(parts.notice)
+	builder.WriteString(parts.footer)
+	builder.WriteString("</project>\n")
+	return builder.String()
+}
+
+var (
+	xmlTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// xmlEscape 转义文本中的 XML 特殊字符；制表符与换行保留原样，便于阅读代码
+func xmlEscape(text string) string {
+	return xmlTextReplacer.Replace(validXMLChars(text))
+}
+
+// xmlEstimated token 数为估算值时的属性
+func xmlEstimated(result *Result) string {
+	if result.TokensEstimated {
+		return ` estimated="true"`
+	}
+	return ""
+}
+
+// xmlAttr 转义属性值
+func xmlAttr(text string) string {
+	return xmlAttrReplacer.Replace(validXMLChars(text))
+}
+
+// validXMLChars 把 XML 1.0 不允许出现的字符替换为 U+FFFD
+func validXMLChars(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return r
+		}
+		if r < 0x20 || r == 0xFFFE || r == 0xFFFF || (r >= 0xD800 && r <= 0xDFFF) {
+			return '\uFFFD'
+		}
+		return r
+	}, text)
+}
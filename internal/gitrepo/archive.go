@@ -0,0 +1,48 @@
+package gitrepo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Archive 以 tar 格式导出 rev 中 dir 目录的内容（不含子模块），rev 可以是提交或树对象，
+// dir 为相对仓库根目录、以 / 分隔的路径，"" 表示整个仓库
+func (r *Repo) Archive(rev, dir string) ([]byte, error) {
+	if _, err := r.resolveTree(rev); err != nil {
+		return nil, err
+	}
+
+	treeish := rev
+	if dir = strings.Trim(dir, "/"); dir != "" && dir != "." {
+		if _, err := r.Run("rev-parse", "--verify", "--quiet", rev+":"+dir); err != nil {
+			return nil, fmt.Errorf("版本 %s 中不存在目录 %s", rev, dir)
+		}
+		treeish = rev + ":" + dir
+	}
+
+	return runGitBytes(r.Root, "archive", "--format=tar", treeish)
+}
+
+// resolveCommit 把分支、标签等版本解析为提交哈希；以 - 开头的版本会被 git 当作选项，直接拒绝
+func (r *Repo) resolveCommit(rev string) (string, error) {
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("无效的版本: %s", rev)
+	}
+	out, err := r.Run("rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("找不到版本 %s", rev)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// resolveTree 与 resolveCommit 相同，但也接受树对象，例如 Snapshot 返回的暂存区快照
+func (r *Repo) resolveTree(rev string) (string, error) {
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("无效的版本: %s", rev)
+	}
+	out, err := r.Run("rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{tree}")
+	if err != nil {
+		return "", fmt.Errorf("找不到版本 %s", rev)
+	}
+	return strings.TrimSpace(out), nil
+}
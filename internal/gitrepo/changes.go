@@ -0,0 +1,253 @@
+package gitrepo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Spec 变更范围，只能设置其中一种
+type Spec struct {
+	Since       string
+	Staged      bool
+	Uncommitted bool
+	Commit      string
+}
+
+// IsSet 是否指定了任何变更范围
+func (s Spec) IsSet() bool {
+	return s.Since != "" || s.Staged || s.Uncommitted || s.Commit != ""
+}
+
+// Validate 检查是否同时指定了多种范围
+func (s Spec) Validate() error {
+	count := 0
+	for _, set := range []bool{s.Since != "", s.Staged, s.Uncommitted, s.Commit != ""} {
+		if set {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("--since、--staged、--uncommitted、--commit 只能指定一个")
+	}
+	for _, ref := range []string{s.Since, s.Commit} {
+		if strings.HasPrefix(ref, "-") {
+			return fmt.Errorf("无效的版本: %s", ref)
+		}
+	}
+	return nil
+}
+
+// Describe 变更范围的描述
+func (s Spec) Describe() string {
+	switch {
+	case s.Since != "":
+		return fmt.Sprintf("相对 %s 的变更", s.Since)
+	case s.Staged:
+		return "已暂存的变更"
+	case s.Uncommitted:
+		return "未提交的变更"
+	case s.Commit != "":
+		return fmt.Sprintf("提交 %s 的变更", s.Commit)
+	}
+	return ""
+}
+
+// Snapshot 变更后的文件内容所在的树：--commit 为该提交，--staged 为暂存区（通过 write-tree
+// 写入对象库，不修改暂存区与引用）；其他范围的内容就是工作区，返回 ""
+func (r *Repo) Snapshot(spec Spec) (string, error) {
+	switch {
+	case spec.Commit != "":
+		return r.resolveCommit(spec.Commit)
+	case spec.Staged:
+		out, err := r.Run("write-tree")
+		if err != nil {
+			return "", fmt.Errorf("读取暂存区失败: %w", err)
+		}
+		return strings.TrimSpace(out), nil
+	}
+	return "", nil
+}
+
+// Change 单个文件的变更
+type Change struct {
+	Path    string // 相对仓库根目录
+	OldPath string // 重命名前的路径
+	Status  string // A 新增 / M 修改 / D 删除 / R 重命名
+	Diff    string
+}
+
+// Changes 获取指定范围内变更的文件及其 unified diff
+func (r *Repo) Changes(spec Spec) ([]*Change, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var diffArgs []string
+	includeUntracked := false
+
+	switch {
+	case spec.Since != "":
+		since, err := r.resolveCommit(spec.Since)
+		if err != nil {
+			return nil, err
+		}
+		base, err := r.Run("merge-base", since, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		diffArgs = []string{"diff", strings.TrimSpace(base)}
+		includeUntracked = true
+	case spec.Staged:
+		diffArgs = []string{"diff", "--cached"}
+	case spec.Uncommitted:
+		diffArgs = []string{"diff", "HEAD"}
+		includeUntracked = true
+	case spec.Commit != "":
+		commit, err := r.resolveCommit(spec.Commit)
+		if err != nil {
+			return nil, err
+		}
+		// 与第一个父提交比较，合并提交即为合并进来的全部变更；根提交与空树比较
+		diffArgs = []string{"diff-tree", "-r", "--root", "--no-commit-id", commit}
+		if parent, err := r.Run("rev-parse", "--verify", "--quiet", commit+"^1"); err == nil {
+			diffArgs = []string{"diff-tree", "-r", "--no-commit-id", strings.TrimSpace(parent), commit}
+		}
+	default:
+		return nil, fmt.Errorf("未指定变更范围")
+	}
+
+	common := []string{"-M", "--no-color", "--no-ext-diff"}
+
+	statusOut, err := r.Run(append(append(append([]string{}, diffArgs...), common...), "--name-status", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	changes := parseNameStatus(statusOut)
+
+	patchOut, err := r.Run(append(append(append([]string{}, diffArgs...), common...), "-p")...)
+	if err != nil {
+		return nil, err
+	}
+	patches := splitPatch(patchOut)
+	for _, change := range changes {
+		change.Diff = patches[change.Path]
+		if change.Diff == "" && change.OldPath != "" {
+			change.Diff = patches[change.OldPath]
+		}
+	}
+
+	if includeUntracked {
+		out, err := r.Run("ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range strings.Split(out, "\x00") {
+			if path != "" {
+				changes = append(changes, &Change{Path: path, Status: "A"})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// parseNameStatus 解析 --name-status -z 输出
+func parseNameStatus(out string) []*Change {
+	fields := strings.Split(out, "\x00")
+	var changes []*Change
+
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+
+		letter := status[:1]
+		switch letter {
+		case "R", "C":
+			if i+2 >= len(fields) {
+				return changes
+			}
+			change := &Change{OldPath: fields[i+1], Path: fields[i+2], Status: "R"}
+			if letter == "C" {
+				change.OldPath = ""
+				change.Status = "A"
+			}
+			changes = append(changes, change)
+			i += 2
+		default:
+			if i+1 >= len(fields) {
+				return changes
+			}
+			if letter == "T" {
+				letter = "M"
+			}
+			changes = append(changes, &Change{Path: fields[i+1], Status: letter})
+			i++
+		}
+	}
+
+	return changes
+}
+
+// splitPatch 将完整补丁按文件拆分，键为新路径（删除的文件为旧路径）
+func splitPatch(patch string) map[string]string {
+	result := make(map[string]string)
+	lines := strings.SplitAfter(patch, "\n")
+
+	var current []string
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		if path := patchPath(current); path != "" {
+			result[path] = strings.Join(current, "")
+		}
+		current = nil
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+		}
+		if line != "" {
+			current = append(current, line)
+		}
+	}
+	flush()
+
+	return result
+}
+
+func patchPath(lines []string) string {
+	oldPath := ""
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+++ b/"):
+			return strings.TrimPrefix(line, "+++ b/")
+		case strings.HasPrefix(line, "--- a/"):
+			oldPath = strings.TrimPrefix(line, "--- a/")
+		case strings.HasPrefix(line, "rename to "):
+			return strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "@@"):
+			return oldPath
+		}
+	}
+
+	if oldPath != "" {
+		return oldPath
+	}
+
+	// 二进制或仅权限变化的文件只有头部: diff --git a/x b/x
+	header := strings.TrimRight(lines[0], "\n")
+	if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+		return header[idx+3:]
+	}
+	return ""
+}
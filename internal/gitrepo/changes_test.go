@@ -0,0 +1,133 @@
+package gitrepo
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo 在临时目录中创建仓库，返回仓库与执行 git 命令的函数
+func testRepo(t *testing.T) (*Repo, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有 git")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, git
+}
+
+func writeFile(t *testing.T, repo *Repo, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo.Root, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// snapshotFiles 读取 Snapshot 返回的树中的全部文件
+func snapshotFiles(t *testing.T, repo *Repo, spec Spec) map[string]string {
+	t.Helper()
+	tree, err := repo.Snapshot(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := repo.Archive(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			content, _ := io.ReadAll(tr)
+			files[hdr.Name] = string(content)
+		}
+	}
+	return files
+}
+
+func TestSnapshotCommit(t *testing.T) {
+	repo, git := testRepo(t)
+	writeFile(t, repo, "a.go", "a v1\n")
+	writeFile(t, repo, "b.go", "b v1\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	writeFile(t, repo, "a.go", "a v2\n")
+	writeFile(t, repo, "b.go", "b v2\n")
+	git("commit", "-q", "-am", "C")
+	commit := git("rev-parse", "HEAD")
+
+	// 提交 C 之后 a.go 被删除，b.go 再次修改
+	git("rm", "-q", "a.go")
+	writeFile(t, repo, "b.go", "b v3\n")
+	git("commit", "-q", "-am", "later")
+
+	spec := Spec{Commit: commit}
+	changes, err := repo.Changes(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Path != "a.go" || changes[0].Diff == "" {
+		t.Fatalf("changes = %+v", changes)
+	}
+	files := snapshotFiles(t, repo, spec)
+	if files["a.go"] != "a v2\n" || files["b.go"] != "b v2\n" {
+		t.Errorf("提交 C 中的内容 = %v", files)
+	}
+}
+
+func TestSnapshotStaged(t *testing.T) {
+	repo, git := testRepo(t)
+	writeFile(t, repo, "a.go", "a v1\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	// 暂存后工作区再次修改，新文件暂存后从工作区删除
+	writeFile(t, repo, "a.go", "a staged\n")
+	writeFile(t, repo, "new.go", "new staged\n")
+	git("add", ".")
+	writeFile(t, repo, "a.go", "a worktree\n")
+	os.Remove(filepath.Join(repo.Root, "new.go"))
+
+	files := snapshotFiles(t, repo, Spec{Staged: true})
+	if files["a.go"] != "a staged\n" || files["new.go"] != "new staged\n" {
+		t.Errorf("暂存区中的内容 = %v", files)
+	}
+	// 只写入对象库，不改变暂存区
+	if status := git("status", "--porcelain"); !strings.Contains(status, "MM a.go") || !strings.Contains(status, "AD new.go") {
+		t.Errorf("status = %q", status)
+	}
+
+	if tree, err := repo.Snapshot(Spec{Uncommitted: true}); err != nil || tree != "" {
+		t.Errorf("工作区范围 tree=%q err=%v", tree, err)
+	}
+}
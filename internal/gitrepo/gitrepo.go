@@ -0,0 +1,62 @@
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo 本地 git 仓库，所有操作都通过本机 git 命令完成，不访问网络
+type Repo struct {
+	Root string
+}
+
+// Open 打开 dir 所在的 git 仓库
+func Open(dir string) (*Repo, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("未找到 git 命令: %w", err)
+	}
+
+	out, err := runGit(absDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s 不在 git 仓库中: %w", dir, err)
+	}
+
+	return &Repo{Root: filepath.Clean(strings.TrimSpace(out))}, nil
+}
+
+// Run 在仓库根目录执行 git 命令并返回标准输出
+func (r *Repo) Run(args ...string) (string, error) {
+	return runGit(r.Root, args...)
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	out, err := runGitBytes(dir, args...)
+	return string(out), err
+}
+
+func runGitBytes(dir string, args ...string) ([]byte, error) {
+	fullArgs := append([]string{"-C", dir, "-c", "core.quotePath=false"}, args...)
+	cmd := exec.Command("git", fullArgs...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+
+	return stdout.Bytes(), nil
+}
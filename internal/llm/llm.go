@@ -0,0 +1,275 @@
+// Package llm 调用 OpenAI 兼容或 Anthropic 风格的对话接口，以流式方式接收回复
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 支持的接口风格
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// anthropicVersion 请求头 anthropic-version 的值
+const anthropicVersion = "2023-06-01"
+
+// Message 一条对话消息，Role 为 user 或 assistant
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Client 对话接口客户端
+type Client struct {
+	// Provider 接口风格: openai（/chat/completions）或 anthropic（/messages）
+	Provider string
+	// BaseURL 接口地址，例如 https://api.openai.com/v1，为空时使用对应服务的官方地址
+	BaseURL string
+	Model   string
+	// APIKey 可以为空，例如本地的兼容服务
+	APIKey string
+	// MaxTokens 回复的最大 token 数，0 表示不限制（anthropic 必须设置，为 0 时使用 4096）
+	MaxTokens int
+	// HTTPClient 为 nil 时使用 http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// DefaultBaseURL 各接口风格的官方地址
+func DefaultBaseURL(provider string) string {
+	if provider == ProviderAnthropic {
+		return "https://api.anthropic.com/v1"
+	}
+	return "https://api.openai.com/v1"
+}
+
+// Validate 检查接口风格与模型
+func (c *Client) Validate() error {
+	if c.Provider != ProviderOpenAI && c.Provider != ProviderAnthropic {
+		return fmt.Errorf("无效的接口风格: %s (可选: openai/anthropic)", c.Provider)
+	}
+	if c.Model == "" {
+		return fmt.Errorf("未配置模型")
+	}
+	return nil
+}
+
+// Endpoint 请求的完整地址
+func (c *Client) Endpoint() string {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL(c.Provider)
+	}
+	base = strings.TrimRight(base, "/")
+	if c.Provider == ProviderAnthropic {
+		return base + "/messages"
+	}
+	return base + "/chat/completions"
+}
+
+// Stream 发送对话并以流式接收回复，每收到一段文本调用一次 onText，返回完整的回复；
+// 出错时返回已收到的部分
+func (c *Client) Stream(ctx context.Context, system string, messages []Message, onText func(string)) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(c.requestBody(system, messages))
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if c.Provider == ProviderAnthropic {
+		req.Header.Set("anthropic-version", anthropicVersion)
+		if c.APIKey != "" {
+			req.Header.Set("x-api-key", c.APIKey)
+		}
+	} else if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return "", fmt.Errorf("%s 返回 %s: %s", c.Endpoint(), resp.Status, errorMessage(data))
+	}
+
+	// 不支持流式的兼容服务会直接返回完整的 JSON
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		text, err := c.parseComplete(data)
+		if err == nil && onText != nil {
+			onText(text)
+		}
+		return text, err
+	}
+
+	return c.readStream(resp.Body, onText)
+}
+
+func (c *Client) requestBody(system string, messages []Message) map[string]any {
+	body := map[string]any{
+		"model":  c.Model,
+		"stream": true,
+	}
+	if c.Provider == ProviderAnthropic {
+		maxTokens := c.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = 4096
+		}
+		body["max_tokens"] = maxTokens
+		if system != "" {
+			body["system"] = system
+		}
+		body["messages"] = messages
+		return body
+	}
+
+	if c.MaxTokens > 0 {
+		body["max_tokens"] = c.MaxTokens
+	}
+	all := make([]Message, 0, len(messages)+1)
+	if system != "" {
+		all = append(all, Message{Role: "system", Content: system})
+	}
+	body["messages"] = append(all, messages...)
+	return body
+}
+
+// streamEvent 两种接口流式事件中用到的字段
+type streamEvent struct {
+	// OpenAI
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+
+	// Anthropic
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readStream 解析 server-sent events，只关心 data 行
+func (c *Client) readStream(r io.Reader, onText func(string)) (string, error) {
+	var reply strings.Builder
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return reply.String(), fmt.Errorf("无法解析的流式数据: %s", data)
+		}
+		if event.Error != nil {
+			return reply.String(), fmt.Errorf("接口返回错误: %s", event.Error.Message)
+		}
+
+		text := ""
+		if c.Provider == ProviderAnthropic {
+			if event.Type == "message_stop" {
+				break
+			}
+			if event.Type == "content_block_delta" && event.Delta.Type == "text_delta" {
+				text = event.Delta.Text
+			}
+		} else if len(event.Choices) > 0 {
+			text = event.Choices[0].Delta.Content
+		}
+		if text != "" {
+			reply.WriteString(text)
+			if onText != nil {
+				onText(text)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return reply.String(), err
+	}
+	return reply.String(), nil
+}
+
+// parseComplete 解析非流式的完整回复
+func (c *Client) parseComplete(data []byte) (string, error) {
+	var resp struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("无法解析的回复: %w", err)
+	}
+
+	if c.Provider == ProviderAnthropic {
+		var b strings.Builder
+		for _, block := range resp.Content {
+			if block.Type == "text" {
+				b.WriteString(block.Text)
+			}
+		}
+		return b.String(), nil
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("回复中没有内容")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// errorMessage 从错误响应中提取 error.message，取不到时返回原文
+func errorMessage(data []byte) string {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &resp) == nil && resp.Error.Message != "" {
+		return resp.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
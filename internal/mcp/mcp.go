@@ -0,0 +1,211 @@
+// Package mcp 通过 stdio 提供 Model Context Protocol 服务，让智能体直接读取项目上下文
+//
+// 消息为逐行的 JSON-RPC 2.0，支持 initialize、ping、tools/list 与 tools/call；
+// 所有输出都写到 Serve 的 w，不向终端打印，可以通过管道完整地测试。
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/version"
+)
+
+// protocolVersions 支持的协议版本，第一个为默认值
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize 单条消息上限
+const maxMessageSize = 16 << 20
+
+// Options 服务选项
+type Options struct {
+	// Root 项目目录，所有工具都只访问该目录
+	Root string
+	// ConfigPath 非空时使用该配置文件，否则按命令行的顺序查找 .ptlm.yaml
+	ConfigPath string
+	// Cache 非空时扫描与生成使用磁盘缓存
+	Cache *cache.Cache
+}
+
+// Server MCP 服务
+type Server struct {
+	opts  Options
+	tools []*tool
+	enc   *json.Encoder
+}
+
+// New 创建服务
+func New(opts Options) *Server {
+	// 项目名称取自目录名，相对路径 "." 需要先转换
+	if absRoot, err := filepath.Abs(opts.Root); err == nil {
+		opts.Root = absRoot
+	}
+	s := &Server{opts: opts}
+	s.tools = s.registerTools()
+	return s
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Serve 从 r 逐行读取请求并把响应写到 w，r 结束时返回 nil；ctx 取消时返回 ctx.Err()
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.enc = json.NewEncoder(w)
+	s.enc.SetEscapeHTML(false)
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for sc.Scan() {
+			line := append([]byte(nil), sc.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- sc.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if err := s.handleLine(ctx, line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleLine 处理一条消息，只有写出失败时返回错误
+func (s *Server) handleLine(ctx context.Context, line []byte) error {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return s.write(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "无法解析的 JSON: " + err.Error()}})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if len(req.ID) == 0 {
+			return nil
+		}
+		return s.write(response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "不是有效的 JSON-RPC 2.0 请求"}})
+	}
+
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+
+	// 没有 id 的是通知，不需要响应
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var re *rpcError
+		if !errors.As(err, &re) {
+			re = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		resp.Result = nil
+		resp.Error = re
+	}
+	return s.write(resp)
+}
+
+func (s *Server) write(resp response) error {
+	return s.enc.Encode(resp)
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(ctx, params)
+	}
+	if strings.HasPrefix(method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("不支持的方法: %s", method)}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	protocol := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == p.ProtocolVersion {
+			protocol = v
+		}
+	}
+
+	return map[string]any{
+		"protocolVersion": protocol,
+		"capabilities": map[string]any{
+			"tools": map[string]any{},
+		},
+		"serverInfo": map[string]any{
+			"name":    "ptlm",
+			"version": version.Version,
+		},
+		"instructions": "读取本地项目的源码上下文：先用 project_tree 或 list_files 了解结构，" +
+			"再用 read_project_context 按分段读取整理好的代码，file_outline 查看单个文件的声明与签名。",
+	}, nil
+}
@@ -0,0 +1,323 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testResponse 解析后的响应，Result 保留原始 JSON
+type testResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// writeProject 在临时目录中写入一个小项目
+func writeProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":           "package main\n\nimport \"fmt\"\n\n// main 入口\nfunc main() {\n\tfmt.Println(hello())\n}\n",
+		"util/hello.go":     "package main\n\n// hello 返回问候语\nfunc hello() string {\n\treturn \"hello\"\n}\n",
+		"README.md":         "# demo\n",
+		"node_modules/x.js": "module.exports = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// serve 把请求逐行交给 Serve，返回按顺序解析的响应
+func serve(t *testing.T, root string, requests ...string) []testResponse {
+	t.Helper()
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	var out bytes.Buffer
+	if err := New(Options{Root: root}).Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var responses []testResponse
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp testResponse
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v\n%s", err, out.String())
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// callTool 调用一个工具，返回文本与是否出错
+func callTool(t *testing.T, root, name, args string) (string, bool) {
+	t.Helper()
+	req := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + args + `}}`
+	responses := serve(t, root, req)
+	if len(responses) != 1 {
+		t.Fatalf("%s: 收到 %d 个响应，应为 1", name, len(responses))
+	}
+	if responses[0].Error != nil {
+		t.Fatalf("%s: %v", name, responses[0].Error)
+	}
+	var result toolResult
+	if err := json.Unmarshal(responses[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != "text" {
+		t.Fatalf("%s: 结果格式不对: %s", name, responses[0].Result)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestInitialize(t *testing.T) {
+	responses := serve(t, t.TempDir(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"ping"}`,
+	)
+	// 通知没有响应
+	if len(responses) != 2 {
+		t.Fatalf("收到 %d 个响应，应为 2", len(responses))
+	}
+
+	var init struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		Capabilities    map[string]any `json:"capabilities"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(responses[0].Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if string(responses[0].ID) != "1" || init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Name != "ptlm" {
+		t.Errorf("initialize = %s", responses[0].Result)
+	}
+	if _, ok := init.Capabilities["tools"]; !ok {
+		t.Errorf("capabilities 缺少 tools: %s", responses[0].Result)
+	}
+	if string(responses[1].ID) != `"two"` || responses[1].Error != nil {
+		t.Errorf("ping = %+v", responses[1])
+	}
+}
+
+func TestInitializeUnknownVersion(t *testing.T) {
+	responses := serve(t, t.TempDir(), `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(responses[0].Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.ProtocolVersion != protocolVersions[0] {
+		t.Errorf("protocolVersion = %q, want %q", init.ProtocolVersion, protocolVersions[0])
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	responses := serve(t, t.TempDir(), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	var list struct {
+		Tools []struct {
+			Name        string         `json:"name"`
+			Description string         `json:"description"`
+			InputSchema map[string]any `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(responses[0].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.Description == "" || tool.InputSchema["type"] != "object" {
+			t.Errorf("%s: 缺少说明或参数格式: %+v", tool.Name, tool)
+		}
+	}
+	want := "list_files,project_tree,read_project_context,file_outline"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("tools = %s, want %s", got, want)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	responses := serve(t, t.TempDir(),
+		`not json`,
+		`{"jsonrpc":"1.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"nope"}}`,
+	)
+	want := []int{codeParseError, codeInvalidRequest, codeMethodNotFound, codeInvalidParams}
+	if len(responses) != len(want) {
+		t.Fatalf("收到 %d 个响应，应为 %d", len(responses), len(want))
+	}
+	for i, resp := range responses {
+		if resp.Error == nil || resp.Error.Code != want[i] {
+			t.Errorf("响应 %d: error = %+v, want code %d", i, resp.Error, want[i])
+		}
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	root := writeProject(t)
+
+	text, isError := callTool(t, root, "list_files", `{}`)
+	if isError {
+		t.Fatal(text)
+	}
+	for _, want := range []string{"3 个文件", "main.go (go, 8 行)", "util/hello.go (go, 6 行)", "README.md"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("缺少 %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "node_modules") {
+		t.Errorf("未应用忽略规则:\n%s", text)
+	}
+
+	text, _ = callTool(t, root, "list_files", `{"paths":["util"]}`)
+	if !strings.Contains(text, "1 个文件") || !strings.Contains(text, "util/hello.go") {
+		t.Errorf("paths 未生效:\n%s", text)
+	}
+}
+
+func TestProjectTree(t *testing.T) {
+	root := writeProject(t)
+
+	text, isError := callTool(t, root, "project_tree", `{}`)
+	if isError {
+		t.Fatal(text)
+	}
+	if !strings.HasPrefix(text, filepath.Base(root)+"/\n") {
+		t.Errorf("缺少项目名称:\n%s", text)
+	}
+	for _, want := range []string{"main.go", "util", "hello.go"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("缺少 %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestReadProjectContext(t *testing.T) {
+	root := writeProject(t)
+
+	text, isError := callTool(t, root, "read_project_context", `{}`)
+	if isError {
+		t.Fatal(text)
+	}
+	if !strings.HasPrefix(text, "第 1/1 部分") {
+		t.Errorf("缺少分段说明:\n%s", text)
+	}
+	for _, want := range []string{"main.go", "fmt.Println(hello())", `return "hello"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("缺少 %q:\n%s", want, text)
+		}
+	}
+
+	text, _ = callTool(t, root, "read_project_context", `{"skeleton":true,"paths":["util"]}`)
+	if strings.Contains(text, `return "hello"`) || !strings.Contains(text, "func hello() string") {
+		t.Errorf("skeleton 未生效:\n%s", text)
+	}
+	if strings.Contains(text, "fmt.Println") {
+		t.Errorf("paths 未生效:\n%s", text)
+	}
+
+	text, isError = callTool(t, root, "read_project_context", `{"index":5}`)
+	if !isError || !strings.Contains(text, "第 5 部分不存在") {
+		t.Errorf("index 越界: isError=%v %s", isError, text)
+	}
+
+	text, isError = callTool(t, root, "read_project_context", `{"unknown":1}`)
+	if !isError || !strings.Contains(text, "参数无效") {
+		t.Errorf("未知参数: isError=%v %s", isError, text)
+	}
+}
+
+func TestFileOutline(t *testing.T) {
+	root := writeProject(t)
+
+	text, isError := callTool(t, root, "file_outline", `{"path":"util/hello.go"}`)
+	if isError {
+		t.Fatal(text)
+	}
+	if !strings.HasPrefix(text, "util/hello.go (go, 6 行)") || !strings.Contains(text, "func hello() string") {
+		t.Errorf("大纲不对:\n%s", text)
+	}
+	if strings.Contains(text, `return "hello"`) {
+		t.Errorf("大纲包含函数体:\n%s", text)
+	}
+
+	// 项目内的绝对路径同样可用
+	text, isError = callTool(t, root, "file_outline", `{"path":`+quote(filepath.Join(root, "main.go"))+`}`)
+	if isError || !strings.HasPrefix(text, "main.go (go, 8 行)") {
+		t.Errorf("绝对路径: isError=%v %s", isError, text)
+	}
+
+	text, isError = callTool(t, root, "file_outline", `{"path":"missing.go"}`)
+	if !isError || !strings.Contains(text, "不存在") {
+		t.Errorf("文件不存在: isError=%v %s", isError, text)
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "project")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.go"), []byte("package secret\n\nfunc Secret() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []struct {
+		tool string
+		args string
+	}{
+		{"file_outline", `{"path":"../secret.go"}`},
+		{"file_outline", `{"path":"util/../../secret.go"}`},
+		{"file_outline", `{"path":` + quote(filepath.Join(parent, "secret.go")) + `}`},
+		{"list_files", `{"paths":["../"]}`},
+		{"read_project_context", `{"paths":["main.go","../secret.go"]}`},
+	}
+	for _, c := range calls {
+		text, isError := callTool(t, root, c.tool, c.args)
+		if !isError || !strings.Contains(text, "不在项目目录中") {
+			t.Errorf("%s %s: isError=%v %s", c.tool, c.args, isError, text)
+		}
+		if strings.Contains(text, "Secret") {
+			t.Errorf("%s %s: 返回了项目外的内容:\n%s", c.tool, c.args, text)
+		}
+	}
+}
+
+func TestServeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 读取端一直阻塞，只能通过 ctx 结束
+	r, w := io.Pipe()
+	defer w.Close()
+	if err := New(Options{Root: t.TempDir()}).Serve(ctx, r, io.Discard); err != context.Canceled {
+		t.Errorf("Serve = %v, want context.Canceled", err)
+	}
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
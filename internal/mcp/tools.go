@@ -0,0 +1,364 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/redact"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/skeleton"
+	"printcode2llm/internal/ui"
+)
+
+// tool 一个可供调用的工具
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	run func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+func (s *Server) registerTools() []*tool {
+	pathsSchema := map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "只包含这些文件或目录（相对项目根目录），为空时包含整个项目",
+	}
+	return []*tool{
+		{
+			Name:        "list_files",
+			Description: "列出项目中会被整理的文件（已应用 .gitignore 与忽略规则），包含语言与行数",
+			InputSchema: objectSchema(map[string]any{
+				"paths": pathsSchema,
+			}),
+			run: s.listFiles,
+		},
+		{
+			Name:        "project_tree",
+			Description: "项目的目录树（已应用 .gitignore 与忽略规则）",
+			InputSchema: objectSchema(map[string]any{}),
+			run:         s.projectTree,
+		},
+		{
+			Name: "read_project_context",
+			Description: "按配置的预算把项目整理为若干分段，返回第 index 个分段（从 1 开始）；" +
+				"返回内容开头注明总分段数，依次读取即可获得完整上下文",
+			InputSchema: objectSchema(map[string]any{
+				"index": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "分段序号，从 1 开始，默认为 1",
+				},
+				"paths": pathsSchema,
+				"max_tokens": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "每段的 token 上限，默认使用配置中的值",
+				},
+				"skeleton": map[string]any{
+					"type":        "boolean",
+					"description": "骨架模式，只保留声明、签名与文档注释",
+				},
+			}),
+			run: s.readProjectContext,
+		},
+		{
+			Name:        "file_outline",
+			Description: "单个代码文件的大纲：包声明、导入、类型、函数签名与文档注释，函数体以占位符代替",
+			InputSchema: objectSchema(map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "相对项目根目录的文件路径",
+				},
+			}, "path"),
+			run: s.fileOutline,
+		},
+	}
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *Server) listTools() any {
+	return map[string]any{"tools": s.tools}
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	for _, t := range s.tools {
+		if t.Name != p.Name {
+			continue
+		}
+		args := p.Arguments
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		text, err := t.run(ctx, args)
+		if err != nil {
+			// 工具执行失败作为结果返回，让模型看到原因
+			return toolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		return toolResult{Content: []content{{Type: "text", Text: text}}}, nil
+	}
+	return nil, fmt.Errorf("未知的工具: %s", p.Name)
+}
+
+func decodeArgs(args json.RawMessage, v any) error {
+	dec := json.NewDecoder(strings.NewReader(string(args)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("参数无效: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadFor(s.opts.ConfigPath, []string{s.opts.Root})
+	if err != nil {
+		return nil, fmt.Errorf("配置加载失败: %w", err)
+	}
+	return cfg, nil
+}
+
+// scan 扫描项目并遮盖敏感信息，filter 为 nil 时包含全部文件
+func (s *Server) scan(ctx context.Context, cfg *config.Config, filter func(string) bool) ([]*scanner.FileInfo, error) {
+	files, err := scanner.ScanDirectoryWithOptions(s.opts.Root, cfg, scanner.Options{
+		Filter:  filter,
+		Cache:   s.cacheFor(cfg),
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Redact.Enabled || cfg.Redact.FailOnSecrets {
+		redactor, err := redact.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		total := 0
+		for _, report := range redactor.Files(files) {
+			total += len(report.Findings)
+		}
+		if total > 0 && cfg.Redact.FailOnSecrets {
+			return nil, fmt.Errorf("发现 %d 处疑似敏感信息，按配置不返回内容", total)
+		}
+	}
+	return files, nil
+}
+
+func (s *Server) cacheFor(cfg *config.Config) *cache.Cache {
+	if !cfg.Cache {
+		return nil
+	}
+	return s.opts.Cache
+}
+
+// pathFilter 只保留 paths 中的文件或其下的文件
+func (s *Server) pathFilter(paths []string) (func(string) bool, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rel, err := s.relPath(p)
+		if err != nil {
+			return nil, err
+		}
+		cleaned = append(cleaned, rel)
+	}
+	return func(relPath string) bool {
+		for _, p := range cleaned {
+			if p == "." || relPath == p || strings.HasPrefix(relPath, p+"/") {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// relPath 把参数中的路径转换为相对项目根目录、以 "/" 分隔的路径；
+// 绝对路径必须位于项目目录之内，跳出项目目录的路径返回错误
+func (s *Server) relPath(p string) (string, error) {
+	rel := p
+	if filepath.IsAbs(p) {
+		var err error
+		if rel, err = filepath.Rel(s.opts.Root, p); err != nil {
+			return "", fmt.Errorf("路径 %s 不在项目目录中", p)
+		}
+	}
+	rel = path.Clean(filepath.ToSlash(rel))
+	if rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return "", fmt.Errorf("路径 %s 不在项目目录中", p)
+	}
+	return rel, nil
+}
+
+func (s *Server) listFiles(ctx context.Context, args json.RawMessage) (string, error) {
+	var a struct {
+		Paths []string `json:"paths"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return "", err
+	}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	filter, err := s.pathFilter(a.Paths)
+	if err != nil {
+		return "", err
+	}
+	files, err := s.scan(ctx, cfg, filter)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	lines := 0
+	for _, file := range files {
+		lines += file.LineCount
+	}
+	fmt.Fprintf(&b, "%d 个文件，共 %s 行\n\n", len(files), ui.FormatNumber(lines))
+	for _, file := range files {
+		fmt.Fprintf(&b, "%s (%s, %d 行)\n", file.RelPath, file.Language, file.LineCount)
+	}
+	return b.String(), nil
+}
+
+func (s *Server) projectTree(ctx context.Context, args json.RawMessage) (string, error) {
+	var a struct{}
+	if err := decodeArgs(args, &a); err != nil {
+		return "", err
+	}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	tree, err := generator.GenerateTree(s.opts.Root, cfg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(s.opts.Root) + "/\n" + tree, nil
+}
+
+func (s *Server) readProjectContext(ctx context.Context, args json.RawMessage) (string, error) {
+	var a struct {
+		Index     int      `json:"index"`
+		Paths     []string `json:"paths"`
+		MaxTokens int      `json:"max_tokens"`
+		Skeleton  *bool    `json:"skeleton"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return "", err
+	}
+	if a.Index == 0 {
+		a.Index = 1
+	}
+	if a.Index < 0 {
+		return "", fmt.Errorf("index 必须从 1 开始")
+	}
+
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if a.MaxTokens > 0 {
+		cfg.Output.MaxTokens = a.MaxTokens
+	}
+	if a.Skeleton != nil {
+		cfg.Output.Skeleton = *a.Skeleton
+	}
+
+	filter, err := s.pathFilter(a.Paths)
+	if err != nil {
+		return "", err
+	}
+	files, err := s.scan(ctx, cfg, filter)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("没有匹配的文件")
+	}
+	result, err := generator.GenerateWithOptions(s.opts.Root, files, cfg, generator.Options{
+		Cache:   s.cacheFor(cfg),
+		Context: ctx,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	total := len(result.Segments)
+	if a.Index > total {
+		return "", fmt.Errorf("第 %d 部分不存在，共 %d 部分", a.Index, total)
+	}
+	seg := result.Segments[a.Index-1]
+	header := fmt.Sprintf("第 %d/%d 部分，文件 %s，约 %s token\n\n", a.Index, total, seg.FileRange, ui.FormatNumber(seg.TokenCount))
+	return header + seg.Content, nil
+}
+
+func (s *Server) fileOutline(ctx context.Context, args json.RawMessage) (string, error) {
+	var a struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return "", err
+	}
+	if a.Path == "" {
+		return "", fmt.Errorf("缺少 path")
+	}
+	target, err := s.relPath(a.Path)
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	files, err := s.scan(ctx, cfg, func(relPath string) bool { return relPath == target })
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("%s 不存在或被忽略规则排除", target)
+	}
+
+	file := files[0]
+	outline, ok := skeleton.Extract(file.Content, file.Language)
+	if !ok {
+		return "", fmt.Errorf("不支持提取 %s 文件的大纲", file.Language)
+	}
+	return fmt.Sprintf("%s (%s, %d 行)\n\n%s", file.RelPath, file.Language, file.LineCount, outline), nil
+}
@@ -2,6 +2,7 @@ package output
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,8 +12,8 @@ import (
 	"printcode2llm/internal/ui"
 )
 
-func CleanOldFiles(prefix string) error {
-	pattern := prefix + "*.md"
+func CleanOldFiles(prefix, ext string) error {
+	pattern := prefix + "*" + ext
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return err
@@ -27,7 +28,28 @@ func CleanOldFiles(prefix string) error {
 	return nil
 }
 
-func WriteResults(results []*generator.Result, cfg *config.Config) (int64, error) {
+// Stdout 输出前缀为 "-" 时把文档写到标准输出
+const Stdout = "-"
+
+// Options 写入选项
+type Options struct {
+	// Part 只输出第 N 部分（从 1 开始），0 表示全部
+	Part int
+	// Writer 非空时把各部分依次写到 Writer，而不是写文件或标准输出
+	Writer io.Writer
+	// Quiet 不输出写入进度，作为库调用时使用
+	Quiet bool
+}
+
+// PartFilename 第 partNum 部分的文件名
+func PartFilename(prefix, ext string, partNum, totalParts int) string {
+	if totalParts == 1 {
+		return prefix + ext
+	}
+	return fmt.Sprintf("%s_Part%d_of_%d%s", prefix, partNum, totalParts, ext)
+}
+
+func WriteResults(results []*generator.Result, cfg *config.Config, opts Options) (int64, error) {
 	var allSegments []*generator.Segment
 
 	for _, result := range results {
@@ -35,24 +57,29 @@ func WriteResults(results []*generator.Result, cfg *config.Config) (int64, error
 	}
 
 	totalParts := len(allSegments)
+	if opts.Part < 0 || opts.Part > totalParts {
+		return 0, fmt.Errorf("第 %d 部分不存在，共 %d 个部分", opts.Part, totalParts)
+	}
+
+	if opts.Writer != nil {
+		return writeStream(opts.Writer, results, allSegments, cfg, opts)
+	}
+	if cfg.Output.OutputPrefix == Stdout {
+		return writeStream(os.Stdout, results, allSegments, cfg, opts)
+	}
+
+	ext := generator.FormatExtension(cfg.Output.Format)
 	var totalSize int64
 
 	for i, segment := range allSegments {
 		partNum := i + 1
-
-		var filename string
-		if totalParts == 1 {
-			filename = fmt.Sprintf("%s.md", cfg.Output.OutputPrefix)
-		} else {
-			filename = fmt.Sprintf("%s_Part%d_of_%d.md", cfg.Output.OutputPrefix, partNum, totalParts)
+		if opts.Part > 0 && partNum != opts.Part {
+			continue
 		}
 
-		content := segment.Content
+		filename := PartFilename(cfg.Output.OutputPrefix, ext, partNum, totalParts)
 
-		if totalParts > 1 {
-			header := generatePartHeader(results, partNum, totalParts)
-			content = header + content
-		}
+		content := partContent(results, segment, partNum, totalParts, cfg)
 
 		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 			return totalSize, fmt.Errorf("写入 %s 失败: %w", filename, err)
@@ -62,12 +89,58 @@ func WriteResults(results []*generator.Result, cfg *config.Config) (int64, error
 		size := fileInfo.Size()
 		totalSize += size
 
-		ui.PrintSuccess("已写入: %s (%s)", filename, ui.FormatBytes(size))
+		if !opts.Quiet {
+			ui.PrintSuccess("已写入: %s (%s)", filename, ui.FormatBytes(size))
+		}
+	}
+
+	return totalSize, nil
+}
+
+// writeStream 依次写出各部分，部分之间用 part_delimiter 分隔
+func writeStream(w io.Writer, results []*generator.Result, segments []*generator.Segment, cfg *config.Config, opts Options) (int64, error) {
+	totalParts := len(segments)
+	var totalSize int64
+	written := 0
+
+	for i, segment := range segments {
+		partNum := i + 1
+		if opts.Part > 0 && partNum != opts.Part {
+			continue
+		}
+
+		content := partContent(results, segment, partNum, totalParts, cfg)
+		if written > 0 {
+			content = cfg.Output.PartDelimiter + content
+		}
+
+		n, err := io.WriteString(w, content)
+		totalSize += int64(n)
+		if err != nil {
+			return totalSize, fmt.Errorf("写入标准输出失败: %w", err)
+		}
+		written++
 	}
 
+	if opts.Quiet {
+		return totalSize, nil
+	}
+	if written == 1 {
+		ui.PrintSuccess("已输出到标准输出 (%s)", ui.FormatBytes(totalSize))
+	} else {
+		ui.PrintSuccess("已输出 %d 个部分到标准输出 (%s)", written, ui.FormatBytes(totalSize))
+	}
 	return totalSize, nil
 }
 
+// partContent 多部分时在 Markdown/XML 开头加上分段注释；JSON 没有注释语法，分段序号已包含在每条记录中
+func partContent(results []*generator.Result, segment *generator.Segment, partNum, totalParts int, cfg *config.Config) string {
+	if totalParts > 1 && hasCommentHeader(cfg.Output.Format) {
+		return generatePartHeader(results, partNum, totalParts) + segment.Content
+	}
+	return segment.Content
+}
+
 func generatePartHeader(results []*generator.Result, partNum, totalParts int) string {
 	var builder strings.Builder
 
@@ -82,4 +155,8 @@ func generatePartHeader(results []*generator.Result, partNum, totalParts int) st
 	builder.WriteString("-->\n\n")
 
 	return builder.String()
-}
\ No newline at end of file
+}
+
+func hasCommentHeader(format string) bool {
+	return format != generator.FormatJSON && format != generator.FormatJSONL
+}
@@ -0,0 +1,88 @@
+// Package parallel 提供有界并发的工作池
+package parallel
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Jobs 把配置中的并发数换算为实际的 worker 数，0 或负数表示 CPU 核心数
+func Jobs(n int) int {
+	if n <= 0 {
+		return runtime.NumCPU()
+	}
+	return n
+}
+
+// ForEach 用最多 jobs 个 goroutine 对 0 到 n-1 依次调用 fn，全部完成后返回
+//
+// 调用方按下标写入结果，输出顺序与输入一致，不受调度影响
+func ForEach(n, jobs int, fn func(i int)) {
+	ForEachContext(context.Background(), n, jobs, fn)
+}
+
+// ForEachContext 与 ForEach 相同，ctx 取消后不再分发新的下标，等已开始的调用结束后返回 ctx.Err()
+func ForEachContext(ctx context.Context, n, jobs int, fn func(i int)) error {
+	jobs = Jobs(jobs)
+	if jobs > n {
+		jobs = n
+	}
+	if jobs <= 1 {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(i)
+		}
+		return ctx.Err()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(jobs)
+	for w := 0; w < jobs; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Counter 统计完成数并回调进度；回调串行执行，fn 为 nil 时什么也不做
+type Counter struct {
+	mu    sync.Mutex
+	done  int
+	total int
+	fn    func(done, total int)
+}
+
+// NewCounter 创建进度计数器
+func NewCounter(total int, fn func(done, total int)) *Counter {
+	return &Counter{total: total, fn: fn}
+}
+
+// Done 完成一项
+func (c *Counter) Done() {
+	if c.fn == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done++
+	c.fn(c.done, c.total)
+}
@@ -0,0 +1,51 @@
+package picker
+
+// escapeKeys 方向键等 CSI/SS3 序列（去掉 ESC [ 或 ESC O 之后的部分）对应的按键
+var escapeKeys = map[string]string{
+	"A":  "up",
+	"B":  "down",
+	"C":  "right",
+	"D":  "left",
+	"H":  "home",
+	"F":  "end",
+	"1~": "home",
+	"7~": "home",
+	"4~": "end",
+	"8~": "end",
+	"5~": "pgup",
+	"6~": "pgdn",
+}
+
+// parseKeys 把一次读取的输入拆分为按键名，普通字符按原样返回
+func parseKeys(b []byte) []string {
+	var keys []string
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == 0x1b && i+1 < len(b) && (b[i+1] == '[' || b[i+1] == 'O'):
+			j := i + 2
+			for j < len(b) && (b[j] >= '0' && b[j] <= '9' || b[j] == ';') {
+				j++
+			}
+			if j < len(b) {
+				if key, ok := escapeKeys[string(b[i+2:j+1])]; ok {
+					keys = append(keys, key)
+				}
+			}
+			i = j + 1
+			continue
+		case c == 0x1b:
+			keys = append(keys, "esc")
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c == ' ':
+			keys = append(keys, "space")
+		case c == 0x03:
+			keys = append(keys, "ctrl-c")
+		case c < 0x80:
+			keys = append(keys, string(c))
+		}
+		i++
+	}
+	return keys
+}
@@ -0,0 +1,360 @@
+// Package picker 在终端中以目录树的形式勾选要整理的文件
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNotTerminal 没有可用的终端
+var ErrNotTerminal = errors.New("交互选择需要在终端中运行")
+
+// Item 一个候选文件
+type Item struct {
+	// Path 相对项目根目录的路径，以 / 分隔
+	Path   string
+	Size   int64
+	Chars  int
+	Tokens int
+}
+
+// Options 选择器选项
+type Options struct {
+	// Title 显示在第一行
+	Title string
+	// Parts 按已选内容估算分段数，为 nil 时不显示
+	Parts func(chars, tokens int) int
+	// Save 保存当前未选中的路径（目录以 / 结尾），返回显示在状态栏的提示
+	Save func(excluded []string) (string, error)
+}
+
+// Result 选择结果
+type Result struct {
+	// Selected 选中的文件路径
+	Selected []string
+	// Excluded 覆盖全部未选文件的最少路径：整个目录未选时只列出目录（以 / 结尾）
+	Excluded []string
+	// Cancelled 放弃选择
+	Cancelled bool
+}
+
+// Run 打开终端显示选择器，直到确认或取消；所有文件默认选中
+func Run(items []Item, opts Options) (*Result, error) {
+	t, err := openTerminal()
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	defer t.close()
+
+	// 备用屏幕，退出后恢复原来的终端内容
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+
+	p := newPicker(items, opts)
+	buf := make([]byte, 64)
+	for {
+		width, height := t.size()
+		fmt.Fprint(t.out, p.render(width, height))
+
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			switch p.handle(key) {
+			case actionDone:
+				return p.result(), nil
+			case actionCancel:
+				return &Result{Cancelled: true}, nil
+			}
+		}
+	}
+}
+
+// node 目录树中的一个文件或目录
+type node struct {
+	name     string
+	path     string
+	dir      bool
+	depth    int
+	parent   *node
+	children []*node
+
+	expanded bool
+	// selected 文件是否选中，目录的状态由子节点汇总
+	selected bool
+
+	size   int64
+	chars  int
+	tokens int
+
+	// 以下为汇总值，文件也按 1 个文件计算
+	files        int
+	picked       int
+	pickedChars  int
+	pickedTokens int
+}
+
+// state 选中状态：全部、部分或未选
+func (n *node) state() string {
+	switch {
+	case n.picked == 0:
+		return "[ ]"
+	case n.picked == n.files:
+		return "[x]"
+	default:
+		return "[~]"
+	}
+}
+
+type picker struct {
+	opts    Options
+	root    *node
+	cursor  int
+	offset  int
+	message string
+}
+
+func newPicker(items []Item, opts Options) *picker {
+	root := &node{dir: true, expanded: true, depth: -1}
+	dirs := map[string]*node{"": root}
+
+	var ensureDir func(dirPath string) *node
+	ensureDir = func(dirPath string) *node {
+		if d, ok := dirs[dirPath]; ok {
+			return d
+		}
+		parentPath, name := "", dirPath
+		if i := strings.LastIndex(dirPath, "/"); i >= 0 {
+			parentPath, name = dirPath[:i], dirPath[i+1:]
+		}
+		parent := ensureDir(parentPath)
+		d := &node{name: name, path: dirPath, dir: true, depth: parent.depth + 1, parent: parent}
+		parent.children = append(parent.children, d)
+		dirs[dirPath] = d
+		return d
+	}
+
+	for _, item := range items {
+		dirPath, name := "", item.Path
+		if i := strings.LastIndex(item.Path, "/"); i >= 0 {
+			dirPath, name = item.Path[:i], item.Path[i+1:]
+		}
+		parent := ensureDir(dirPath)
+		parent.children = append(parent.children, &node{
+			name:     name,
+			path:     item.Path,
+			depth:    parent.depth + 1,
+			parent:   parent,
+			selected: true,
+			size:     item.Size,
+			chars:    item.Chars,
+			tokens:   item.Tokens,
+		})
+	}
+
+	for _, d := range dirs {
+		sort.SliceStable(d.children, func(i, j int) bool {
+			a, b := d.children[i], d.children[j]
+			if a.dir != b.dir {
+				return a.dir
+			}
+			return a.name < b.name
+		})
+	}
+
+	p := &picker{opts: opts, root: root}
+	p.refresh(root)
+	return p
+}
+
+// refresh 重新计算 n 及其子树的汇总值
+func (p *picker) refresh(n *node) {
+	if !n.dir {
+		n.files = 1
+		n.picked, n.pickedChars, n.pickedTokens = 0, 0, 0
+		if n.selected {
+			n.picked, n.pickedChars, n.pickedTokens = 1, n.chars, n.tokens
+		}
+		return
+	}
+
+	n.files, n.picked, n.pickedChars, n.pickedTokens = 0, 0, 0, 0
+	n.size, n.chars, n.tokens = 0, 0, 0
+	for _, c := range n.children {
+		p.refresh(c)
+		n.files += c.files
+		n.picked += c.picked
+		n.pickedChars += c.pickedChars
+		n.pickedTokens += c.pickedTokens
+		n.size += c.size
+		n.chars += c.chars
+		n.tokens += c.tokens
+	}
+}
+
+// rows 当前可见的行，按目录展开状态展平
+func (p *picker) rows() []*node {
+	var rows []*node
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, c := range n.children {
+			rows = append(rows, c)
+			if c.dir && c.expanded {
+				walk(c)
+			}
+		}
+	}
+	walk(p.root)
+	return rows
+}
+
+func setAll(n *node, selected bool) {
+	if !n.dir {
+		n.selected = selected
+		return
+	}
+	for _, c := range n.children {
+		setAll(c, selected)
+	}
+}
+
+// toggle 切换选中状态：目录未全选时全选，否则全部取消
+func (p *picker) toggle(n *node) {
+	setAll(n, n.picked != n.files)
+	p.refresh(p.root)
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	actionDone
+	actionCancel
+)
+
+// handle 处理一个按键
+func (p *picker) handle(key string) action {
+	rows := p.rows()
+	if len(rows) == 0 {
+		if key == "q" || key == "esc" || key == "ctrl-c" {
+			return actionCancel
+		}
+		return actionNone
+	}
+	if p.cursor >= len(rows) {
+		p.cursor = len(rows) - 1
+	}
+	current := rows[p.cursor]
+	p.message = ""
+
+	switch key {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(rows)-1 {
+			p.cursor++
+		}
+	case "pgup":
+		p.cursor = max(p.cursor-10, 0)
+	case "pgdn":
+		p.cursor = min(p.cursor+10, len(rows)-1)
+	case "home", "g":
+		p.cursor = 0
+	case "end", "G":
+		p.cursor = len(rows) - 1
+	case "right", "l":
+		if current.dir {
+			if current.expanded && p.cursor < len(rows)-1 {
+				p.cursor++
+			}
+			current.expanded = true
+		}
+	case "left", "h":
+		if current.dir && current.expanded {
+			current.expanded = false
+		} else if current.parent != p.root {
+			for i, row := range rows {
+				if row == current.parent {
+					p.cursor = i
+					break
+				}
+			}
+		}
+	case "space":
+		p.toggle(current)
+	case "a":
+		p.toggle(p.root)
+	case "s":
+		p.save()
+	case "enter":
+		if p.root.picked == 0 {
+			p.message = "至少需要选择一个文件"
+			return actionNone
+		}
+		return actionDone
+	case "q", "esc", "ctrl-c":
+		return actionCancel
+	}
+	return actionNone
+}
+
+func (p *picker) save() {
+	if p.opts.Save == nil {
+		return
+	}
+	excluded := p.excluded()
+	if len(excluded) == 0 {
+		p.message = "没有未选中的文件，无需保存"
+		return
+	}
+	msg, err := p.opts.Save(excluded)
+	if err != nil {
+		p.message = "保存失败: " + err.Error()
+		return
+	}
+	p.message = msg
+}
+
+// excluded 覆盖全部未选文件的最少路径
+func (p *picker) excluded() []string {
+	var paths []string
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, c := range n.children {
+			switch {
+			case c.picked == c.files:
+			case c.picked == 0 && c.dir:
+				paths = append(paths, c.path+"/")
+			case c.picked == 0:
+				paths = append(paths, c.path)
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(p.root)
+	return paths
+}
+
+func (p *picker) result() *Result {
+	r := &Result{Excluded: p.excluded()}
+	var walk func(n *node)
+	walk = func(n *node) {
+		if !n.dir {
+			if n.selected {
+				r.Selected = append(r.Selected, n.path)
+			}
+			return
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(p.root)
+	return r
+}
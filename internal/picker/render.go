@@ -0,0 +1,150 @@
+package picker
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"printcode2llm/internal/ui"
+)
+
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+)
+
+const helpLine = "↑↓ 移动  空格 选择  ←→ 折叠/展开  a 全选  s 保存规则  回车 生成  q 取消"
+
+// render 生成一整屏内容，从左上角开始覆盖上一次的画面
+func (p *picker) render(width, height int) string {
+	if width < 40 {
+		width = 40
+	}
+	if height < 8 {
+		height = 8
+	}
+
+	rows := p.rows()
+	if p.cursor >= len(rows) {
+		p.cursor = max(len(rows)-1, 0)
+	}
+	view := height - 4
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+view {
+		p.offset = p.cursor - view + 1
+	}
+	p.offset = max(min(p.offset, len(rows)-view), 0)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	line := func(style, text string) {
+		text = truncate(text, width)
+		if style != "" {
+			text = style + text + styleReset
+		}
+		b.WriteString(text + "\x1b[K\r\n")
+	}
+
+	line(styleBold, p.opts.Title)
+	line(styleDim, helpLine)
+	line("", "")
+
+	for i := p.offset; i < p.offset+view; i++ {
+		if i >= len(rows) {
+			line("", "")
+			continue
+		}
+		style := ""
+		if rows[i].picked == 0 {
+			style = styleDim
+		}
+		if i == p.cursor {
+			style = styleReverse
+		}
+		line(style, formatRow(rows[i], width))
+	}
+
+	status := fmt.Sprintf("已选 %d/%d 个文件 · 约 %s tokens",
+		p.root.picked, p.root.files, ui.FormatNumber(p.root.pickedTokens))
+	if p.opts.Parts != nil {
+		status += fmt.Sprintf(" · 约 %d 个部分", p.opts.Parts(p.root.pickedChars, p.root.pickedTokens))
+	}
+	if p.message != "" {
+		status += "  " + p.message
+	}
+	b.WriteString(styleBold + truncate(status, width) + styleReset + "\x1b[K\x1b[J")
+	return b.String()
+}
+
+// formatRow 一行：缩进、展开标记、选中状态、名称，右侧对齐大小与 token 数
+func formatRow(n *node, width int) string {
+	marker := "  "
+	name := n.name
+	if n.dir {
+		marker = "▸ "
+		if n.expanded {
+			marker = "▾ "
+		}
+		name += "/"
+	}
+	left := strings.Repeat("  ", n.depth) + marker + n.state() + " " + name
+
+	right := fmt.Sprintf("%9s  %8s tok", ui.FormatBytes(n.size), ui.FormatNumber(n.tokens))
+	if n.dir {
+		right = fmt.Sprintf("%d/%d 个文件  ", n.picked, n.files) + right
+	}
+
+	gap := width - displayWidth(left) - displayWidth(right) - 1
+	if gap < 1 {
+		left = truncate(left, max(width-displayWidth(right)-2, 10))
+		gap = max(width-displayWidth(left)-displayWidth(right)-1, 1)
+	}
+	return left + strings.Repeat(" ", gap) + right
+}
+
+// truncate 按显示宽度截断，超出时以 … 结尾
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	w := 0
+	for i, r := range s {
+		rw := runeWidth(r)
+		if w+rw > width-1 {
+			return s[:i] + "…"
+		}
+		w += rw
+	}
+	return s
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth 东亚宽字符占两列，其余按一列计算
+func runeWidth(r rune) int {
+	switch {
+	case r == utf8.RuneError:
+		return 1
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package picker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// terminal 控制终端，输出可能被重定向时仍直接读写 /dev/tty
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state string
+}
+
+// openTerminal 打开控制终端并切换到原始模式
+func openTerminal() (*terminal, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	state, err := stty(tty, "-g")
+	if err != nil {
+		tty.Close()
+		return nil, err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		tty.Close()
+		return nil, err
+	}
+	return &terminal{in: tty, out: tty, state: strings.TrimSpace(state)}, nil
+}
+
+// close 恢复终端原来的模式
+func (t *terminal) close() {
+	stty(t.in, t.state)
+	t.in.Close()
+}
+
+// size 终端的列数与行数，无法获取时使用 80x24
+func (t *terminal) size() (int, int) {
+	out, err := stty(t.in, "size")
+	if err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	return 80, 24
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,122 @@
+//go:build windows
+// +build windows
+
+package picker
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode             = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+type coord struct {
+	x, y int16
+}
+
+type smallRect struct {
+	left, top, right, bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	size              coord
+	cursorPosition    coord
+	attributes        uint16
+	window            smallRect
+	maximumWindowSize coord
+}
+
+// terminal 控制台输入输出，输出可能被重定向时仍直接读写 CONIN$/CONOUT$
+type terminal struct {
+	in      *os.File
+	out     *os.File
+	inMode  uint32
+	outMode uint32
+}
+
+// openTerminal 打开控制台，关闭行缓冲与回显并开启 VT 序列
+func openTerminal() (*terminal, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+
+	t := &terminal{in: in, out: out}
+	if err := getConsoleMode(in, &t.inMode); err != nil {
+		t.closeFiles()
+		return nil, err
+	}
+	if err := getConsoleMode(out, &t.outMode); err != nil {
+		t.closeFiles()
+		return nil, err
+	}
+
+	inMode := t.inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(in, inMode); err != nil {
+		t.closeFiles()
+		return nil, err
+	}
+	if err := setConsoleMode(out, t.outMode|enableVirtualTerminalProcessing); err != nil {
+		setConsoleMode(in, t.inMode)
+		t.closeFiles()
+		return nil, err
+	}
+	return t, nil
+}
+
+// close 恢复控制台原来的模式
+func (t *terminal) close() {
+	setConsoleMode(t.in, t.inMode)
+	setConsoleMode(t.out, t.outMode)
+	t.closeFiles()
+}
+
+func (t *terminal) closeFiles() {
+	t.in.Close()
+	t.out.Close()
+}
+
+// size 控制台窗口的列数与行数，无法获取时使用 80x24
+func (t *terminal) size() (int, int) {
+	var info consoleScreenBufferInfo
+	ret, _, _ := procGetConsoleScreenBufferInfo.Call(t.out.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return 80, 24
+	}
+	return int(info.window.right-info.window.left) + 1, int(info.window.bottom-info.window.top) + 1
+}
+
+func getConsoleMode(f *os.File, mode *uint32) error {
+	ret, _, err := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(mode)))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
+func setConsoleMode(f *os.File, mode uint32) error {
+	ret, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(mode))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
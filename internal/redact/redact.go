@@ -0,0 +1,360 @@
+package redact
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+)
+
+// allowMarkers 行内含有这些标记时不检查该行
+var allowMarkers = []string{"ptlm:allow", "pragma: allowlist secret"}
+
+// placeholderPattern 遮盖后的占位符，例如 [REDACTED:jwt:2]
+var placeholderPattern = regexp.MustCompile(`\[REDACTED:[a-z0-9-]+:\d+\]`)
+
+// codeLanguages 这些语言中未加引号的赋值是变量或表达式，不按密码处理
+var codeLanguages = map[string]bool{
+	"javascript": true, "typescript": true, "python": true, "java": true,
+	"c": true, "cpp": true, "go": true, "rust": true, "php": true, "ruby": true,
+	"swift": true, "kotlin": true, "scala": true, "dart": true, "csharp": true,
+	"objc": true, "vue": true, "svelte": true, "lua": true, "perl": true,
+	"groovy": true, "r": true, "elixir": true, "erlang": true, "haskell": true,
+	"ocaml": true, "clojure": true,
+}
+
+// configLanguages 与 configExts 配置文件，未加引号的值也可能是密码，例如 .env 中的 DB_PASSWORD=hunter2
+var configLanguages = map[string]bool{"yaml": true, "toml": true, "ini": true}
+
+var configExts = map[string]bool{
+	".env": true, ".ini": true, ".cfg": true, ".conf": true, ".properties": true,
+	".yaml": true, ".yml": true, ".toml": true,
+}
+
+// Source 内容的类别，决定未加引号的赋值是否按密码处理
+type Source int
+
+const (
+	// SourceText 文档等其他文本，只检查带引号的值，说明文字中的 password=... 不按密码处理
+	SourceText Source = iota
+	// SourceCode 程序代码，未加引号的是变量或表达式
+	SourceCode
+	// SourceConfig 配置文件，未加引号的值同样按密码处理
+	SourceConfig
+)
+
+// sourceOf 按语言与文件名判断内容的类别，.env.local 等以 .env 开头的文件也是配置文件
+func sourceOf(file *scanner.FileInfo) Source {
+	name := strings.ToLower(path.Base(file.RelPath))
+	switch {
+	case codeLanguages[file.Language]:
+		return SourceCode
+	case configLanguages[file.Language], configExts[path.Ext(name)], name == ".env", strings.HasPrefix(name, ".env."):
+		return SourceConfig
+	}
+	return SourceText
+}
+
+// Finding 一处被遮盖的敏感信息，Line 为原文中的行号
+type Finding struct {
+	Line        int
+	Kind        string
+	Placeholder string
+}
+
+// Report 一个文件的遮盖结果
+type Report struct {
+	Path     string
+	Findings []Finding
+}
+
+// Summary 按类型汇总，例如 "credential (行 3, 8), jwt (行 10)"
+func (r *Report) Summary() string {
+	var kinds []string
+	lines := make(map[string][]string)
+	for _, f := range r.Findings {
+		if _, ok := lines[f.Kind]; !ok {
+			kinds = append(kinds, f.Kind)
+		}
+		lines[f.Kind] = append(lines[f.Kind], fmt.Sprint(f.Line))
+	}
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s (行 %s)", kind, strings.Join(lines[kind], ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Redactor 在内容发送出去之前遮盖密钥等敏感信息
+//
+// 同一个值在所有文件中使用同一个占位符，占位符按类型编号，不包含值的任何信息
+type Redactor struct {
+	allowPaths  []string
+	allowValues []*regexp.Regexp
+
+	mu           sync.Mutex
+	placeholders map[string]string
+	counters     map[string]int
+	// known 已发现的值，之后在其他位置原样出现时同样遮盖
+	known []span
+}
+
+// New 按配置创建，允许列表中的正则无效时返回错误
+func New(cfg *config.Config) (*Redactor, error) {
+	r := &Redactor{
+		allowPaths:   cfg.Redact.AllowPaths,
+		placeholders: make(map[string]string),
+		counters:     make(map[string]int),
+	}
+	for _, expr := range cfg.Redact.AllowValues {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的允许值正则 %q: %w", expr, err)
+		}
+		r.allowValues = append(r.allowValues, re)
+	}
+	return r, nil
+}
+
+// Files 遮盖各文件正文与 diff 中的敏感信息，返回有遮盖的文件报告
+func (r *Redactor) Files(files []*scanner.FileInfo) []*Report {
+	var reports []*Report
+	for _, file := range files {
+		if scanner.MatchAny(r.allowPaths, file.RelPath) {
+			continue
+		}
+
+		src := sourceOf(file)
+		report := &Report{Path: file.RelPath}
+
+		if file.Content != "" {
+			content, findings, lineMap := r.redact(file.Content, src)
+			if len(findings) > 0 {
+				file.Content = content
+				file.LineCount = scanner.CountLines(content)
+				file.LineMap = composeLineMap(file.LineMap, lineMap)
+				report.Findings = append(report.Findings, findings...)
+			}
+		}
+		if file.Diff != "" {
+			// diff 中的行号不对应文件，这里只遮盖，重复的发现不再计入
+			diff, findings := r.Redact(file.Diff, src)
+			if len(findings) > 0 {
+				file.Diff = diff
+				if len(report.Findings) == 0 {
+					report.Findings = findings
+				}
+			}
+		}
+
+		if len(report.Findings) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// span 原文中一段待替换的区间；用于记录已发现的值时只有 value 与 kind
+type span struct {
+	start, end int
+	kind       string
+	value      string
+}
+
+// Redact 遮盖一段文本中的敏感信息，src 为内容的类别
+func (r *Redactor) Redact(content string, src Source) (string, []Finding) {
+	redacted, findings, _ := r.redact(content, src)
+	return redacted, findings
+}
+
+// redact 同 Redact，遮盖的值跨越多行（例如私钥）时同时返回结果各行在原文中的行号，
+// 格式与 scanner.FileInfo.LineMap 相同；行数不变时为 nil
+func (r *Redactor) redact(content string, src Source) (string, []Finding, []int) {
+	var spans []span
+	lower := asciiLower(content)
+	for _, d := range detectors {
+		for _, m := range d.matches(content, lower) {
+			start, end := valueRange(m, d.group)
+			if start < 0 || overlaps(spans, start, end) {
+				continue
+			}
+			value := content[start:end]
+			if d.accept != nil && !d.accept(value, src) {
+				continue
+			}
+			// 带引号的值只遮盖引号内的部分
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				start, end = start+1, end-1
+				value = content[start:end]
+			}
+			if placeholderPattern.MatchString(value) || r.allowed(content, start, value) {
+				continue
+			}
+			// 先分配占位符，文件中其他位置出现的同一个值也能在下面被遮盖
+			r.placeholder(d.kind, value)
+			spans = append(spans, span{start: start, end: end, kind: d.kind})
+		}
+	}
+
+	// 规则只认得出赋值给 password 的值，同一个值赋给其他变量时按已发现的值遮盖
+	for _, k := range r.knownValues() {
+		for offset := 0; ; {
+			idx := strings.Index(content[offset:], k.value)
+			if idx < 0 {
+				break
+			}
+			start := offset + idx
+			end := start + len(k.value)
+			if !overlaps(spans, start, end) && !r.allowed(content, start, k.value) {
+				spans = append(spans, span{start: start, end: end, kind: k.kind})
+			}
+			offset = end
+		}
+	}
+
+	if len(spans) == 0 {
+		return content, nil, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var builder strings.Builder
+	findings := make([]Finding, 0, len(spans))
+	lineMap := []int{1}
+	// copyLines 原样保留的文本中每个换行都开始新的一行
+	copyLines := func(text string, line int) int {
+		for n := strings.Count(text, "\n"); n > 0; n-- {
+			line++
+			lineMap = append(lineMap, line)
+		}
+		return line
+	}
+	last, line, merged := 0, 1, false
+	for _, s := range spans {
+		line = copyLines(content[last:s.start], line)
+		placeholder := r.placeholder(s.kind, content[s.start:s.end])
+		builder.WriteString(content[last:s.start])
+		builder.WriteString(placeholder)
+		findings = append(findings, Finding{Line: line, Kind: s.kind, Placeholder: placeholder})
+		if n := strings.Count(content[s.start:s.end], "\n"); n > 0 {
+			line += n
+			merged = true
+		}
+		last = s.end
+	}
+	line = copyLines(content[last:], line)
+	builder.WriteString(content[last:])
+
+	if !merged {
+		return builder.String(), findings, nil
+	}
+	return builder.String(), findings, append(lineMap, line+1)
+}
+
+// composeLineMap 在已有的行号对应关系上再叠加一次遮盖的结果
+func composeLineMap(base, next []int) []int {
+	if base == nil {
+		return next
+	}
+	if next == nil {
+		return base
+	}
+	result := make([]int, len(next))
+	for i, n := range next {
+		if n-1 < len(base) {
+			result[i] = base[n-1]
+		} else {
+			result[i] = base[len(base)-1]
+		}
+	}
+	return result
+}
+
+// valueRange 从匹配结果中取出需要遮盖的区间
+func valueRange(m []int, group int) (int, int) {
+	switch {
+	case group > 0:
+		return m[2*group], m[2*group+1]
+	case group < 0:
+		for g := 1; 2*g < len(m); g++ {
+			if m[2*g] >= 0 {
+				return m[2*g], m[2*g+1]
+			}
+		}
+		return -1, -1
+	}
+	return m[0], m[1]
+}
+
+func overlaps(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed 值在允许列表中，或所在行带有允许标记
+func (r *Redactor) allowed(content string, pos int, value string) bool {
+	for _, re := range r.allowValues {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+
+	lineStart := strings.LastIndex(content[:pos], "\n") + 1
+	lineEnd := strings.Index(content[pos:], "\n")
+	if lineEnd < 0 {
+		lineEnd = len(content)
+	} else {
+		lineEnd += pos
+	}
+	line := content[lineStart:lineEnd]
+	for _, marker := range allowMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder 同一个值总是得到同一个占位符
+func (r *Redactor) placeholder(kind, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := kind + "\x00" + value
+	if p, ok := r.placeholders[key]; ok {
+		return p
+	}
+	r.counters[kind]++
+	p := fmt.Sprintf("[REDACTED:%s:%d]", kind, r.counters[kind])
+	r.placeholders[key] = p
+	if len(value) >= minKnownLength {
+		r.known = append(r.known, span{kind: kind, value: value})
+	}
+	return p
+}
+
+// minKnownLength 短值容易与普通文本重合，不参与跨位置遮盖
+const minKnownLength = 8
+
+func (r *Redactor) knownValues() []span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]span(nil), r.known...)
+}
+
+// ContainsPlaceholder 文本中是否有遮盖后的占位符，写回文件前用于提醒
+func ContainsPlaceholder(text string) bool {
+	return placeholderPattern.MatchString(text)
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+
+	"printcode2llm/internal/charset"
+	"printcode2llm/internal/config"
+)
+
+// encodingRule 一条 source_encoding 规则
+type encodingRule struct {
+	includeRule
+	encoding string
+}
+
+// encodingRules 按顺序排列的编码规则，后出现的优先
+type encodingRules []encodingRule
+
+// compileEncodingRules 解析 source_encoding，模式无效或编码未知时返回错误
+func compileEncodingRules(rules []config.EncodingRule) (encodingRules, error) {
+	compiled := make(encodingRules, 0, len(rules))
+	for _, r := range rules {
+		enc, ok := charset.Lookup(r.Encoding)
+		if !ok {
+			return nil, fmt.Errorf("source_encoding: 未知的编码 %q，可选: utf-8/gbk/gb18030/big5/shift_jis/latin1/utf-16le/utf-16be", r.Encoding)
+		}
+		rule, ok := parseIncludePattern(r.Pattern)
+		if !ok || rule.negate {
+			return nil, fmt.Errorf("source_encoding: 无效的模式 %q", r.Pattern)
+		}
+		compiled = append(compiled, encodingRule{includeRule: rule, encoding: enc})
+	}
+	return compiled, nil
+}
+
+// ValidateSourceEncoding 检查 source_encoding 的模式与编码名称
+func ValidateSourceEncoding(rules []config.EncodingRule) error {
+	_, err := compileEncodingRules(rules)
+	return err
+}
+
+// lookup 文件指定的编码，路径本身或任一上级目录匹配即生效；没有规则匹配时为空，表示自动检测
+func (rules encodingRules) lookup(relPath string) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	parts := strings.Split(relPath, "/")
+	encoding := ""
+	for j := range rules {
+		rule := &rules[j]
+		for i := range parts {
+			isDir := i < len(parts)-1
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(strings.Join(parts[:i+1], "/")) {
+				encoding = rule.encoding
+				break
+			}
+		}
+	}
+	return encoding
+}
@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/config"
+)
+
+// Exclusion 扫描时被排除的一个路径及原因
+type Exclusion struct {
+	// RelPath 相对扫描目录、以 / 分隔；目录被排除时其下的路径不再单独报告
+	RelPath string
+	IsDir   bool
+	Reason  string
+	// Rule 命中的规则，例如忽略模式、正则或 .gitignore 中的一行，可为空
+	Rule string
+	// Source 规则的来源：配置项名称或忽略文件的路径，可为空
+	Source string
+}
+
+// 排除原因，另见 SkipTooLarge 与 SkipUnreadable
+const (
+	ExcludeDefault       = "默认忽略"
+	ExcludeCustom        = "自定义排除"
+	ExcludeRegex         = "正则排除"
+	ExcludeGitignore     = "gitignore 忽略"
+	ExcludeNotIncluded   = "不在包含范围"
+	ExcludeBinaryExt     = "二进制扩展名"
+	ExcludeBinaryContent = "二进制内容"
+)
+
+// skippedFile 过大或无法读取的排除项对应的跳过记录，其他原因返回 false
+func (e *Exclusion) skippedFile(size int64) (SkippedFile, bool) {
+	switch e.Reason {
+	case SkipTooLarge:
+		return SkippedFile{RelPath: e.RelPath, Size: size, Reason: e.Reason}, true
+	case SkipUnreadable:
+		return SkippedFile{RelPath: e.RelPath, Size: size, Reason: e.Reason + ": " + e.Rule}, true
+	}
+	return SkippedFile{}, false
+}
+
+// fileExclusion 通过忽略规则的文件按扩展名与大小是否排除，未排除时为 nil
+func fileExclusion(relPath string, size int64, cfg *config.Config) *Exclusion {
+	if isBinaryExtension(relPath, cfg) {
+		return &Exclusion{RelPath: relPath, Reason: ExcludeBinaryExt, Rule: strings.ToLower(path.Ext(relPath)), Source: "binary_extensions"}
+	}
+	if cfg.MaxFileSize > 0 && size > cfg.MaxFileSize {
+		return &Exclusion{RelPath: relPath, Reason: SkipTooLarge, Rule: fmt.Sprintf("%d > %d 字节", size, cfg.MaxFileSize), Source: "max_file_size"}
+	}
+	return nil
+}
+
+// unreadable 无法读取的路径，规则中不重复路径
+func unreadable(relPath string, isDir bool, err error) *Exclusion {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &Exclusion{RelPath: relPath, IsDir: isDir, Reason: SkipUnreadable, Rule: err.Error()}
+}
+
+// Explanation 一个路径是否会被整理
+type Explanation struct {
+	RelPath string
+	IsDir   bool
+	// Exclusion 被排除时的原因，RelPath 可能是被排除的上级目录；会被整理时为 nil
+	Exclusion *Exclusion
+	// File 会被整理的文件内容与检测结果，目录或被排除时为 nil
+	File *FileInfo
+}
+
+// Explain 按扫描时的顺序检查 dir 下的 relPath：上级目录、忽略与包含规则、扩展名、大小与内容。
+// 与变更范围等过滤条件无关
+func Explain(dir, relPath string, cfg *config.Config) (*Explanation, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+	relPath = path.Clean(filepath.ToSlash(relPath))
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return nil, fmt.Errorf("%s 不是 %s 中的文件或目录", relPath, dir)
+	}
+
+	fsys := os.DirFS(absDir)
+	info, err := fs.Stat(fsys, relPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("文件或目录不存在")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreChecker := NewIgnoreChecker(absDir, cfg)
+	exp := &Explanation{RelPath: relPath, IsDir: info.IsDir()}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if e := ignoreChecker.Explain(strings.Join(parts[:i], "/"), true); e != nil {
+			exp.Exclusion = e
+			return exp, nil
+		}
+	}
+	if e := ignoreChecker.Explain(relPath, exp.IsDir); e != nil {
+		exp.Exclusion = e
+		return exp, nil
+	}
+	if exp.IsDir {
+		return exp, nil
+	}
+
+	if e := fileExclusion(relPath, info.Size(), cfg); e != nil {
+		exp.Exclusion = e
+		return exp, nil
+	}
+	encodings, err := compileEncodingRules(cfg.SourceEncoding)
+	if err != nil {
+		return nil, err
+	}
+	file, e := loadFile(fsys, candidate{
+		path:     filepath.Join(absDir, filepath.FromSlash(relPath)),
+		relPath:  relPath,
+		size:     info.Size(),
+		encoding: encodings.lookup(relPath),
+	}, cfg, nil, "")
+	exp.File, exp.Exclusion = file, e
+	return exp, nil
+}
@@ -0,0 +1,395 @@
+package scanner
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// gitignoreRule 单条 gitignore 规则
+type gitignoreRule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// gitignoreFile 一个忽略文件及其生效目录
+type gitignoreFile struct {
+	source string
+	base   string // 相对仓库根目录，"" 表示根目录
+	rules  []gitignoreRule
+}
+
+// gitignoreMatcher 按 git 语义匹配 .gitignore、.git/info/exclude 与全局忽略文件
+type gitignoreMatcher struct {
+	// fsys 仓库根目录，各级 .gitignore 从这里读取
+	fsys fs.FS
+	// display 显示忽略文件来源时使用的根路径，为空时显示相对路径
+	display string
+	// prefix 扫描目录相对仓库根目录的路径，"" 表示扫描的就是仓库根目录
+	prefix  string
+	globals []*gitignoreFile
+
+	mu    sync.Mutex
+	cache map[string]*gitignoreFile
+}
+
+// newGitignoreMatcherFS 以 fsys 的根目录为仓库根目录创建匹配器，只读取其中的 .gitignore
+func newGitignoreMatcherFS(fsys fs.FS) *gitignoreMatcher {
+	return &gitignoreMatcher{
+		fsys:  fsys,
+		cache: make(map[string]*gitignoreFile),
+	}
+}
+
+// newGitignoreMatcher 以 root 所在的 git 仓库为准创建匹配器，不在仓库中时以 root 为根
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	repoRoot, gitDir := findGitRepo(root)
+	if repoRoot == "" {
+		m := newGitignoreMatcherFS(os.DirFS(root))
+		m.display = root
+		return m
+	}
+
+	m := newGitignoreMatcherFS(os.DirFS(repoRoot))
+	m.display = repoRoot
+	if rel, err := filepath.Rel(repoRoot, root); err == nil && rel != "." {
+		m.prefix = filepath.ToSlash(rel)
+	}
+
+	if path := globalExcludesFile(gitDir); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			m.globals = appendIfNotNil(m.globals, parseGitignoreFile(data, path, ""))
+		}
+	}
+	exclude := filepath.Join(gitDir, "info", "exclude")
+	if data, err := os.ReadFile(exclude); err == nil {
+		m.globals = appendIfNotNil(m.globals, parseGitignoreFile(data, exclude, ""))
+	}
+
+	return m
+}
+
+// Match 判断相对扫描目录的路径（以 / 分隔）是否被忽略，同时返回命中的规则（未命中时为 nil）
+func (m *gitignoreMatcher) Match(relPath string, isDir bool) (bool, *gitignoreRule, string) {
+	rel := path.Join(m.prefix, relPath)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false, nil, ""
+	}
+
+	// 父目录被忽略时，其下文件无法被重新包含
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if ignored, rule, source := m.matchRel(parent, true); ignored {
+			return true, rule, source
+		}
+	}
+
+	return m.matchRel(rel, isDir)
+}
+
+func (m *gitignoreMatcher) matchRel(rel string, isDir bool) (bool, *gitignoreRule, string) {
+	files := append([]*gitignoreFile{}, m.globals...)
+
+	dir := ""
+	files = appendIfNotNil(files, m.load(dir))
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		if dir == "" {
+			dir = part
+		} else {
+			dir += "/" + part
+		}
+		files = appendIfNotNil(files, m.load(dir))
+	}
+
+	// 越深层的文件优先级越高，同一文件内后出现的规则优先
+	ignored := false
+	var matched *gitignoreRule
+	source := ""
+	for _, f := range files {
+		target := rel
+		if f.base != "" {
+			target = strings.TrimPrefix(rel, f.base+"/")
+		}
+		for i := range f.rules {
+			rule := &f.rules[i]
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(target) {
+				ignored = !rule.negate
+				matched = rule
+				source = f.source
+			}
+		}
+	}
+
+	return ignored, matched, source
+}
+
+func (m *gitignoreMatcher) load(dir string) *gitignoreFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, ok := m.cache[dir]; ok {
+		return f
+	}
+
+	name := path.Join(dir, ".gitignore")
+	var f *gitignoreFile
+	if data, err := fs.ReadFile(m.fsys, name); err == nil {
+		source := name
+		if m.display != "" {
+			source = filepath.Join(m.display, filepath.FromSlash(name))
+		}
+		f = parseGitignoreFile(data, source, dir)
+	}
+	m.cache[dir] = f
+	return f
+}
+
+func appendIfNotNil(files []*gitignoreFile, f *gitignoreFile) []*gitignoreFile {
+	if f == nil {
+		return files
+	}
+	return append(files, f)
+}
+
+// parseGitignoreFile 解析忽略文件，没有有效规则时返回 nil
+func parseGitignoreFile(data []byte, source, base string) *gitignoreFile {
+	f := &gitignoreFile{source: source, base: base}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rule, ok := parseGitignoreLine(line); ok {
+			f.rules = append(f.rules, rule)
+		}
+	}
+
+	if len(f.rules) == 0 {
+		return nil
+	}
+	return f
+}
+
+// parseGitignoreLine 解析单行 gitignore 规则
+func parseGitignoreLine(line string) (gitignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{pattern: line}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	// 开头或中间含有 / 的模式相对于 .gitignore 所在目录
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	re, err := compileGitignorePattern(line, anchored)
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	rule.re = re
+
+	return rule, true
+}
+
+func trimUnescapedTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// compileGitignorePattern 将 gitignore 通配符转换为正则
+func compileGitignorePattern(pattern string, anchored bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				atStart := i == 0 || pattern[i-1] == '/'
+				next := i + 2
+				atEnd := next == len(pattern) || pattern[next] == '/'
+				if atStart && atEnd {
+					if next == len(pattern) {
+						b.WriteString(".*")
+					} else {
+						b.WriteString("(?:.*/)?")
+					}
+					i = next
+					continue
+				}
+				b.WriteString("[^/]*")
+				i++
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == 0 && i+2 < len(pattern) {
+				// 紧跟 [ 的 ] 视为字符本身
+				if next := strings.IndexByte(pattern[i+2:], ']'); next >= 0 {
+					end = next + 1
+				} else {
+					end = -1
+				}
+			}
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			class = strings.ReplaceAll(class, `\`, `\\`)
+			class = strings.ReplaceAll(class, "[", `\[`)
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// findGitRepo 向上查找包含 .git 的目录，返回工作区根目录与 git 目录
+func findGitRepo(start string) (string, string) {
+	dir := start
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if info.IsDir() {
+				return dir, gitPath
+			}
+			// worktree 或 submodule 中 .git 是一个指向真实目录的文件
+			if gitDir := readGitDirFile(gitPath); gitDir != "" {
+				return dir, gitDir
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+func readGitDirFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return ""
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return gitDir
+}
+
+// globalExcludesFile 获取 core.excludesFile，未配置时使用 git 默认位置
+func globalExcludesFile(gitDir string) string {
+	home, _ := os.UserHomeDir()
+
+	candidates := []string{filepath.Join(gitDir, "config")}
+	if home != "" {
+		candidates = append(candidates, filepath.Join(home, ".gitconfig"))
+	}
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" && home != "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	if xdgConfig != "" {
+		candidates = append(candidates, filepath.Join(xdgConfig, "git", "config"))
+	}
+
+	for _, candidate := range candidates {
+		if value := readGitConfigValue(candidate, "core", "excludesfile"); value != "" {
+			if strings.HasPrefix(value, "~/") && home != "" {
+				value = filepath.Join(home, value[2:])
+			}
+			return value
+		}
+	}
+
+	if xdgConfig != "" {
+		return filepath.Join(xdgConfig, "git", "ignore")
+	}
+	return ""
+}
+
+// readGitConfigValue 从 git 配置文件中读取简单的 section.key 值
+func readGitConfigValue(path, section, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	current := ""
+	value := ""
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if current != section {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.ToLower(strings.TrimSpace(k)) != key {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+
+	return value
+}
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"io/fs"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -9,61 +10,182 @@ import (
 )
 
 type IgnoreChecker struct {
-	patterns  []string
+	root     string
+	patterns []string
+	// defaults patterns 中前 defaults 个来自 default_ignore，其余来自 custom_ignore
+	defaults int
+	// paths custom_ignore.paths 中相对扫描根目录的路径，值为配置中的原始写法
+	paths     map[string]string
 	regexList []*regexp.Regexp
+	includes  []includeRule
+	gitignore *gitignoreMatcher
 	cfg       *config.Config
 }
 
-func NewIgnoreChecker(cfg *config.Config) *IgnoreChecker {
+func NewIgnoreChecker(root string, cfg *config.Config) *IgnoreChecker {
+	checker := newIgnoreChecker(root, cfg)
+	if cfg.RespectGitignore {
+		checker.gitignore = newGitignoreMatcher(root)
+	}
+	return checker
+}
+
+// NewIgnoreCheckerFS 为 fs.FS 创建检查器，路径为相对 fsys 根目录、以 / 分隔的路径；
+// 只读取 fsys 中的 .gitignore，不读取本机的 git 配置
+func NewIgnoreCheckerFS(fsys fs.FS, cfg *config.Config) *IgnoreChecker {
+	checker := newIgnoreChecker("", cfg)
+	if cfg.RespectGitignore {
+		checker.gitignore = newGitignoreMatcherFS(fsys)
+	}
+	return checker
+}
+
+func newIgnoreChecker(root string, cfg *config.Config) *IgnoreChecker {
 	checker := &IgnoreChecker{
+		root:      root,
 		patterns:  make([]string, 0),
 		regexList: make([]*regexp.Regexp, 0),
 		cfg:       cfg,
 	}
 
 	checker.patterns = append(checker.patterns, cfg.DefaultIgnore...)
+	checker.defaults = len(checker.patterns)
 	checker.patterns = append(checker.patterns, cfg.CustomIgnore.Patterns...)
 
+	for _, p := range cfg.CustomIgnore.Paths {
+		if clean := strings.Trim(filepath.ToSlash(p), "/"); clean != "" {
+			if checker.paths == nil {
+				checker.paths = make(map[string]string)
+			}
+			checker.paths[clean] = p
+		}
+	}
+
 	for _, regexStr := range cfg.CustomIgnore.Regex {
 		if re, err := regexp.Compile(regexStr); err == nil {
 			checker.regexList = append(checker.regexList, re)
 		}
 	}
 
+	for _, pattern := range cfg.Include.Patterns {
+		if rule, ok := parseIncludePattern(pattern); ok {
+			checker.includes = append(checker.includes, rule)
+		}
+	}
+
 	return checker
 }
 
+// ShouldIgnore 被忽略规则排除，或不在包含模式范围内
 func (ic *IgnoreChecker) ShouldIgnore(path string, isDir bool) bool {
+	return ic.Ignored(path, isDir) || !ic.Included(path, isDir)
+}
+
+// Ignored 是否被默认忽略、自定义排除或 .gitignore 排除，不考虑包含模式
+func (ic *IgnoreChecker) Ignored(path string, isDir bool) bool {
+	return ic.ignoredBy(path, isDir) != nil
+}
+
+// Explain 返回路径本身被排除的原因与命中的规则，未被排除时为 nil；不检查上级目录
+func (ic *IgnoreChecker) Explain(path string, isDir bool) *Exclusion {
+	if e := ic.ignoredBy(path, isDir); e != nil {
+		return e
+	}
+	if included, rule := ic.includedBy(path, isDir); !included {
+		return &Exclusion{RelPath: ic.relPath(path), IsDir: isDir, Reason: ExcludeNotIncluded, Rule: rule, Source: "include.patterns"}
+	}
+	return nil
+}
+
+// ignoredBy 按默认忽略、自定义排除、排除路径、正则、.gitignore 的顺序匹配，返回第一个命中的规则
+func (ic *IgnoreChecker) ignoredBy(path string, isDir bool) *Exclusion {
 	name := filepath.Base(path)
-	cleanPath := filepath.ToSlash(path)
+	cleanPath := ic.relPath(path)
 
-	for _, pattern := range ic.patterns {
-		if strings.Contains(pattern, "*") || strings.Contains(pattern, "?") {
-			matched, _ := filepath.Match(pattern, name)
-			if matched {
-				return true
-			}
-			
-			matched, _ = filepath.Match(pattern, cleanPath)
-			if matched {
-				return true
-			}
-		} else {
-			if name == pattern {
-				return true
-			}
-			
-			if strings.Contains(cleanPath, pattern) {
-				return true
+	for i, pattern := range ic.patterns {
+		if matchPattern(pattern, name, cleanPath) {
+			if i < ic.defaults {
+				return &Exclusion{RelPath: cleanPath, IsDir: isDir, Reason: ExcludeDefault, Rule: pattern, Source: "default_ignore"}
 			}
+			return &Exclusion{RelPath: cleanPath, IsDir: isDir, Reason: ExcludeCustom, Rule: pattern, Source: "custom_ignore.patterns"}
 		}
 	}
 
+	if rule, ok := ic.paths[cleanPath]; ok {
+		return &Exclusion{RelPath: cleanPath, IsDir: isDir, Reason: ExcludeCustom, Rule: rule, Source: "custom_ignore.paths"}
+	}
+
 	for _, re := range ic.regexList {
 		if re.MatchString(cleanPath) || re.MatchString(name) {
-			return true
+			return &Exclusion{RelPath: cleanPath, IsDir: isDir, Reason: ExcludeRegex, Rule: re.String(), Source: "custom_ignore.regex"}
+		}
+	}
+
+	if ic.gitignore != nil {
+		if ignored, rule, source := ic.gitignore.Match(cleanPath, isDir); ignored {
+			return &Exclusion{RelPath: cleanPath, IsDir: isDir, Reason: ExcludeGitignore, Rule: rule.pattern, Source: source}
 		}
 	}
 
+	return nil
+}
+
+// relPath 返回相对扫描根目录的路径，避免根目录自身的路径参与匹配
+func (ic *IgnoreChecker) relPath(path string) string {
+	if ic.root != "" {
+		if rel, err := filepath.Rel(ic.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// MatchAny 相对路径是否匹配任一模式，模式规则与忽略列表相同；
+// 与扫描时忽略目录一样，匹配上级目录也算匹配，例如 "cmd/*" 匹配 cmd/ptlm/main.go
+func MatchAny(patterns []string, relPath string) bool {
+	return matchAnyWith(patterns, relPath, matchPattern)
+}
+
+// MatchKeep skeleton_keep 的匹配：规则与 MatchAny 相同，另外以 / 开头的模式只匹配
+// 相对扫描根目录的完整路径，例如 "/main.go" 不匹配 cmd/main.go
+func MatchKeep(patterns []string, relPath string) bool {
+	return matchAnyWith(patterns, relPath, matchAnchored)
+}
+
+func matchAnyWith(patterns []string, relPath string, match func(pattern, name, relPath string) bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	segments := strings.Split(relPath, "/")
+	for i := range segments {
+		prefix := strings.Join(segments[:i+1], "/")
+		for _, pattern := range patterns {
+			if match(pattern, segments[i], prefix) {
+				return true
+			}
+		}
+	}
 	return false
-}
\ No newline at end of file
+}
+
+// matchPattern 含通配符的模式匹配文件名或相对路径，否则按文件名相等或路径包含匹配
+func matchPattern(pattern, name, relPath string) bool {
+	if strings.Contains(pattern, "*") || strings.Contains(pattern, "?") {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		matched, _ := filepath.Match(pattern, relPath)
+		return matched
+	}
+	return name == pattern || strings.Contains(relPath, pattern)
+}
+
+// matchAnchored 以 / 开头的模式只匹配完整的相对路径，其余模式同 matchPattern
+func matchAnchored(pattern, name, relPath string) bool {
+	if anchored, ok := strings.CutPrefix(pattern, "/"); ok && anchored != "" {
+		if strings.Contains(anchored, "*") || strings.Contains(anchored, "?") {
+			matched, _ := filepath.Match(anchored, relPath)
+			return matched
+		}
+		return relPath == strings.TrimSuffix(anchored, "/")
+	}
+	return matchPattern(pattern, name, relPath)
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"testing"
+
+	"printcode2llm/internal/config"
+)
+
+func TestCustomPatternsNotAnchored(t *testing.T) {
+	cfg := config.Default()
+	cfg.RespectGitignore = false
+	cfg.CustomIgnore.Patterns = []string{"/main.go"}
+	ic := NewIgnoreCheckerFS(nil, cfg)
+
+	// 排除模式不区分开头的 /，与以前一样按路径包含匹配
+	if !ic.Ignored("cmd/main.go", false) {
+		t.Error("cmd/main.go 应被 /main.go 排除")
+	}
+}
+
+func TestCustomPaths(t *testing.T) {
+	cfg := config.Default()
+	cfg.RespectGitignore = false
+	cfg.CustomIgnore.Paths = []string{"main.go", "docs/", "/web/widgets"}
+	ic := NewIgnoreCheckerFS(nil, cfg)
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"main.go", false, true},
+		{"cmd/main.go", false, false},
+		{"docs", true, true},
+		{"src/docs", true, false},
+		{"web/widgets", true, true},
+		{"widgets", true, false},
+	}
+	for _, c := range cases {
+		if got := ic.Ignored(c.path, c.isDir); got != c.ignored {
+			t.Errorf("Ignored(%s) = %v, want %v", c.path, got, c.ignored)
+		}
+	}
+
+	e := ic.Explain("docs", true)
+	if e == nil || e.Source != "custom_ignore.paths" || e.Rule != "docs/" || e.Reason != ExcludeCustom {
+		t.Errorf("Explain(docs) = %+v", e)
+	}
+}
+
+func TestMatchKeep(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		keep    bool
+		any     bool
+	}{
+		{"/main.go", "main.go", true, false},
+		{"/main.go", "cmd/main.go", false, true},
+		{"/cmd/", "cmd/ptlm/main.go", true, false},
+		{"/cmd/*.go", "cmd/root.go", true, false},
+		{"/cmd/*.go", "internal/cmd/root.go", false, false},
+		{"main.go", "cmd/main.go", true, true},
+		{"cmd/*", "cmd/ptlm/main.go", true, true},
+	}
+	for _, c := range cases {
+		if got := MatchKeep([]string{c.pattern}, c.path); got != c.keep {
+			t.Errorf("MatchKeep(%s, %s) = %v, want %v", c.pattern, c.path, got, c.keep)
+		}
+		if got := MatchAny([]string{c.pattern}, c.path); got != c.any {
+			t.Errorf("MatchAny(%s, %s) = %v, want %v", c.pattern, c.path, got, c.any)
+		}
+	}
+}
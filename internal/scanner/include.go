@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// includeRule 一条包含模式
+type includeRule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// segments 锚定模式按 / 拆分的各段，用于判断目录下是否可能有匹配的文件；未锚定时为 nil
+	segments []string
+}
+
+// parseIncludePattern 解析包含模式：含 / 的模式相对根目录，否则匹配任意层级的名称；
+// 以 / 结尾只匹配目录，以 ! 开头表示再次排除
+func parseIncludePattern(pattern string) (includeRule, bool) {
+	rule := includeRule{pattern: pattern}
+
+	p := strings.TrimSpace(pattern)
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	}
+	p = strings.TrimPrefix(p, "./")
+	anchored := strings.Contains(p, "/")
+	rule.dirOnly = strings.HasSuffix(p, "/")
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return includeRule{}, false
+	}
+
+	re, err := compileGitignorePattern(p, anchored)
+	if err != nil {
+		return includeRule{}, false
+	}
+	rule.re = re
+	if anchored {
+		rule.segments = strings.Split(p, "/")
+	}
+	return rule, true
+}
+
+// mayContain 目录下是否可能有匹配的路径
+func (r *includeRule) mayContain(dir string) bool {
+	if r.segments == nil {
+		return true
+	}
+	for i, name := range strings.Split(dir, "/") {
+		if i >= len(r.segments) {
+			return false
+		}
+		segment := r.segments[i]
+		if strings.Contains(segment, "**") {
+			return true
+		}
+		if matched, _ := path.Match(strings.ReplaceAll(segment, "[!", "[^"), name); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// HasIncludes 是否配置了包含模式
+func (ic *IgnoreChecker) HasIncludes() bool {
+	return len(ic.includes) > 0
+}
+
+// Included 判断路径是否在包含模式范围内，没有包含模式时总是 true；
+// 路径本身或任一上级目录匹配即包含，后出现的模式优先。
+// 目录本身未匹配时，只要其下可能有匹配的文件也返回 true，以便继续遍历
+func (ic *IgnoreChecker) Included(path string, isDir bool) bool {
+	included, _ := ic.includedBy(path, isDir)
+	return included
+}
+
+// includedBy 同 Included，同时返回最后命中的模式，没有任何模式命中时为空
+func (ic *IgnoreChecker) includedBy(path string, isDir bool) (bool, string) {
+	if len(ic.includes) == 0 {
+		return true, ""
+	}
+
+	rel := ic.relPath(path)
+	parts := strings.Split(rel, "/")
+	included := false
+	matched := ""
+	for i := range parts {
+		sub := strings.Join(parts[:i+1], "/")
+		subIsDir := isDir || i < len(parts)-1
+		for j := range ic.includes {
+			rule := &ic.includes[j]
+			if rule.dirOnly && !subIsDir {
+				continue
+			}
+			if rule.re.MatchString(sub) {
+				included = !rule.negate
+				matched = rule.pattern
+			}
+		}
+	}
+	if included || !isDir {
+		return included, matched
+	}
+
+	for j := range ic.includes {
+		if rule := &ic.includes[j]; !rule.negate && rule.mayContain(rel) {
+			return true, rule.pattern
+		}
+	}
+	return false, matched
+}
+
+// ValidateIncludeTree 检查 include.tree 的取值
+func ValidateIncludeTree(mode string) error {
+	switch mode {
+	case "", "prune", "collapse":
+		return nil
+	}
+	return fmt.Errorf("未知的目录树模式 %q，可选: prune/collapse", mode)
+}
@@ -0,0 +1,238 @@
+package scanner
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+)
+
+// 语言的识别方式，见 FileInfo.LanguageSource
+const (
+	LanguageByFilename  = "文件名"
+	LanguageByModeline  = "modeline"
+	LanguageByShebang   = "shebang"
+	LanguageByContent   = "内容特征"
+	LanguageByExtension = "扩展名"
+)
+
+// detectLanguage 识别文件的语言，依次检查：
+//
+//  1. filename_map 中的文件名或通配模式，例如 Dockerfile、Makefile、.bashrc
+//  2. 开头或结尾 5 行中的 Vim/Emacs modeline，例如 "vim: set ft=cpp:"、"-*- mode: python -*-"
+//  3. 扩展名没有对应语言时，第一行的 shebang，例如 "#!/usr/bin/env python3"
+//  4. 扩展名对应多种语言时按内容区分，例如 .h 中出现 class、namespace 时为 cpp
+//  5. language_map 中的扩展名
+//
+// 都未识别时为 text
+func detectLanguage(relPath, content string, cfg *config.Config) (string, string) {
+	if language := filenameLanguage(path.Base(relPath), cfg.FilenameMap); language != "" {
+		return language, LanguageByFilename
+	}
+	if language := modelineLanguage(content); language != "" {
+		return language, LanguageByModeline
+	}
+
+	ext := strings.ToLower(filepath.Ext(relPath))
+	language := cfg.LanguageMap[ext]
+	if language == "" || language == "text" {
+		if shebang := shebangLanguage(content); shebang != "" {
+			return shebang, LanguageByShebang
+		}
+	}
+	if language == "" {
+		return "text", LanguageByExtension
+	}
+	if refined := contentLanguage(ext, language, content); refined != "" {
+		return refined, LanguageByContent
+	}
+	return language, LanguageByExtension
+}
+
+// languageRulesKey 影响语言识别的配置（filename_map 与 language_map）的摘要，作为扫描缓存键的一部分
+func languageRulesKey(cfg *config.Config) string {
+	var parts []string
+	for _, rules := range []map[string]string{cfg.FilenameMap, cfg.LanguageMap} {
+		keys := make([]string, 0, len(rules))
+		for k := range rules {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			parts = append(parts, k+"="+rules[k])
+		}
+		parts = append(parts, "")
+	}
+	return cache.Key(parts...)
+}
+
+// filenameLanguage 按文件名查找，先精确匹配，再按模式的字典序尝试含通配符的模式
+func filenameLanguage(name string, rules map[string]string) string {
+	if language, ok := rules[name]; ok {
+		return language
+	}
+
+	var patterns []string
+	for pattern := range rules {
+		if strings.ContainsAny(pattern, "*?[") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return rules[pattern]
+		}
+	}
+	return ""
+}
+
+// modelineLines 查找 modeline 的行数，与 Vim 的默认值相同
+const modelineLines = 5
+
+var (
+	vimModelinePattern   = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):.*?\b(?:ft|filetype|syntax)=([\w+#.-]+)`)
+	emacsModelinePattern = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	emacsModePattern     = regexp.MustCompile(`(?i)(?:^|;)\s*mode:\s*([\w+#.-]+)`)
+)
+
+// modelineLanguage 开头或结尾几行中 modeline 指定的语言
+func modelineLanguage(content string) string {
+	head, tail := edgeLines(content, modelineLines)
+	for _, line := range append(head, tail...) {
+		if m := vimModelinePattern.FindStringSubmatch(line); m != nil {
+			// 复合类型 "cpp.doxygen" 取第一个
+			ft, _, _ := strings.Cut(m[1], ".")
+			return normalizeLanguage(ft)
+		}
+		if m := emacsModelinePattern.FindStringSubmatch(line); m != nil {
+			if mode := emacsModePattern.FindStringSubmatch(m[1]); mode != nil {
+				return normalizeLanguage(mode[1])
+			}
+			// "-*- python -*-" 形式只写模式名
+			if m[1] != "" && !strings.ContainsAny(m[1], ":; ") {
+				return normalizeLanguage(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// edgeLines 开头与结尾各 n 行，不足 2n 行时 tail 不与 head 重复
+func edgeLines(content string, n int) ([]string, []string) {
+	var head []string
+	rest := content
+	for len(head) < n && rest != "" {
+		line, after, _ := strings.Cut(rest, "\n")
+		head = append(head, line)
+		rest = after
+	}
+
+	var tail []string
+	rest = strings.TrimRight(rest, "\r\n")
+	for len(tail) < n && rest != "" {
+		i := strings.LastIndexByte(rest, '\n')
+		tail = append(tail, rest[i+1:])
+		if i < 0 {
+			break
+		}
+		rest = rest[:i]
+	}
+	return head, tail
+}
+
+// shebangVersionPattern 解释器名称末尾的版本号，例如 python3.11、perl5
+var shebangVersionPattern = regexp.MustCompile(`[\d.]+$`)
+
+// shebangLanguages 解释器名称（去掉版本号）对应的语言
+var shebangLanguages = map[string]string{
+	"sh": "shell", "bash": "shell", "zsh": "shell", "ksh": "shell", "dash": "shell", "ash": "shell",
+	"python": "python", "pypy": "python",
+	"node": "javascript", "nodejs": "javascript", "bun": "javascript",
+	"deno": "typescript", "ts-node": "typescript", "tsx": "typescript",
+	"ruby": "ruby", "perl": "perl", "php": "php", "lua": "lua", "luajit": "lua",
+	"rscript": "r", "pwsh": "powershell", "powershell": "powershell",
+	"elixir": "elixir", "escript": "erlang", "runhaskell": "haskell", "runghc": "haskell",
+	"groovy": "groovy", "kotlin": "kotlin", "scala": "scala", "swift": "swift",
+	"make": "makefile",
+}
+
+// shebangLanguage 第一行 "#!" 指定的解释器对应的语言，支持 /usr/bin/env 及其参数
+func shebangLanguage(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			// 跳过 env 的选项与环境变量赋值，例如 "env -S VAR=1 python3 -u"
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = path.Base(field)
+			break
+		}
+	}
+
+	name := strings.ToLower(interpreter)
+	if language, ok := shebangLanguages[name]; ok {
+		return language
+	}
+	return shebangLanguages[shebangVersionPattern.ReplaceAllString(name, "")]
+}
+
+// contentHint 按内容区分同一扩展名的语言
+type contentHint struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// contentHints 扩展名对应的候选语言，按顺序取第一个匹配的
+var contentHints = map[string][]contentHint{
+	".h": {
+		{"objc", regexp.MustCompile(`(?m)^\s*(?:@interface|@implementation|@protocol|#import)\b`)},
+		{"cpp", regexp.MustCompile(`(?m)^\s*(?:class\s+\w+\s*[:{]|namespace\s+\w*\s*\{|template\s*<|(?:public|private|protected)\s*:)|\bstd::|#include\s*<(?:iostream|string|vector|map|memory|algorithm)>`)},
+	},
+}
+
+// contentLanguage 按内容特征修正扩展名对应的语言，无法判断时为空
+func contentLanguage(ext, language, content string) string {
+	for _, hint := range contentHints[ext] {
+		if hint.language != language && hint.pattern.MatchString(content) {
+			return hint.language
+		}
+	}
+	return ""
+}
+
+// languageAliases modeline 中常见的名称与 language_map 中名称的对应
+var languageAliases = map[string]string{
+	"sh": "shell", "bash": "shell", "zsh": "shell", "shell-script": "shell",
+	"py": "python", "python3": "python",
+	"js": "javascript", "ts": "typescript",
+	"c++": "cpp", "objective-c": "objc", "objcpp": "objc",
+	"rb": "ruby", "cperl": "perl", "rs": "rust", "cs": "csharp",
+	"make": "makefile", "gnumakefile": "makefile",
+	"yml": "yaml", "md": "markdown", "ps1": "powershell",
+}
+
+// normalizeLanguage 统一为小写并替换常见别名
+func normalizeLanguage(name string) string {
+	name = strings.ToLower(name)
+	name = strings.TrimSuffix(name, "-mode")
+	if alias, ok := languageAliases[name]; ok {
+		return alias
+	}
+	return name
+}
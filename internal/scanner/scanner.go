@@ -2,14 +2,20 @@ package scanner
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 	"unicode/utf8"
 
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/charset"
 	"printcode2llm/internal/config"
+	"printcode2llm/internal/parallel"
 )
 
 type FileInfo struct {
@@ -23,26 +29,96 @@ type FileInfo struct {
 	LineCount  int
 	Size       int64
 	Encoding   string
+
+	// LanguageSource Language 的识别方式，见 LanguageByFilename 等常量
+	LanguageSource string
+
+	// ChangeStatus 变更模式下的状态 (A/M/D/R)，Diff 为对应的 unified diff
+	ChangeStatus string
+	Diff         string
+
+	// LineMap 遮盖敏感信息合并了多行时非空：LineMap[i] 为 Content 第 i+1 行在原文中的行号，
+	// 最后多出的一项为原文最后一行之后的行号，用于取得每行在原文中的结束行
+	LineMap []int
+}
+
+// SkippedFile 通过忽略规则、但因为过大或无法读取而没有读取内容的文件
+type SkippedFile struct {
+	// RelPath 相对扫描目录、以 / 分隔
+	RelPath string
+	Size    int64
+	Reason  string
+}
+
+// 跳过原因
+const (
+	SkipTooLarge   = "超过大小限制"
+	SkipUnreadable = "无法读取"
+)
+
+// Options 扫描选项
+type Options struct {
+	// Filter 非空时只保留返回 true 的文件，参数为相对扫描目录的路径
+	Filter func(relPath string) bool
+	// Cache 非空时按内容哈希缓存编码、二进制与语言检测的结果，命中时不再解码与检测
+	Cache *cache.Cache
+	// Context 非空时取消后尽快停止扫描并返回 ctx.Err()
+	Context context.Context
+	// Progress 非空时每读取完一个文件回调一次，total 为通过忽略规则的文件数
+	Progress func(done, total int)
+	// OnSkip 非空时按路径顺序报告跳过的文件（超过 max_file_size 或无法读取），二进制文件不报告
+	OnSkip func(SkippedFile)
+	// OnExclude 非空时按路径顺序报告所有被排除的路径及原因，被 Filter 排除的不报告
+	OnExclude func(Exclusion)
 }
 
 // ScanDirectory 扫描目录
 func ScanDirectory(dir string, cfg *config.Config) ([]*FileInfo, error) {
+	return ScanDirectoryWithOptions(dir, cfg, Options{})
+}
+
+// ScanDirectoryWithOptions 按选项扫描目录
+//
+// 目录遍历与忽略规则按顺序执行，读取文件与内容检测交给 cfg.Jobs 个 worker 并行处理
+func ScanDirectoryWithOptions(dir string, cfg *config.Config, opts Options) ([]*FileInfo, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
 	}
 
-	var files []*FileInfo
-	ignoreChecker := NewIgnoreChecker(cfg)
+	return scanFS(os.DirFS(absDir), absDir, NewIgnoreChecker(absDir, cfg), cfg, opts)
+}
 
-	err = filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			// 记录错误但继续处理
-			return nil
-		}
+// ScanFS 扫描 fs.FS 中的文件，例如压缩包或某个 git 版本的内容
+//
+// root 只用于生成 FileInfo.Path（root 与相对路径拼接），忽略规则只读取 fsys 中的 .gitignore
+func ScanFS(fsys fs.FS, root string, cfg *config.Config, opts Options) ([]*FileInfo, error) {
+	return scanFS(fsys, root, NewIgnoreCheckerFS(fsys, cfg), cfg, opts)
+}
+
+func scanFS(fsys fs.FS, root string, ignoreChecker *IgnoreChecker, cfg *config.Config, opts Options) ([]*FileInfo, error) {
+	encodings, err := compileEncodingRules(cfg.SourceEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []candidate
+	var excluded []Exclusion
 
-		relPath, err := filepath.Rel(absDir, path)
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err = fs.WalkDir(fsys, ".", func(relPath string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
+			// 记录错误但继续处理
+			if relPath != "." && opts.OnExclude != nil {
+				excluded = append(excluded, *unreadable(relPath, d != nil && d.IsDir(), err))
+			}
 			return nil
 		}
 
@@ -51,82 +127,98 @@ func ScanDirectory(dir string, cfg *config.Config) ([]*FileInfo, error) {
 		}
 
 		// 检查是否应该忽略
-		if ignoreChecker.ShouldIgnore(path, info.IsDir()) {
-			if info.IsDir() {
-				return filepath.SkipDir
+		if ignoreChecker.ShouldIgnore(relPath, d.IsDir()) {
+			if opts.OnExclude != nil {
+				if e := ignoreChecker.Explain(relPath, d.IsDir()); e != nil {
+					excluded = append(excluded, *e)
+				}
+			}
+			if d.IsDir() {
+				return fs.SkipDir
 			}
 			return nil
 		}
 
 		// 只处理文件
-		if info.IsDir() {
+		if d.IsDir() {
 			return nil
 		}
 
-		// 检查文件大小（跳过过大的文件）
-		if info.Size() > 10*1024*1024 { // 10MB
+		if opts.Filter != nil && !opts.Filter(relPath) {
 			return nil
 		}
 
-		// 检查是否是二进制文件（通过扩展名）
-		if isBinaryExtension(path, cfg) {
-			return nil
-		}
-
-		// 读取文件内容
-		content, err := os.ReadFile(path)
+		info, err := d.Info()
 		if err != nil {
-			// 无法读取的文件跳过
+			if opts.OnExclude != nil {
+				excluded = append(excluded, *unreadable(relPath, false, err))
+			}
 			return nil
 		}
 
-		// 检测是否是二进制文件（通过内容）
-		if isBinaryContent(content) {
+		c := candidate{
+			path:     filepath.Join(root, filepath.FromSlash(relPath)),
+			relPath:  relPath,
+			size:     info.Size(),
+			encoding: encodings.lookup(relPath),
+		}
+		// 二进制扩展名与过大的文件不读取，过大的留待报告
+		if c.skip = fileExclusion(relPath, c.size, cfg); c.skip != nil && c.skip.Reason == ExcludeBinaryExt {
+			if opts.OnExclude != nil {
+				excluded = append(excluded, *c.skip)
+			}
 			return nil
 		}
+		candidates = append(candidates, c)
+		return nil
+	})
 
-		// 转换为字符串
-		contentStr := string(content)
-
-		// 检测编码
-		encoding := detectEncoding(content)
-
-		// 检测换行符
-		hasNewline := detectNewline(contentStr)
+	if err != nil {
+		return nil, fmt.Errorf("扫描目录失败: %w", err)
+	}
 
-		// 获取语言类型
-		ext := strings.ToLower(filepath.Ext(path))
-		language := cfg.LanguageMap[ext]
-		if language == "" {
-			language = "text"
+	rules := ""
+	if opts.Cache != nil {
+		rules = languageRulesKey(cfg)
+	}
+	loaded := make([]*FileInfo, len(candidates))
+	skipped := make([]*Exclusion, len(candidates))
+	progress := parallel.NewCounter(len(candidates), opts.Progress)
+	err = parallel.ForEachContext(ctx, len(candidates), cfg.Jobs, func(i int) {
+		if candidates[i].skip != nil {
+			skipped[i] = candidates[i].skip
+		} else {
+			loaded[i], skipped[i] = loadFile(fsys, candidates[i], cfg, opts.Cache, rules)
 		}
-
-		// 判断是否是代码文件
-		isCode := !isNonCodeFile(ext, cfg)
-
-		// 统计行数
-		lineCount := countLines(contentStr)
-
-		files = append(files, &FileInfo{
-			Path:       path,
-			RelPath:    filepath.ToSlash(relPath),
-			Language:   language,
-			Content:    contentStr,
-			IsCode:     isCode,
-			IsBinary:   false,
-			HasNewline: hasNewline,
-			LineCount:  lineCount,
-			Size:       info.Size(),
-			Encoding:   encoding,
-		})
-
-		return nil
+		progress.Done()
 	})
-
 	if err != nil {
 		return nil, fmt.Errorf("扫描目录失败: %w", err)
 	}
 
+	files := make([]*FileInfo, 0, len(loaded))
+	for i, file := range loaded {
+		if file != nil {
+			files = append(files, file)
+			continue
+		}
+		if s, ok := skipped[i].skippedFile(candidates[i].size); ok && opts.OnSkip != nil {
+			opts.OnSkip(s)
+		}
+		if opts.OnExclude != nil {
+			excluded = append(excluded, *skipped[i])
+		}
+	}
+
+	if opts.OnExclude != nil {
+		sort.SliceStable(excluded, func(i, j int) bool {
+			return excluded[i].RelPath < excluded[j].RelPath
+		})
+		for _, e := range excluded {
+			opts.OnExclude(e)
+		}
+	}
+
 	// 排序：目录优先，然后按名称
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].RelPath < files[j].RelPath
@@ -135,6 +227,135 @@ func ScanDirectory(dir string, cfg *config.Config) ([]*FileInfo, error) {
 	return files, nil
 }
 
+// candidate 通过忽略规则、等待读取的文件
+type candidate struct {
+	// path 显示用的路径，relPath 为相对 fsys 根目录的路径
+	path    string
+	relPath string
+	size    int64
+	// encoding source_encoding 指定的编码，为空时自动检测
+	encoding string
+	// skip 非空时不读取，为跳过的原因
+	skip *Exclusion
+}
+
+// scanFormat 扫描缓存条目的格式版本，检测逻辑或条目结构变化时递增
+const scanFormat = "3"
+
+// detection 文件内容检测的结果，用于缓存
+type detection struct {
+	Binary    bool   `json:"binary"`
+	BinaryWhy string `json:"binary_why,omitempty"`
+	// Encoding 原始编码
+	Encoding   string `json:"encoding,omitempty"`
+	HasNewline bool   `json:"has_newline,omitempty"`
+	LineCount  int    `json:"line_count,omitempty"`
+	// Language 与 LanguageSource 为语言识别的结果
+	Language       string `json:"language,omitempty"`
+	LanguageSource string `json:"language_source,omitempty"`
+	// Decoded 为 true 时 Content 是转换为 UTF-8 后的内容；为 false 时原始字节就是输出的内容，不重复保存
+	Decoded bool   `json:"decoded,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// loadFile 读取文件并检测类型；是二进制内容或无法读取时返回 nil 与原因
+//
+// fc 非空时检测结果按内容哈希缓存，rules 为 languageRulesKey 的结果；命中时跳过解码、
+// 二进制检测与语言识别，同样的内容换了位置、修改时间或来自压缩包与 git 版本时都能复用
+func loadFile(fsys fs.FS, c candidate, cfg *config.Config, fc *cache.Cache, rules string) (*FileInfo, *Exclusion) {
+	// 读取文件内容
+	content, err := fs.ReadFile(fsys, c.relPath)
+	if err != nil {
+		return nil, unreadable(c.relPath, false, err)
+	}
+
+	// 语言识别只用到文件名，与所在目录无关
+	key := ""
+	var detected detection
+	if fc != nil {
+		key = cache.Key("scan", scanFormat, string(content), path.Base(c.relPath), c.encoding, rules)
+	}
+	if !fc.Get(key, &detected) {
+		detected = detectFile(c.relPath, content, c.encoding, cfg)
+		fc.Put(key, detected)
+	}
+
+	// 检测是否是二进制文件（通过内容）
+	if detected.Binary {
+		return nil, binaryExclusion(c.relPath, detected)
+	}
+	contentStr := detected.Content
+	if !detected.Decoded {
+		contentStr = string(content)
+	}
+
+	// 判断是否是代码文件
+	ext := strings.ToLower(filepath.Ext(c.path))
+	isCode := !isNonCodeFile(ext, cfg)
+
+	return &FileInfo{
+		Path:           c.path,
+		RelPath:        c.relPath,
+		Language:       detected.Language,
+		LanguageSource: detected.LanguageSource,
+		Content:        contentStr,
+		IsCode:         isCode,
+		IsBinary:       false,
+		HasNewline:     detected.HasNewline,
+		LineCount:      detected.LineCount,
+		Size:           c.size,
+		Encoding:       detected.Encoding,
+	}, nil
+}
+
+// binaryExclusion 按内容判定为二进制的排除项，旧缓存中没有具体原因
+func binaryExclusion(relPath string, detected detection) *Exclusion {
+	return &Exclusion{RelPath: relPath, Reason: ExcludeBinaryContent, Rule: detected.BinaryWhy}
+}
+
+// detectFile 检测内容并识别语言；转换后的内容与原始字节不同时才放入结果的 Content
+func detectFile(relPath string, content []byte, encoding string, cfg *config.Config) detection {
+	detected, contentStr := detect(content, encoding)
+	if detected.Binary {
+		return detected
+	}
+	detected.Language, detected.LanguageSource = detectLanguage(relPath, contentStr, cfg)
+	if contentStr != string(content) {
+		detected.Decoded, detected.Content = true, contentStr
+	}
+	return detected
+}
+
+// detect 检测编码、二进制、换行与行数，同时返回转换为 UTF-8 的内容；
+// encoding 为 source_encoding 指定的编码，为空时自动检测
+func detect(content []byte, encoding string) (detection, string) {
+	// 检测编码
+	if encoding == "" {
+		var ok bool
+		if encoding, ok = charset.Detect(content); !ok {
+			why := "不是有效的 UTF-8，也不像 GBK/Big5/Shift_JIS/Latin-1 编码"
+			if bytes.IndexByte(content[:min(len(content), binarySampleSize)], 0) != -1 {
+				why = "包含 NUL 字节"
+			}
+			return detection{Binary: true, BinaryWhy: why}, ""
+		}
+	}
+	contentStr := charset.Decode(content, encoding)
+
+	// 检测是否是二进制文件（通过转换后的内容）
+	if why := binaryReason([]byte(contentStr)); why != "" {
+		return detection{Binary: true, BinaryWhy: why}, ""
+	}
+
+	return detection{
+		Encoding: encoding,
+		// 检测换行符
+		HasNewline: detectNewline(contentStr),
+		// 统计行数
+		LineCount: CountLines(contentStr),
+	}, contentStr
+}
+
 // isBinaryExtension 检查是否是二进制文件扩展名
 func isBinaryExtension(path string, cfg *config.Config) bool {
 	ext := strings.ToLower(filepath.Ext(path))
@@ -146,29 +367,35 @@ func isBinaryExtension(path string, cfg *config.Config) bool {
 	return false
 }
 
-// isBinaryContent 检测文件内容是否是二进制
-func isBinaryContent(content []byte) bool {
+// binarySampleSize 内容检测检查的字节数
+const binarySampleSize = 8192
+
+// binaryReason 检测文件内容是否是二进制，返回判定的原因，是文本时为空
+func binaryReason(content []byte) string {
 	// 空文件不是二进制
 	if len(content) == 0 {
-		return false
+		return ""
 	}
 
-	// 检查前8KB内容
-	sampleSize := 8192
+	// 检查前8KB内容，不截断末尾的字符
+	sampleSize := binarySampleSize
 	if len(content) < sampleSize {
 		sampleSize = len(content)
 	}
+	for sampleSize < len(content) && sampleSize > 0 && !utf8.RuneStart(content[sampleSize]) {
+		sampleSize--
+	}
 
 	sample := content[:sampleSize]
 
 	// 检查是否包含 NULL 字节
 	if bytes.IndexByte(sample, 0) != -1 {
-		return true
+		return "包含 NUL 字节"
 	}
 
 	// 检查是否是有效的 UTF-8
 	if !utf8.Valid(sample) {
-		return true
+		return "不是有效的 UTF-8"
 	}
 
 	// 计算非打印字符的比例
@@ -186,39 +413,10 @@ func isBinaryContent(content []byte) bool {
 
 	// 如果超过 30% 是非打印字符，认为是二进制
 	if float64(nonPrintable)/float64(len(sample)) > 0.3 {
-		return true
-	}
-
-	return false
-}
-
-// detectEncoding 检测文件编码
-func detectEncoding(content []byte) string {
-	// 检查 BOM
-	if len(content) >= 3 {
-		// UTF-8 BOM
-		if content[0] == 0xEF && content[1] == 0xBB && content[2] == 0xBF {
-			return "UTF-8 with BOM"
-		}
-	}
-
-	if len(content) >= 2 {
-		// UTF-16 LE BOM
-		if content[0] == 0xFF && content[1] == 0xFE {
-			return "UTF-16 LE"
-		}
-		// UTF-16 BE BOM
-		if content[0] == 0xFE && content[1] == 0xFF {
-			return "UTF-16 BE"
-		}
-	}
-
-	// 检查是否是有效的 UTF-8
-	if utf8.Valid(content) {
-		return "UTF-8"
+		return "控制字符超过 30%"
 	}
 
-	return "Unknown"
+	return ""
 }
 
 // detectNewline 检测换行符类型
@@ -255,8 +453,8 @@ func isNonCodeFile(ext string, cfg *config.Config) bool {
 	return false
 }
 
-// countLines 统计行数
-func countLines(content string) int {
+// CountLines 统计行数，末尾的换行不计入额外的一行
+func CountLines(content string) int {
 	if content == "" {
 		return 0
 	}
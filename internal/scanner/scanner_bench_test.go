@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/config"
+)
+
+// writeBenchTree 生成 dirs*filesPerDir 个 Go 文件，每个约 lines 行，含注释与空行
+func writeBenchTree(tb testing.TB, dir string, dirs, filesPerDir, lines int) {
+	tb.Helper()
+	for d := 0; d < dirs; d++ {
+		pkg := fmt.Sprintf("pkg%02d", d)
+		if err := os.MkdirAll(filepath.Join(dir, pkg), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		for f := 0; f < filesPerDir; f++ {
+			var b strings.Builder
+			fmt.Fprintf(&b, "// Package %s is generated for benchmarks.\npackage %s\n\nimport \"fmt\"\n\n", pkg, pkg)
+			for i := 0; b.Len() < lines*32; i++ {
+				fmt.Fprintf(&b, "// F%d_%d prints its argument.\n// It has a doc comment.\nfunc F%d_%d(x int) int {\n\t// body comment\n\tif x > %d {\n\t\tfmt.Println(\"value\", x)\n\t}\n\n\treturn x * %d\n}\n\n", f, i, f, i, i, i)
+			}
+			name := filepath.Join(dir, pkg, fmt.Sprintf("file%03d.go", f))
+			if err := os.WriteFile(name, []byte(b.String()), 0o644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchJobs 串行与使用全部核心两种并发数，单核机器上额外测 4 个 worker
+func benchJobs() []int {
+	n := runtime.NumCPU()
+	if n == 1 {
+		n = 4
+	}
+	return []int{1, n}
+}
+
+func BenchmarkScanDirectory(b *testing.B) {
+	dir := b.TempDir()
+	writeBenchTree(b, dir, 20, 20, 200)
+
+	for _, jobs := range benchJobs() {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			cfg := config.Default()
+			cfg.Jobs = jobs
+			for i := 0; i < b.N; i++ {
+				files, err := ScanDirectoryWithOptions(dir, cfg, Options{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(files) != 400 {
+					b.Fatalf("scanned %d files, want 400", len(files))
+				}
+			}
+		})
+	}
+}
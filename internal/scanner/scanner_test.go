@@ -0,0 +1,202 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+)
+
+// cacheTestFiles 包含 UTF-8、带 BOM、GBK、按内容识别语言与二进制内容的文件
+var cacheTestFiles = map[string][]byte{
+	"main.go":       []byte("package main\n\nfunc main() {}\n"),
+	"bom.txt":       []byte("\xEF\xBB\xBFhello\n"),
+	"gbk.txt":       {0xC4, 0xE3, 0xBA, 0xC3, '\n'}, // “你好” 的 GBK 编码
+	"include/foo.h": []byte("namespace foo {\nclass Bar {};\n}\n"),
+	"run":           []byte("#!/usr/bin/env python3\nprint('hi')\n"),
+	"blob.dat2":     {0x00, 0x01, 0x02, 0x03},
+}
+
+func writeTree(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func openCache(t *testing.T, dir string) *cache.Cache {
+	t.Helper()
+	fc, err := cache.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fc
+}
+
+// withoutPath 去掉随目录变化的 Path，便于比较两次扫描的结果
+func withoutPath(files []*FileInfo) []FileInfo {
+	result := make([]FileInfo, len(files))
+	for i, f := range files {
+		result[i] = *f
+		result[i].Path = ""
+	}
+	return result
+}
+
+func TestScanCacheByContent(t *testing.T) {
+	cacheDir := t.TempDir()
+	cfg := config.Default()
+
+	dir := t.TempDir()
+	writeTree(t, dir, cacheTestFiles)
+	uncached, err := ScanDirectory(dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uncached) != len(cacheTestFiles)-1 {
+		t.Fatalf("扫描到 %d 个文件，应排除二进制内容的文件", len(uncached))
+	}
+
+	fc := openCache(t, cacheDir)
+	first, err := ScanDirectoryWithOptions(dir, cfg, Options{Cache: fc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Hits() != 0 || fc.Misses() != len(cacheTestFiles) {
+		t.Fatalf("首次扫描 hits=%d misses=%d", fc.Hits(), fc.Misses())
+	}
+	if !reflect.DeepEqual(withoutPath(first), withoutPath(uncached)) {
+		t.Fatalf("使用缓存的结果不同:\n%+v\n%+v", first, uncached)
+	}
+
+	// 内容相同、位置与修改时间都不同的副本全部命中，结果与首次扫描相同
+	moved := t.TempDir()
+	writeTree(t, moved, cacheTestFiles)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(moved, "main.go"), later, later)
+
+	fc = openCache(t, cacheDir)
+	second, err := ScanDirectoryWithOptions(moved, cfg, Options{Cache: fc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Hits() != len(cacheTestFiles) || fc.Misses() != 0 {
+		t.Fatalf("第二次扫描 hits=%d misses=%d", fc.Hits(), fc.Misses())
+	}
+	if !reflect.DeepEqual(withoutPath(second), withoutPath(first)) {
+		t.Fatalf("命中缓存的结果不同:\n%+v\n%+v", second, first)
+	}
+	for _, f := range second {
+		switch f.RelPath {
+		case "gbk.txt":
+			if f.Content != "你好\n" || f.Encoding != "GBK" {
+				t.Errorf("gbk.txt: %q %s", f.Content, f.Encoding)
+			}
+		case "bom.txt":
+			if f.Content != "hello\n" {
+				t.Errorf("bom.txt: %q", f.Content)
+			}
+		case "include/foo.h":
+			if f.Language != "cpp" || f.LanguageSource != LanguageByContent {
+				t.Errorf("foo.h: %s (%s)", f.Language, f.LanguageSource)
+			}
+		case "run":
+			if f.Language != "python" {
+				t.Errorf("run: %s", f.Language)
+			}
+		}
+	}
+
+	// 修改内容后不命中
+	writeTree(t, moved, map[string][]byte{"main.go": []byte("package main\n\nfunc main() { println() }\n")})
+	fc = openCache(t, cacheDir)
+	if _, err := ScanDirectoryWithOptions(moved, cfg, Options{Cache: fc}); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Misses() != 1 {
+		t.Errorf("修改一个文件后 misses=%d, want 1", fc.Misses())
+	}
+}
+
+func TestScanCacheLanguageRules(t *testing.T) {
+	cacheDir := t.TempDir()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string][]byte{"query.sql": []byte("select 1;\n")})
+
+	cfg := config.Default()
+	if _, err := ScanDirectoryWithOptions(dir, cfg, Options{Cache: openCache(t, cacheDir)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// language_map 变化后不使用旧的识别结果
+	cfg = config.Default()
+	cfg.LanguageMap[".sql"] = "plsql"
+	fc := openCache(t, cacheDir)
+	files, err := ScanDirectoryWithOptions(dir, cfg, Options{Cache: fc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Hits() != 0 || len(files) != 1 || files[0].Language != "plsql" {
+		t.Errorf("hits=%d files=%+v", fc.Hits(), files)
+	}
+}
+
+func TestScanFSUsesCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	cfg := config.Default()
+
+	dir := t.TempDir()
+	writeTree(t, dir, cacheTestFiles)
+	if _, err := ScanDirectoryWithOptions(dir, cfg, Options{Cache: openCache(t, cacheDir)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 压缩包或 git 版本中相同内容的文件同样命中
+	fsys := fstest.MapFS{}
+	for name, data := range cacheTestFiles {
+		fsys[name] = &fstest.MapFile{Data: data, Mode: 0o644}
+	}
+	fc := openCache(t, cacheDir)
+	files, err := ScanFS(fsys, "/archive", cfg, Options{Cache: fc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Hits() != len(cacheTestFiles) || len(files) != len(cacheTestFiles)-1 {
+		t.Errorf("hits=%d files=%d", fc.Hits(), len(files))
+	}
+}
+
+func TestDetectBinaryAndLegacy(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		why      string
+	}{
+		{"PNG 文件头", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00"), "", "包含 NUL 字节"},
+		{"ELF 文件头", []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00>\x00"), "", "包含 NUL 字节"},
+		{"控制字符", []byte("\x01\x02\x03\x04abc"), "", "控制字符超过 30%"},
+		{"无法识别的编码", []byte("\x93quoted\x94 \x01\xFF"), "", "不是有效的 UTF-8，也不像 GBK/Big5/Shift_JIS/Latin-1 编码"},
+		{"GBK", []byte("// \xD6\xD0\xCE\xC4\n"), "GBK", ""},
+		{"UTF-16 BOM", []byte("\xFF\xFEa\x00\n\x00"), "UTF-16 LE", ""},
+		{"Latin-1", []byte("# caf\xE9\n"), "ISO-8859-1", ""},
+	}
+
+	for _, tt := range tests {
+		detected, _ := detect(tt.data, "")
+		if detected.Binary != (tt.why != "") || detected.BinaryWhy != tt.why || detected.Encoding != tt.encoding {
+			t.Errorf("%s: %+v, want encoding=%q why=%q", tt.name, detected, tt.encoding, tt.why)
+		}
+	}
+}
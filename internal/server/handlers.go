@@ -0,0 +1,451 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/output"
+	"printcode2llm/internal/redact"
+	"printcode2llm/internal/scanner"
+	"printcode2llm/internal/source"
+)
+
+// maxBodySize 请求体上限
+const maxBodySize = 1 << 20
+
+// Request 请求参数，与命令行参数对应，未设置的字段使用配置中的值
+type Request struct {
+	// Dir 项目目录，相对路径基于第一个允许的目录，为空时即第一个允许的目录
+	Dir string `json:"dir"`
+	// Rev 非空时读取该 git 版本的内容
+	Rev string `json:"rev"`
+
+	Format    string   `json:"format"`
+	MaxChars  int      `json:"max_chars"`
+	MaxTokens int      `json:"max_tokens"`
+	Tokenizer string   `json:"tokenizer"`
+	Compress  *bool    `json:"compress"`
+	Ultra     bool     `json:"ultra_compress"`
+	Skeleton  *bool    `json:"skeleton"`
+	KeepFull  []string `json:"keep_full"`
+	SplitMode string   `json:"split_mode"`
+	Tree      *bool    `json:"tree"`
+	Exclude   []string `json:"exclude"`
+	Regex     []string `json:"regex"`
+	Only      []string `json:"only"`
+	Redact    *bool    `json:"redact"`
+	Part      int      `json:"part"`
+
+	// As 生成结果的返回方式: json（默认）返回分段列表；markdown 返回拼接后的文档，
+	// format 为 xml 等时为对应格式
+	As string `json:"as"`
+}
+
+// FileEntry 文件列表中的一项
+type FileEntry struct {
+	Path       string `json:"path"`
+	Language   string `json:"language"`
+	IsCode     bool   `json:"is_code"`
+	Lines      int    `json:"lines"`
+	Size       int64  `json:"size"`
+	Encoding   string `json:"encoding,omitempty"`
+	Redactions int    `json:"redactions,omitempty"`
+}
+
+// FilesResponse /api/files 的返回
+type FilesResponse struct {
+	Project string      `json:"project"`
+	Path    string      `json:"path"`
+	Files   []FileEntry `json:"files"`
+	Lines   int         `json:"lines"`
+	Size    int64       `json:"size"`
+}
+
+// SegmentEntry 一个分段
+type SegmentEntry struct {
+	Part      int    `json:"part"`
+	Total     int    `json:"total"`
+	Content   string `json:"content"`
+	Chars     int    `json:"chars"`
+	Tokens    int    `json:"tokens"`
+	FileRange string `json:"file_range,omitempty"`
+}
+
+// GenerateResponse /api/generate 以 JSON 返回时的内容
+type GenerateResponse struct {
+	Project     string         `json:"project"`
+	Path        string         `json:"path"`
+	Files       int            `json:"files"`
+	CodeFiles   int            `json:"code_files"`
+	ConfigFiles int            `json:"config_files"`
+	Lines       int            `json:"lines"`
+	Chars       int            `json:"chars"`
+	Tokens      int            `json:"tokens"`
+	Tokenizer   string         `json:"tokenizer"`
+	Redactions  int            `json:"redactions,omitempty"`
+	Warnings    []string       `json:"warnings,omitempty"`
+	Segments    []SegmentEntry `json:"segments"`
+	// TokensEstimated tokens 是没有词表时的估算值
+	TokensEstimated bool `json:"tokens_estimated,omitempty"`
+}
+
+// scanned 一次扫描的结果
+type scanned struct {
+	cfg        *config.Config
+	path       string
+	fsys       fs.FS
+	files      []*scanner.FileInfo
+	redactions map[string]int
+	total      int
+	skipped    []scanner.SkippedFile
+}
+
+// httpError 带状态码的错误
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(err error) error {
+	return &httpError{status: http.StatusBadRequest, err: err}
+}
+
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	res, err := s.scan(r, req)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	resp := FilesResponse{
+		Project: filepath.Base(res.path),
+		Path:    res.path,
+		Files:   make([]FileEntry, 0, len(res.files)),
+	}
+	for _, file := range res.files {
+		resp.Files = append(resp.Files, FileEntry{
+			Path:       file.RelPath,
+			Language:   file.Language,
+			IsCode:     file.IsCode,
+			Lines:      file.LineCount,
+			Size:       file.Size,
+			Encoding:   file.Encoding,
+			Redactions: res.redactions[file.RelPath],
+		})
+		resp.Lines += file.LineCount
+		resp.Size += file.Size
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if req.As != "" && req.As != "json" && req.As != "markdown" {
+		s.fail(w, badRequest(fmt.Errorf("无效的返回方式: %s (可选: json/markdown)", req.As)))
+		return
+	}
+	res, err := s.scan(r, req)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if res.cfg.Redact.FailOnSecrets && res.total > 0 {
+		s.fail(w, &httpError{
+			status: http.StatusUnprocessableEntity,
+			err:    fmt.Errorf("发现 %d 处疑似敏感信息，未生成文档", res.total),
+		})
+		return
+	}
+
+	result, err := generator.GenerateWithOptions(res.path, res.files, res.cfg, generator.Options{
+		Cache:   s.cacheFor(res.cfg),
+		Context: r.Context(),
+		FS:      res.fsys,
+		Skipped: res.skipped,
+	})
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if req.Part > len(result.Segments) {
+		s.fail(w, badRequest(fmt.Errorf("第 %d 部分不存在，共 %d 部分", req.Part, len(result.Segments))))
+		return
+	}
+
+	if req.As == "markdown" {
+		w.Header().Set("Content-Type", contentType(res.cfg.Output.Format))
+		output.WriteResults([]*generator.Result{result}, res.cfg, output.Options{Part: req.Part, Writer: w, Quiet: true})
+		return
+	}
+
+	resp := GenerateResponse{
+		Project:     result.ProjectName,
+		Path:        result.ProjectPath,
+		Files:       result.FileCount,
+		CodeFiles:   result.CodeFiles,
+		ConfigFiles: result.ConfigFiles,
+		Lines:       result.TotalLines,
+		Chars:       result.TotalChars,
+		Tokens:      result.TotalTokens,
+		Tokenizer:   result.Tokenizer,
+		Redactions:  res.total,
+		Warnings:    result.Warnings,
+		Segments:    make([]SegmentEntry, 0, len(result.Segments)),
+	}
+	resp.TokensEstimated = result.TokensEstimated
+	for _, seg := range result.Segments {
+		if req.Part > 0 && seg.PartNum != req.Part {
+			continue
+		}
+		resp.Segments = append(resp.Segments, SegmentEntry{
+			Part:      seg.PartNum,
+			Total:     seg.TotalPart,
+			Content:   seg.Content,
+			Chars:     seg.CharCount,
+			Tokens:    seg.TokenCount,
+			FileRange: seg.FileRange,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// scan 按与命令行相同的顺序加载配置、应用请求参数、扫描并遮盖敏感信息
+func (s *Server) scan(r *http.Request, req *Request) (*scanned, error) {
+	dir, status, err := s.allowedDir(req.Dir)
+	if err != nil {
+		return nil, &httpError{status: status, err: err}
+	}
+
+	cfg, err := config.LoadFor(s.opts.ConfigPath, []string{dir})
+	if err != nil {
+		return nil, fmt.Errorf("配置加载失败: %w", err)
+	}
+	if err := req.apply(cfg); err != nil {
+		return nil, badRequest(err)
+	}
+
+	res := &scanned{cfg: cfg, path: dir}
+	scanOpts := scanner.Options{
+		Cache:   s.cacheFor(cfg),
+		Context: r.Context(),
+		OnSkip:  func(f scanner.SkippedFile) { res.skipped = append(res.skipped, f) },
+	}
+	if req.Rev != "" {
+		src, err := source.OpenRevision(dir, req.Rev, cfg.MaxFileSize)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		res.path, res.fsys = src.Root, src.FS
+		res.files, err = scanner.ScanFS(src.FS, src.Root, cfg, scanOpts)
+	} else {
+		res.files, err = scanner.ScanDirectoryWithOptions(dir, cfg, scanOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Redact.Enabled || cfg.Redact.FailOnSecrets {
+		redactor, err := redact.New(cfg)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		res.redactions = make(map[string]int)
+		for _, report := range redactor.Files(res.files) {
+			res.redactions[report.Path] = len(report.Findings)
+			res.total += len(report.Findings)
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) cacheFor(cfg *config.Config) *cache.Cache {
+	if !cfg.Cache {
+		return nil
+	}
+	return s.opts.Cache
+}
+
+// fail 输出错误；客户端已断开时不再写入
+func (s *Server) fail(w http.ResponseWriter, err error) {
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		writeError(w, he.status, he.err)
+	case errors.Is(err, context.Canceled):
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// parseRequest 读取查询字符串，POST 请求再用 JSON 请求体覆盖
+func parseRequest(r *http.Request) (*Request, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return nil, &httpError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("不支持 %s", r.Method)}
+	}
+
+	req, err := requestFromQuery(r.URL.Query())
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	if r.Method == http.MethodPost {
+		dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(req); err != nil && !errors.Is(err, io.EOF) {
+			return nil, badRequest(fmt.Errorf("请求体不是有效的 JSON: %w", err))
+		}
+	}
+	return req, nil
+}
+
+func requestFromQuery(q url.Values) (*Request, error) {
+	req := &Request{
+		Dir:       q.Get("dir"),
+		Rev:       q.Get("rev"),
+		Format:    q.Get("format"),
+		Tokenizer: q.Get("tokenizer"),
+		SplitMode: q.Get("split_mode"),
+		KeepFull:  splitList(q["keep_full"]),
+		Exclude:   splitList(q["exclude"]),
+		Regex:     q["regex"],
+		Only:      splitList(q["only"]),
+		As:        q.Get("as"),
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"max_chars", &req.MaxChars},
+		{"max_tokens", &req.MaxTokens},
+		{"part", &req.Part},
+	}
+	for _, p := range ints {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s 必须是非负整数: %s", p.name, v)
+			}
+			*p.dst = n
+		}
+	}
+
+	bools := []struct {
+		name string
+		dst  **bool
+	}{
+		{"compress", &req.Compress},
+		{"skeleton", &req.Skeleton},
+		{"tree", &req.Tree},
+		{"redact", &req.Redact},
+	}
+	for _, p := range bools {
+		if v := q.Get(p.name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s 必须是 true 或 false: %s", p.name, v)
+			}
+			*p.dst = &b
+		}
+	}
+	if v := q.Get("ultra_compress"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("ultra_compress 必须是 true 或 false: %s", v)
+		}
+		req.Ultra = b
+	}
+
+	return req, nil
+}
+
+// splitList 展开重复参数与逗号分隔的值
+func splitList(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				result = append(result, p)
+			}
+		}
+	}
+	return result
+}
+
+// apply 把请求参数覆盖到配置上，规则与命令行参数相同
+func (req *Request) apply(cfg *config.Config) error {
+	if req.MaxChars > 0 {
+		cfg.Output.MaxChars = req.MaxChars
+	}
+	if req.MaxTokens > 0 {
+		cfg.Output.MaxTokens = req.MaxTokens
+	}
+	if req.Tokenizer != "" {
+		cfg.Output.Tokenizer = req.Tokenizer
+	}
+	if req.Compress != nil {
+		cfg.Output.Compress = *req.Compress
+	}
+	if req.Ultra {
+		cfg.Output.UltraCompress = true
+		cfg.Output.Compress = true
+	}
+	if req.Skeleton != nil {
+		cfg.Output.Skeleton = *req.Skeleton
+	}
+	cfg.Output.SkeletonKeep = append(cfg.Output.SkeletonKeep, req.KeepFull...)
+	if req.Redact != nil {
+		cfg.Redact.Enabled = *req.Redact
+	}
+	if req.SplitMode != "" {
+		cfg.Output.SplitMode = req.SplitMode
+	}
+	if req.Format != "" {
+		cfg.Output.Format = req.Format
+	}
+	if err := generator.ValidateFormat(cfg.Output.Format); err != nil {
+		return err
+	}
+	if req.Tree != nil {
+		cfg.Output.IncludeTree = *req.Tree
+	}
+	cfg.CustomIgnore.Patterns = append(cfg.CustomIgnore.Patterns, req.Exclude...)
+	cfg.CustomIgnore.Regex = append(cfg.CustomIgnore.Regex, req.Regex...)
+	cfg.Include.Patterns = append(cfg.Include.Patterns, req.Only...)
+	return nil
+}
+
+func contentType(format string) string {
+	switch format {
+	case generator.FormatXML:
+		return "application/xml; charset=utf-8"
+	case generator.FormatJSON:
+		return "application/json; charset=utf-8"
+	case generator.FormatJSONL:
+		return "application/x-ndjson; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
@@ -0,0 +1,185 @@
+// Package server 以本地 HTTP 接口提供扫描与生成，供编辑器插件等工具调用
+//
+// 接口:
+//
+//	GET  /api/health     版本信息
+//	GET  /api/files      扫描目录，返回文件列表与统计
+//	POST /api/generate   生成分段，返回 JSON 或原始文档
+//
+// 参数既可以放在查询字符串中，也可以作为 POST 的 JSON 请求体，字段见 Request。
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/version"
+)
+
+// Options 服务选项
+type Options struct {
+	// ConfigPath 非空时每个请求都使用该配置文件，否则与命令行相同，按目标目录查找 .ptlm.yaml
+	ConfigPath string
+	// Roots 允许访问的目录，请求的目录必须位于其中之一
+	Roots []string
+	// Cache 非空时扫描与生成使用磁盘缓存
+	Cache *cache.Cache
+	// AllowRemote 接受 Host 不是本机的请求；默认拒绝，防止网页通过 DNS 重绑定访问接口
+	AllowRemote bool
+}
+
+// Server 实现 http.Handler，可直接交给 http.Server 或 httptest.NewServer
+type Server struct {
+	opts Options
+	// roots 解析符号链接后的允许目录，literal 为解析前的绝对路径，用于字面检查
+	roots   []string
+	literal []string
+	mux     *http.ServeMux
+}
+
+// New 创建服务，Roots 中的目录必须存在
+func New(opts Options) (*Server, error) {
+	if len(opts.Roots) == 0 {
+		return nil, errors.New("至少需要一个允许访问的目录")
+	}
+
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	for _, root := range opts.Roots {
+		resolved, err := resolveDir(root)
+		if err != nil {
+			return nil, fmt.Errorf("允许访问的目录无效: %w", err)
+		}
+		s.roots = append(s.roots, resolved)
+		if absRoot, err := filepath.Abs(root); err == nil {
+			s.literal = append(s.literal, absRoot)
+		}
+	}
+
+	s.mux.HandleFunc("/api/health", s.handleHealth)
+	s.mux.HandleFunc("/api/files", s.handleFiles)
+	s.mux.HandleFunc("/api/generate", s.handleGenerate)
+	return s, nil
+}
+
+// Roots 解析后的允许访问目录
+func (s *Server) Roots() []string {
+	return append([]string(nil), s.roots...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.AllowRemote && !isLoopbackHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("拒绝来自 %s 的请求，只接受本机地址", r.Host))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持 %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"version": version.Version,
+		"roots":   s.roots,
+	})
+}
+
+// resolveDir 转换为绝对路径并解析符号链接，路径必须是已存在的目录
+func resolveDir(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(absDir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s 不是目录", dir)
+	}
+	return resolved, nil
+}
+
+// allowedDir 解析请求的目录：相对路径基于第一个允许的目录，结果必须位于某个允许的目录之内
+func (s *Server) allowedDir(dir string) (string, int, error) {
+	if dir == "" {
+		return s.roots[0], 0, nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.roots[0], dir)
+	}
+
+	// 先按字面路径检查，不在允许范围内的路径不暴露是否存在
+	if clean := filepath.Clean(dir); !within(s.literal, clean) && !within(s.roots, clean) {
+		return "", http.StatusForbidden, fmt.Errorf("%s 不在允许访问的目录中", dir)
+	}
+	resolved, err := resolveDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", http.StatusNotFound, fmt.Errorf("目录不存在: %s", dir)
+		}
+		return "", http.StatusBadRequest, err
+	}
+	// 再检查解析符号链接之后的路径
+	if !within(s.roots, resolved) {
+		return "", http.StatusForbidden, fmt.Errorf("%s 不在允许访问的目录中", dir)
+	}
+	return resolved, 0, nil
+}
+
+// within path 是否位于 roots 之一中
+func within(roots []string, path string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopbackHost Host 头是否指向本机
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// IsLoopbackAddr 监听地址是否只在本机可达
+func IsLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	return isLoopbackHost(host)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
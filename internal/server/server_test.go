@@ -0,0 +1,389 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles 在 dir 中写入文件，键为 "/" 分隔的相对路径
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testEnv 一个允许访问的项目目录 root，以及与其并列、不允许访问的 outside
+type testEnv struct {
+	root    string
+	outside string
+	srv     *httptest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	parent := t.TempDir()
+	env := &testEnv{
+		root:    filepath.Join(parent, "project"),
+		outside: filepath.Join(parent, "outside"),
+	}
+	writeFiles(t, env.root, map[string]string{
+		"main.go":           "package main\n\nimport \"fmt\"\n\n// main 入口\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n",
+		"lib/util.go":       "package lib\n\n// Add 求和\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"node_modules/x.js": "module.exports = 1\n",
+	})
+	writeFiles(t, env.outside, map[string]string{
+		"secret.go": "package secret\n\nconst Token = \"outside\"\n",
+	})
+
+	s, err := New(Options{Roots: []string{env.root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.srv = httptest.NewServer(s)
+	t.Cleanup(env.srv.Close)
+	return env
+}
+
+// do 发送请求并返回状态码与响应体
+func (env *testEnv) do(t *testing.T, method, path, body string) (int, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, env.srv.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := env.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func decode[T any](t *testing.T, data []byte) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("解析响应失败: %v\n%s", err, data)
+	}
+	return v
+}
+
+func TestNewRequiresRoots(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("没有允许的目录时应返回错误")
+	}
+	if _, err := New(Options{Roots: []string{filepath.Join(t.TempDir(), "missing")}}); err == nil {
+		t.Error("不存在的目录应返回错误")
+	}
+}
+
+func TestRejectNonLoopbackHost(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, host := range []string{"evil.example.com", "evil.example.com:8080", "192.168.1.10:7878", "localhost.evil.com"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		env.srv.Config.Handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Host %s: status = %d, want 403", host, rec.Code)
+		}
+	}
+
+	for _, host := range []string{"localhost:7878", "127.0.0.1:7878", "[::1]:7878", "LOCALHOST"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		env.srv.Config.Handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Host %s: status = %d, want 200", host, rec.Code)
+		}
+	}
+
+	s, err := New(Options{Roots: []string{env.root}, AllowRemote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Host = "evil.example.com"
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("AllowRemote: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:7878", true},
+		{"localhost:7878", true},
+		{"[::1]:7878", true},
+		{":7878", false},
+		{"0.0.0.0:7878", false},
+		{"192.168.1.10:7878", false},
+		{"7878", false},
+	}
+	for _, tt := range tests {
+		if got := IsLoopbackAddr(tt.addr); got != tt.want {
+			t.Errorf("IsLoopbackAddr(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRejectDirOutsideRoots(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 项目内指向外部目录的符号链接
+	link := filepath.Join(env.root, "escape")
+	if err := os.Symlink(env.outside, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+
+	dirs := []string{
+		env.outside,
+		"../outside",
+		"lib/../../outside",
+		filepath.Join(env.root, "..", "outside"),
+		"/",
+		"escape",
+		link,
+	}
+	for _, path := range []string{"/api/files", "/api/generate"} {
+		for _, dir := range dirs {
+			status, body := env.do(t, http.MethodGet, path+"?dir="+url.QueryEscape(dir), "")
+			if status != http.StatusForbidden {
+				t.Errorf("%s dir=%s: status = %d, want 403\n%s", path, dir, status, body)
+			}
+			if strings.Contains(string(body), "secret.go") {
+				t.Errorf("%s dir=%s: 返回了允许范围外的内容\n%s", path, dir, body)
+			}
+		}
+	}
+
+	// JSON 请求体中的 dir 同样检查
+	status, _ := env.do(t, http.MethodPost, "/api/generate", `{"dir":"../outside"}`)
+	if status != http.StatusForbidden {
+		t.Errorf("POST dir=../outside: status = %d, want 403", status)
+	}
+
+	// 允许范围外不存在的目录同样是 403，不暴露是否存在
+	status, _ = env.do(t, http.MethodGet, "/api/files?dir="+url.QueryEscape("../missing"), "")
+	if status != http.StatusForbidden {
+		t.Errorf("dir=../missing: status = %d, want 403", status)
+	}
+	status, _ = env.do(t, http.MethodGet, "/api/files?dir=missing", "")
+	if status != http.StatusNotFound {
+		t.Errorf("dir=missing: status = %d, want 404", status)
+	}
+}
+
+func TestSymlinkedRoot(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 允许的目录本身是符号链接时，通过链接路径与真实路径都可以访问
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(env.root, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	s, err := New(Options{Roots: []string{link}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	for _, dir := range []string{"", link, filepath.Join(link, "lib"), "lib"} {
+		resp, err := srv.Client().Get(srv.URL + "/api/files?dir=" + url.QueryEscape(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("dir=%q: status = %d, want 200", dir, resp.StatusCode)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	env := newTestEnv(t)
+
+	status, body := env.do(t, http.MethodGet, "/api/files", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d\n%s", status, body)
+	}
+	resp := decode[FilesResponse](t, body)
+	if resp.Project != "project" {
+		t.Errorf("project = %q", resp.Project)
+	}
+	var paths []string
+	for _, f := range resp.Files {
+		paths = append(paths, f.Path)
+	}
+	if got := strings.Join(paths, ","); got != "lib/util.go,main.go" {
+		t.Errorf("files = %s, want lib/util.go,main.go", got)
+	}
+	if resp.Lines != 14 || resp.Files[1].Language != "go" || !resp.Files[1].IsCode {
+		t.Errorf("统计不对: %+v", resp)
+	}
+
+	// 查询参数与 JSON 请求体中的过滤条件
+	status, body = env.do(t, http.MethodGet, "/api/files?dir=lib", "")
+	if resp := decode[FilesResponse](t, body); status != http.StatusOK || len(resp.Files) != 1 || resp.Files[0].Path != "util.go" {
+		t.Errorf("dir=lib: %d %s", status, body)
+	}
+	status, body = env.do(t, http.MethodPost, "/api/files", `{"exclude":["lib"]}`)
+	if resp := decode[FilesResponse](t, body); status != http.StatusOK || len(resp.Files) != 1 || resp.Files[0].Path != "main.go" {
+		t.Errorf("exclude=lib: %d %s", status, body)
+	}
+}
+
+func TestFilesBadRequest(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/files?max_tokens=abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/files?compress=maybe", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/files", `{"unknown":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/files", `not json`, http.StatusBadRequest},
+		{http.MethodDelete, "/api/files", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/health", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		status, body := env.do(t, tt.method, tt.path, tt.body)
+		if status != tt.status {
+			t.Errorf("%s %s %s: status = %d, want %d\n%s", tt.method, tt.path, tt.body, status, tt.status, body)
+		}
+		if e := decode[map[string]string](t, body); e["error"] == "" {
+			t.Errorf("%s %s: 缺少 error\n%s", tt.method, tt.path, body)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	env := newTestEnv(t)
+
+	status, body := env.do(t, http.MethodPost, "/api/generate", `{}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d\n%s", status, body)
+	}
+	resp := decode[GenerateResponse](t, body)
+	if resp.Files != 2 || resp.CodeFiles != 2 || len(resp.Segments) != 1 {
+		t.Fatalf("结果不对: %s", body)
+	}
+	// 默认分词器没有词表，token 数标注为估算
+	if !resp.TokensEstimated || !strings.Contains(resp.Segments[0].Content, "约 ") {
+		t.Errorf("token 数未标注为估算: %s", body)
+	}
+	seg := resp.Segments[0]
+	if seg.Part != 1 || seg.Total != 1 || seg.Tokens == 0 || seg.Chars == 0 {
+		t.Errorf("分段不对: %+v", seg)
+	}
+	for _, want := range []string{"main.go", "lib/util.go", "return a + b"} {
+		if !strings.Contains(seg.Content, want) {
+			t.Errorf("缺少 %q:\n%s", want, seg.Content)
+		}
+	}
+
+	// skeleton 与 only
+	status, body = env.do(t, http.MethodPost, "/api/generate", `{"skeleton":true,"only":["lib/**"]}`)
+	resp = decode[GenerateResponse](t, body)
+	if status != http.StatusOK || resp.Files != 1 {
+		t.Fatalf("only: %d %s", status, body)
+	}
+	if content := resp.Segments[0].Content; strings.Contains(content, "return a + b") || !strings.Contains(content, "func Add(a, b int) int") {
+		t.Errorf("skeleton 未生效:\n%s", content)
+	}
+
+	// 按预算分段后只取一段
+	status, body = env.do(t, http.MethodGet, "/api/generate?max_tokens=60&tree=false&part=2", "")
+	resp = decode[GenerateResponse](t, body)
+	if status != http.StatusOK || len(resp.Segments) != 1 || resp.Segments[0].Part != 2 || resp.Segments[0].Total < 2 {
+		t.Errorf("part=2: %d %s", status, body)
+	}
+	status, _ = env.do(t, http.MethodGet, "/api/generate?part=99", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("part=99: status = %d, want 400", status)
+	}
+}
+
+func TestGenerateAs(t *testing.T) {
+	env := newTestEnv(t)
+
+	req, _ := http.NewRequest(http.MethodGet, env.srv.URL+"/api/generate?as=markdown", nil)
+	resp, err := env.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/markdown") {
+		t.Fatalf("as=markdown: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "```go") || !strings.Contains(string(body), "func main()") {
+		t.Errorf("markdown 内容不对:\n%s", body)
+	}
+
+	status, body2 := env.do(t, http.MethodGet, "/api/generate?as=markdown&format=xml", "")
+	if status != http.StatusOK || !strings.Contains(string(body2), "<") {
+		t.Errorf("format=xml: %d %s", status, body2)
+	}
+
+	for _, q := range []string{"as=pdf", "format=docx"} {
+		if status, _ := env.do(t, http.MethodGet, "/api/generate?"+q, ""); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, status)
+		}
+	}
+}
+
+func TestGenerateFailOnSecrets(t *testing.T) {
+	env := newTestEnv(t)
+	writeFiles(t, env.root, map[string]string{
+		".ptlm.yaml": "redact:\n  enabled: true\n  fail_on_secrets: true\n",
+		"config.ini": "[db]\npassword=hunter2secret\n",
+	})
+
+	status, body := env.do(t, http.MethodGet, "/api/generate", "")
+	if status != http.StatusUnprocessableEntity || strings.Contains(string(body), "hunter2secret") {
+		t.Errorf("status = %d, want 422\n%s", status, body)
+	}
+
+	status, body = env.do(t, http.MethodGet, "/api/files", "")
+	resp := decode[FilesResponse](t, body)
+	found := false
+	for _, f := range resp.Files {
+		found = found || (f.Path == "config.ini" && f.Redactions == 1)
+	}
+	if status != http.StatusOK || !found {
+		t.Errorf("files 应报告遮盖数: %d %s", status, body)
+	}
+}
@@ -0,0 +1,358 @@
+package skeleton
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// charQuoteLanguages 单引号表示字符字面量的语言，其余语言中单引号是字符串
+var charQuoteLanguages = map[string]bool{
+	"c":      true,
+	"cpp":    true,
+	"objc":   true,
+	"java":   true,
+	"csharp": true,
+	"kotlin": true,
+	"scala":  true,
+	"swift":  true,
+	"rust":   true,
+	"go":     true,
+}
+
+// tripleQuoteLanguages 支持 """ 多行字符串的语言
+var tripleQuoteLanguages = map[string]bool{
+	"java":   true,
+	"kotlin": true,
+	"scala":  true,
+	"swift":  true,
+	"dart":   true,
+}
+
+// preprocessorLanguages 使用 C 预处理指令的语言
+var preprocessorLanguages = map[string]bool{
+	"c":      true,
+	"cpp":    true,
+	"objc":   true,
+	"csharp": true,
+}
+
+// maskCStyle 把注释与字符串字面量替换为空格（保留换行），便于只在代码部分匹配括号
+func maskCStyle(content, language string) []byte {
+	src := []byte(content)
+	mask := []byte(content)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(mask); k++ {
+			if mask[k] != '\n' {
+				mask[k] = ' '
+			}
+		}
+	}
+
+	lineStart := true
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		atLineStart := lineStart
+		lineStart = ch == '\n' || (lineStart && (ch == ' ' || ch == '\t'))
+
+		switch {
+		case ch == '#' && atLineStart && preprocessorLanguages[language]:
+			// 预处理指令中的括号不成对，整行（含续行）跳过
+			end := i
+			for end < len(src) && (src[end] != '\n' || src[end-1] == '\\') {
+				end++
+			}
+			blank(i, end)
+			i = end - 1
+
+		case ch == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := indexFrom(src, i, "\n", len(src))
+			blank(i, end)
+			i = end - 1
+
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := indexFrom(src, i+2, "*/", len(src)-2) + 2
+			blank(i, end)
+			i = end - 1
+
+		case ch == '"' && tripleQuoteLanguages[language] && strings.HasPrefix(content[i:], `"""`):
+			end := indexFrom(src, i+3, `"""`, len(src)-3) + 3
+			blank(i, end)
+			i = end - 1
+
+		case ch == '"':
+			// C# 的 @"..." 中反斜杠不转义
+			verbatim := language == "csharp" && i > 0 && src[i-1] == '@'
+			end := scanQuoted(src, i, '"', !verbatim, language == "csharp" || language == "swift")
+			blank(i, end)
+			i = end - 1
+
+		case ch == '`' && (language == "javascript" || language == "typescript" || language == "go"):
+			end := scanQuoted(src, i, '`', language != "go", true)
+			blank(i, end)
+			i = end - 1
+
+		case ch == '\'':
+			end := i + 1
+			if charQuoteLanguages[language] {
+				// 只在形如 'x'、'\n' 时视为字符字面量，避免把 Rust 的生命周期 'a 当作字符串
+				if n, ok := charLiteralLen(src[i:]); ok {
+					end = i + n
+				}
+			} else {
+				end = scanQuoted(src, i, '\'', true, false)
+			}
+			blank(i, end)
+			i = end - 1
+		}
+	}
+
+	return mask
+}
+
+// indexFrom 从 start 开始查找 sep，找不到时返回 fallback
+func indexFrom(src []byte, start int, sep string, fallback int) int {
+	if start > len(src) {
+		return fallback
+	}
+	if idx := strings.Index(string(src[start:]), sep); idx >= 0 {
+		return start + idx
+	}
+	if fallback < start {
+		return len(src)
+	}
+	return fallback
+}
+
+// scanQuoted 返回从 start 处引号开始的字符串结束位置（不含）；不允许跨行的字符串在行尾结束
+func scanQuoted(src []byte, start int, quote byte, escapes, multiline bool) int {
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			return i + 1
+		case '\n':
+			if !multiline {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+// charLiteralLen 字符字面量的长度，不是字符字面量时返回 false
+func charLiteralLen(src []byte) (int, bool) {
+	if len(src) < 3 {
+		return 0, false
+	}
+	if src[1] == '\\' {
+		for i := 2; i < len(src) && i < 16; i++ {
+			if src[i] == '\'' {
+				return i + 1, true
+			}
+			if src[i] == '\n' {
+				break
+			}
+		}
+		return 0, false
+	}
+	_, size := utf8.DecodeRune(src[1:])
+	if 1+size < len(src) && src[1+size] == '\'' {
+		return size + 2, true
+	}
+	return 0, false
+}
+
+// asiLanguages 可以省略分号的语言，换行也会结束声明头部
+var asiLanguages = map[string]bool{
+	"go":         true,
+	"javascript": true,
+	"typescript": true,
+	"kotlin":     true,
+	"swift":      true,
+	"scala":      true,
+}
+
+// frame 一层未闭合的大括号或圆括号，start 为当前声明头部的起点
+type frame struct {
+	start int
+	brace bool
+}
+
+// extractCStyle 按大括号扫描，把函数体替换为占位符；类、结构体等容器内部继续扫描
+func extractCStyle(content, language string) string {
+	mask := maskCStyle(content, language)
+
+	var builder strings.Builder
+	last := 0
+	frames := []frame{{start: 0, brace: true}}
+
+	for i := 0; i < len(mask); i++ {
+		top := &frames[len(frames)-1]
+		switch mask[i] {
+		case '(', '[':
+			frames = append(frames, frame{start: i + 1})
+		case ')', ']':
+			for len(frames) > 1 {
+				popped := frames[len(frames)-1]
+				frames = frames[:len(frames)-1]
+				if !popped.brace {
+					break
+				}
+			}
+		case ',':
+			// 参数列表中的回调以逗号分隔；大括号内的逗号属于 throws A, B 等签名的一部分
+			if !top.brace {
+				top.start = i + 1
+			}
+		case ';':
+			top.start = i + 1
+		case '\n':
+			if top.brace && asiLanguages[language] && !continuesLine(mask[top.start:i]) {
+				top.start = i + 1
+			}
+		case '}':
+			for len(frames) > 1 {
+				popped := frames[len(frames)-1]
+				frames = frames[:len(frames)-1]
+				if popped.brace {
+					break
+				}
+			}
+			frames[len(frames)-1].start = i + 1
+		case '{':
+			if isFunctionHeader(string(mask[top.start:i]), language) {
+				if end := matchBrace(mask, i); end > 0 {
+					builder.WriteString(content[last:i])
+					builder.WriteString("{ " + Placeholder + " }")
+					last = end + 1
+					i = end
+					top.start = end + 1
+					continue
+				}
+			}
+			frames = append(frames, frame{start: i + 1, brace: true})
+		}
+	}
+	builder.WriteString(content[last:])
+
+	return builder.String()
+}
+
+// continuesLine 行尾是运算符、逗号等时声明延续到下一行
+func continuesLine(header []byte) bool {
+	text := strings.TrimRight(string(header), " \t\r")
+	if text == "" {
+		return true
+	}
+	return strings.ContainsRune(",=.:+-*/&|<>?!(", rune(text[len(text)-1]))
+}
+
+// matchBrace 与 open 处左大括号配对的右大括号位置，没有时返回 -1
+func matchBrace(mask []byte, open int) int {
+	depth := 0
+	for i := open; i < len(mask); i++ {
+		switch mask[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+var (
+	headerWordPattern = regexp.MustCompile(`[A-Za-z_$][\w$]*`)
+	// callPattern 名称（或 C++ lambda 的捕获列表、模板参数）后紧跟参数列表
+	callPattern = regexp.MustCompile(`([\w$>\]]|operator\S*)\s*\(`)
+	// annotationPattern Java/Kotlin 注解、TypeScript 装饰器与 Rust 属性
+	annotationPattern = regexp.MustCompile(`#!?\[[^\]]*\]|@[\w.]+(\s*\([^()]*\))?`)
+	// namedFunctionPattern 函数关键字后紧跟名称与参数列表（或泛型参数），名称可以是 new、type 等容器关键字
+	namedFunctionPattern = regexp.MustCompile(`\b(?:fn|fun|func|function|def)\s+[A-Za-z_$][\w$]*\s*[<(\[]`)
+)
+
+// functionKeywords 出现即表示函数定义的关键字
+var functionKeywords = map[string]bool{
+	"fn":       true,
+	"fun":      true,
+	"func":     true,
+	"function": true,
+	"def":      true,
+}
+
+// blockKeywords 以这些关键字开头的是语句块，内部保留
+var blockKeywords = map[string]bool{
+	"if": true, "else": true, "for": true, "foreach": true, "while": true, "do": true,
+	"switch": true, "match": true, "when": true, "try": true, "catch": true, "finally": true,
+	"with": true, "using": true, "lock": true, "synchronized": true, "unsafe": true,
+	"loop": true, "select": true, "guard": true, "defer": true, "repeat": true,
+	"return": true, "case": true, "default": true,
+}
+
+// containerKeywords 出现即表示类型或命名空间，内部继续提取
+var containerKeywords = map[string]bool{
+	"class": true, "struct": true, "interface": true, "enum": true, "union": true,
+	"namespace": true, "module": true, "trait": true, "impl": true, "object": true,
+	"record": true, "extension": true, "protocol": true, "extern": true, "new": true,
+	"type": true, "mixin": true, "package": true, "implementation": true,
+}
+
+// isFunctionHeader 判断大括号之前的声明头部是否是函数定义
+func isFunctionHeader(header, language string) bool {
+	if language != "objc" {
+		header = annotationPattern.ReplaceAllString(header, " ")
+	}
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if strings.HasSuffix(header, "=>") {
+		return true
+	}
+
+	words := headerWordPattern.FindAllString(header, -1)
+	if len(words) > 0 && blockKeywords[words[0]] {
+		return false
+	}
+	// Objective-C 方法: - (void)run {
+	if language == "objc" && (header[0] == '-' || header[0] == '+') {
+		return true
+	}
+
+	// Rust 的 fn new(...) 等以容器关键字为名称的函数
+	if namedFunctionPattern.MatchString(header) {
+		return true
+	}
+
+	// 签名中的 "fn"/"func" 等关键字需要出现在参数列表之前，例如 Go 的 func (r *T) Name() {
+	paren := strings.Index(header, "(")
+	prefixWords := words
+	if paren >= 0 {
+		prefixWords = headerWordPattern.FindAllString(header[:paren], -1)
+	}
+	for _, word := range prefixWords {
+		if containerKeywords[word] {
+			return false
+		}
+	}
+	for _, word := range words {
+		if functionKeywords[word] {
+			return true
+		}
+	}
+
+	// 其余语言：名称后跟参数列表，右括号之后不能是赋值
+	closing := strings.LastIndex(header, ")")
+	if paren < 0 || closing < paren || !callPattern.MatchString(header) {
+		return false
+	}
+	tail := header[closing+1:]
+	return !strings.Contains(tail, "=") && !strings.Contains(header[:paren], "=")
+}
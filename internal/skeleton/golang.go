@@ -0,0 +1,34 @@
+package skeleton
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// extractGo 把顶层函数与方法的函数体替换为占位符，其余内容（包括注释与格式）保持原样
+func extractGo(content string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start := fset.Position(fn.Body.Lbrace).Offset
+		end := fset.Position(fn.Body.Rbrace).Offset + 1
+		builder.WriteString(content[last:start])
+		builder.WriteString("{ " + Placeholder + " }")
+		last = end
+	}
+	builder.WriteString(content[last:])
+
+	return builder.String(), nil
+}
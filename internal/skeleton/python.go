@@ -0,0 +1,179 @@
+package skeleton
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pythonSpan 一个字符串字面量的字节区间
+type pythonSpan struct {
+	start, end int
+}
+
+// maskPython 把注释与字符串替换为空格（保留换行），同时返回各字符串的位置，用于识别文档字符串
+func maskPython(content string) ([]byte, []pythonSpan) {
+	src := []byte(content)
+	mask := []byte(content)
+	var spans []pythonSpan
+
+	for i := 0; i < len(src); i++ {
+		switch ch := src[i]; ch {
+		case '#':
+			end := indexFrom(src, i, "\n", len(src))
+			for k := i; k < end; k++ {
+				mask[k] = ' '
+			}
+			i = end - 1
+
+		case '"', '\'':
+			end := len(src)
+			if triple := strings.Repeat(string(ch), 3); strings.HasPrefix(content[i:], triple) {
+				end = indexPythonQuote(src, i+3, triple) + 3
+			} else {
+				end = scanQuoted(src, i, ch, true, false)
+			}
+			if end > len(src) {
+				end = len(src)
+			}
+			spans = append(spans, pythonSpan{start: i, end: end})
+			for k := i; k < end; k++ {
+				if mask[k] != '\n' {
+					mask[k] = ' '
+				}
+			}
+			i = end - 1
+		}
+	}
+
+	return mask, spans
+}
+
+// indexPythonQuote 查找未转义的三引号结束位置
+func indexPythonQuote(src []byte, start int, quote string) int {
+	for i := start; i+len(quote) <= len(src); i++ {
+		if src[i] == '\\' {
+			i++
+			continue
+		}
+		if string(src[i:i+len(quote)]) == quote {
+			return i
+		}
+	}
+	return len(src) - len(quote)
+}
+
+var (
+	pythonDefPattern   = regexp.MustCompile(`^(\s*)(async\s+)?def\s`)
+	pythonStringPrefix = regexp.MustCompile(`^[rRuUbBfF]{0,2}["']`)
+)
+
+// extractPython 按缩进识别函数体，保留签名与文档字符串，函数体以 ... 代替
+func extractPython(content string) string {
+	mask, spans := maskPython(content)
+	lines := strings.Split(content, "\n")
+	masked := strings.Split(string(mask), "\n")
+
+	// offsets[i] 第 i 行在 content 中的起始位置
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+	lineOf := func(offset int) int {
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] > offset {
+				return i - 1
+			}
+		}
+		return len(lines) - 1
+	}
+
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		m := pythonDefPattern.FindStringSubmatch(masked[i])
+		if m == nil {
+			result = append(result, lines[i])
+			continue
+		}
+		indent := len(m[1])
+
+		// 签名可能跨多行，直到括号闭合且以冒号结尾
+		end, depth := i, 0
+		for ; end < len(lines); end++ {
+			depth += bracketDelta(masked[end])
+			if depth <= 0 && strings.HasSuffix(strings.TrimSpace(masked[end]), ":") {
+				break
+			}
+		}
+		if end >= len(lines) {
+			result = append(result, lines[i:]...)
+			break
+		}
+		result = append(result, lines[i:end+1]...)
+
+		// 函数体：之后缩进更深的行以及括号内的续行，行首被字符串遮盖的行视为空行
+		bodyStart, bodyEnd := end+1, end
+		depth = 0
+		for k := end + 1; k < len(lines); k++ {
+			text := masked[k]
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if depth <= 0 && lineIndent(text) <= indent {
+				break
+			}
+			depth += bracketDelta(text)
+			bodyEnd = k
+		}
+		if bodyEnd < bodyStart {
+			// 单行函数，例如 def f(): return 1
+			i = end
+			continue
+		}
+
+		first := bodyStart
+		for first <= bodyEnd && strings.TrimSpace(masked[first]) == "" && strings.TrimSpace(lines[first]) == "" {
+			first++
+		}
+		bodyIndent := lines[first][:lineIndent(lines[first])]
+
+		// 文档字符串
+		stmt := strings.TrimLeft(lines[first], " \t")
+		if pythonStringPrefix.MatchString(stmt) {
+			offset := offsets[first] + len(lines[first]) - len(stmt)
+			for _, span := range spans {
+				if span.start >= offset {
+					last := lineOf(span.end - 1)
+					if last > bodyEnd {
+						last = bodyEnd
+					}
+					result = append(result, lines[first:last+1]...)
+					break
+				}
+			}
+		}
+
+		result = append(result, bodyIndent+PythonPlaceholder)
+		i = bodyEnd
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// bracketDelta 一行中左括号与右括号数量之差
+func bracketDelta(line string) int {
+	delta := 0
+	for _, ch := range line {
+		switch ch {
+		case '(', '[', '{':
+			delta++
+		case ')', ']', '}':
+			delta--
+		}
+	}
+	return delta
+}
+
+// lineIndent 行首空白的长度
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
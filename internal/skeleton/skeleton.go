@@ -0,0 +1,73 @@
+package skeleton
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Placeholder 替换函数体的占位注释
+const Placeholder = "/* ... */"
+
+// PythonPlaceholder Python 中替换函数体的占位语句
+const PythonPlaceholder = "..."
+
+var cStyleLanguages = map[string]bool{
+	"javascript": true,
+	"typescript": true,
+	"java":       true,
+	"c":          true,
+	"cpp":        true,
+	"go":         true,
+	"rust":       true,
+	"php":        true,
+	"swift":      true,
+	"kotlin":     true,
+	"scala":      true,
+	"dart":       true,
+	"csharp":     true,
+	"objc":       true,
+}
+
+// Supported 是否支持提取该语言的骨架
+func Supported(language string) bool {
+	language = strings.ToLower(language)
+	return language == "python" || cStyleLanguages[language]
+}
+
+// Extract 提取代码骨架：保留包声明、导入、类型声明、函数签名与文档注释，函数体以占位符代替
+//
+// Go 代码基于语法树处理，解析失败时与其他 C 风格语言一样按词法扫描大括号；
+// 不支持的语言返回 false，调用方应输出原文
+func Extract(content, language string) (string, bool) {
+	language = strings.ToLower(language)
+
+	var outline string
+	switch {
+	case language == "go":
+		var err error
+		if outline, err = extractGo(content); err != nil {
+			outline = extractCStyle(content, language)
+		}
+	case language == "python":
+		outline = extractPython(content)
+	case cStyleLanguages[language]:
+		outline = extractCStyle(content, language)
+	default:
+		return content, false
+	}
+
+	return tidy(outline), true
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// tidy 去掉行尾空白，合并连续空行
+func tidy(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	content = strings.Join(lines, "\n")
+	content = blankLinesPattern.ReplaceAllString(content, "\n\n")
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,96 @@
+package skeleton
+
+import "testing"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		src      string
+		want     string
+	}{
+		{
+			"Go 字符串中的括号与函数字面量",
+			"go",
+			"package a\n\nimport \"fmt\"\n\n// T 类型\ntype T struct {\n\tA int\n}\n\n// F 说明\nfunc (t *T) F() string {\n\ts := \"}{\"\n\tg := func() { fmt.Println(s) }\n\tg()\n\treturn s\n}\n\nvar x = func() int { return 1 }()\n",
+			"package a\n\nimport \"fmt\"\n\n// T 类型\ntype T struct {\n\tA int\n}\n\n// F 说明\nfunc (t *T) F() string { /* ... */ }\n\nvar x = func() int { return 1 }()",
+		},
+		{
+			"Go 无法解析时按大括号扫描，跳过字符串与注释中的括号",
+			"go",
+			"package a\n\nfunc F() {\n\ts := \"}\"\n\t// }\n\tif true {\n\t}\n}\n\nfunc broken( {\n}\n",
+			"package a\n\nfunc F() { /* ... */ }\n\nfunc broken( {\n}",
+		},
+		{
+			"JavaScript 类方法、嵌套函数与箭头函数",
+			"javascript",
+			"// 注释 {\nclass A {\n  /** 文档 */\n  m(a) {\n    const s = \"}\";\n    const t = `${a} {`;\n    return () => { return s; };\n  }\n}\n\nfunction f(x) {\n  // }\n  if (x) { return '{'; }\n  function inner() { return 1; }\n}\n\nexport const g = (a, b) => {\n  return a + b;\n};\n",
+			"// 注释 {\nclass A {\n  /** 文档 */\n  m(a) { /* ... */ }\n}\n\nfunction f(x) { /* ... */ }\n\nexport const g = (a, b) => { /* ... */ };",
+		},
+		{
+			"Java 注解、throws 与内部类",
+			"java",
+			"package a;\n\n@Service\npublic class A {\n    private int x = 1;\n\n    @Override\n    public String toString() throws IOException, Exception {\n        char c = '{';\n        return \"}\";\n    }\n\n    static class B {\n        void m() { }\n    }\n}\n",
+			"package a;\n\n@Service\npublic class A {\n    private int x = 1;\n\n    @Override\n    public String toString() throws IOException, Exception { /* ... */ }\n\n    static class B {\n        void m() { /* ... */ }\n    }\n}",
+		},
+		{
+			"C 预处理指令与换行后的函数体",
+			"c",
+			"#include <stdio.h>\n#define M(x) { x }\n\nstruct s {\n    int a;\n};\n\nint main(int argc, char **argv)\n{\n    printf(\"{\\n\");\n    return 0;\n}\n",
+			"#include <stdio.h>\n#define M(x) { x }\n\nstruct s {\n    int a;\n};\n\nint main(int argc, char **argv)\n{ /* ... */ }",
+		},
+		{
+			"Rust 生命周期、字符字面量与名为 new 的函数",
+			"rust",
+			"impl<'a> Foo<'a> {\n    pub fn new(s: &'a str) -> Self {\n        let c = '}';\n        Foo { s }\n    }\n}\n",
+			"impl<'a> Foo<'a> {\n    pub fn new(s: &'a str) -> Self { /* ... */ }\n}",
+		},
+		{
+			"Python 装饰器、多行签名、嵌套函数与文档字符串",
+			"python",
+			"import os\n\n\n@decorator\n@other(arg=1)\ndef f(a,\n      b):\n    \"\"\"文档\n    多行\"\"\"\n    def inner():\n        return 1\n    s = \"\"\"\ndef fake():\n\"\"\"\n    return inner()\n\n\nclass C(Base):\n    \"\"\"类文档\"\"\"\n    x = 1\n\n    @property\n    def p(self):  # 注释 :\n        return self.x\n",
+			"import os\n\n@decorator\n@other(arg=1)\ndef f(a,\n      b):\n    \"\"\"文档\n    多行\"\"\"\n    ...\n\nclass C(Base):\n    \"\"\"类文档\"\"\"\n    x = 1\n\n    @property\n    def p(self):  # 注释 :\n        ...",
+		},
+	}
+
+	for _, tt := range tests {
+		got, ok := Extract(tt.src, tt.language)
+		if !ok {
+			t.Errorf("%s: 应支持 %s", tt.name, tt.language)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsFunctionHeader(t *testing.T) {
+	tests := []struct {
+		header   string
+		language string
+		want     bool
+	}{
+		{"func (r *T) Name() error ", "go", true},
+		{"func Map[T any](s []T) ", "go", true},
+		{"pub fn new() -> Self ", "rust", true},
+		{"impl Foo ", "rust", false},
+		{"fun interface Action ", "kotlin", false},
+		{"new Runnable() ", "java", false},
+		{"if (x > 0) ", "java", false},
+		{"const f = (a) =>", "typescript", true},
+		{"x = foo() ", "java", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFunctionHeader(tt.header, tt.language); got != tt.want {
+			t.Errorf("%q (%s) = %v, want %v", tt.header, tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if got, ok := Extract("a: 1\n", "yaml"); ok || got != "a: 1\n" {
+		t.Errorf("不支持的语言应原样返回: %q %v", got, ok)
+	}
+}
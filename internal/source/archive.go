@@ -0,0 +1,112 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// loadZip 把 zip 中的目录与普通文件载入内存，符号链接等其它条目被跳过
+func loadZip(path string, maxFileSize int64) (*memFS, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	fsys := newMemFS()
+	for _, f := range r.File {
+		mode := f.Mode()
+		if mode.IsDir() {
+			fsys.addDir(f.Name, f.Modified)
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		size := int64(f.UncompressedSize64)
+		if tooLarge(size, maxFileSize) {
+			fsys.addFile(f.Name, nil, size, f.Modified)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		var r io.Reader = rc
+		if maxFileSize > 0 {
+			r = io.LimitReader(rc, maxFileSize+1)
+		}
+		data, err := io.ReadAll(r)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		size = int64(len(data))
+		if tooLarge(size, maxFileSize) {
+			// 目录中记录的大小与实际内容不符
+			data = nil
+		}
+		fsys.addFile(f.Name, data, size, f.Modified)
+	}
+	return fsys, nil
+}
+
+// loadTarFile 打开 tar 文件，compression 为 ""、"gzip" 或 "bzip2"
+func loadTarFile(path, compression string, maxFileSize int64) (*memFS, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	switch compression {
+	case "gzip":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case "bzip2":
+		r = bzip2.NewReader(file)
+	}
+	return loadTar(r, maxFileSize)
+}
+
+// loadTar 把 tar 中的目录与普通文件载入内存，符号链接、pax 全局头等其它条目被跳过
+func loadTar(r io.Reader, maxFileSize int64) (*memFS, error) {
+	fsys := newMemFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys.addDir(hdr.Name, hdr.ModTime)
+		case tar.TypeReg:
+			if tooLarge(hdr.Size, maxFileSize) {
+				fsys.addFile(hdr.Name, nil, hdr.Size, hdr.ModTime)
+				continue
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			fsys.addFile(hdr.Name, data, hdr.Size, hdr.ModTime)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var errTooLarge = errors.New("文件过大，未载入内容")
+
+// memFS 载入内存的只读文件树，实现 fs.FS 与 fs.ReadDirFS
+type memFS struct {
+	root *memNode
+}
+
+// memNode 文件或目录，同时实现 fs.FileInfo
+type memNode struct {
+	name     string
+	dir      bool
+	data     []byte
+	size     int64
+	modTime  time.Time
+	children map[string]*memNode
+}
+
+func newMemFS() *memFS {
+	return &memFS{root: &memNode{name: ".", dir: true, children: make(map[string]*memNode)}}
+}
+
+// cleanName 把压缩包中的条目名转换为 fs.FS 路径，去掉开头的 / 与 ./，含 .. 的条目不予载入
+func cleanName(name string) (string, bool) {
+	name = strings.TrimLeft(strings.ReplaceAll(name, `\`, "/"), "/")
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, "/../") {
+		return "", false
+	}
+	return name, true
+}
+
+// mkdirAll 创建 dir 及其上级目录，路径上已有同名文件时返回 nil
+func (m *memFS) mkdirAll(dir string) *memNode {
+	node := m.root
+	if dir == "." {
+		return node
+	}
+	for _, part := range strings.Split(dir, "/") {
+		child, ok := node.children[part]
+		if !ok {
+			child = &memNode{name: part, dir: true, modTime: node.modTime, children: make(map[string]*memNode)}
+			node.children[part] = child
+		}
+		if !child.dir {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+func (m *memFS) addDir(name string, modTime time.Time) {
+	name, ok := cleanName(name)
+	if !ok {
+		return
+	}
+	if node := m.mkdirAll(name); node != nil {
+		node.modTime = modTime
+	}
+}
+
+// tooLarge 与扫描目录时的 max_file_size 规则相同：超过 limit 的文件只保留目录项、不载入内容，
+// 扫描时按过大跳过；limit 不大于 0 时不限制
+func tooLarge(size, limit int64) bool {
+	return limit > 0 && size > limit
+}
+
+// addFile 添加文件，data 为 nil 且 size 大于 0 时表示内容过大未载入
+func (m *memFS) addFile(name string, data []byte, size int64, modTime time.Time) {
+	name, ok := cleanName(name)
+	if !ok {
+		return
+	}
+	parent := m.mkdirAll(path.Dir(name))
+	if parent == nil {
+		return
+	}
+	base := path.Base(name)
+	if existing, ok := parent.children[base]; ok && existing.dir {
+		return
+	}
+	parent.children[base] = &memNode{name: base, data: data, size: size, modTime: modTime}
+}
+
+// unwrapSingleDir 根目录下只有一个目录时以该目录为根
+func (m *memFS) unwrapSingleDir() *memFS {
+	if len(m.root.children) != 1 {
+		return m
+	}
+	for _, child := range m.root.children {
+		if child.dir {
+			return &memFS{root: &memNode{name: ".", dir: true, modTime: child.modTime, children: child.children}}
+		}
+	}
+	return m
+}
+
+func (m *memFS) lookup(op, name string) (*memNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node := m.root
+	if name == "." {
+		return node, nil
+	}
+	for _, part := range strings.Split(name, "/") {
+		if !node.dir {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		child, ok := node.children[part]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// Open 实现 fs.FS
+func (m *memFS) Open(name string) (fs.File, error) {
+	node, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.dir {
+		return &memDir{node: node, entries: node.entries()}, nil
+	}
+	return &memFile{node: node, path: name, reader: bytes.NewReader(node.data)}, nil
+}
+
+// ReadDir 实现 fs.ReadDirFS，按名称排序
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("不是目录")}
+	}
+	return node.entries(), nil
+}
+
+func (n *memNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+func (n *memNode) Name() string       { return n.name }
+func (n *memNode) Size() int64        { return n.size }
+func (n *memNode) ModTime() time.Time { return n.modTime }
+func (n *memNode) IsDir() bool        { return n.dir }
+func (n *memNode) Sys() any           { return nil }
+
+func (n *memNode) Mode() fs.FileMode {
+	if n.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// memFile 打开的文件
+type memFile struct {
+	node   *memNode
+	path   string
+	reader *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.node, nil }
+func (f *memFile) Close() error               { return nil }
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.node.data == nil && f.node.size > 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.path, Err: errTooLarge}
+	}
+	return f.reader.Read(p)
+}
+
+// memDir 打开的目录，实现 fs.ReadDirFile
+type memDir struct {
+	node    *memNode
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.node, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errors.New("是目录")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
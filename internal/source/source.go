@@ -0,0 +1,131 @@
+// Package source 把压缩包与 git 版本转换为 fs.FS，作为扫描与目录树的输入
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"printcode2llm/internal/gitrepo"
+)
+
+// Source 一个非本地目录的输入
+type Source struct {
+	// FS 输入的内容，根目录即项目根目录
+	FS fs.FS
+	// Root 显示用的路径，最后一段作为项目名称，例如 /tmp/repo.zip、/src/app@v1.2.0
+	Root string
+}
+
+// archiveExts 支持的压缩包扩展名，按从长到短的顺序匹配
+var archiveExts = []string{".tar.gz", ".tar.bz2", ".tgz", ".tbz2", ".tar", ".zip"}
+
+// IsArchive 路径是否是支持的压缩包文件
+func IsArchive(path string) bool {
+	if archiveExt(path) == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func archiveExt(path string) string {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// OpenArchive 读取 zip、tar、tar.gz 或 tar.bz2 压缩包；
+// 压缩包只有一个顶层目录时（例如 GitHub 下载的 repo-main/）以该目录为项目根目录。
+// maxFileSize 即配置中的 max_file_size，超过的文件不载入内容、扫描时按过大跳过，不大于 0 时不限制
+func OpenArchive(path string, maxFileSize int64) (*Source, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+
+	var fsys *memFS
+	switch archiveExt(absPath) {
+	case ".zip":
+		fsys, err = loadZip(absPath, maxFileSize)
+	case ".tar":
+		fsys, err = loadTarFile(absPath, "", maxFileSize)
+	case ".tar.gz", ".tgz":
+		fsys, err = loadTarFile(absPath, "gzip", maxFileSize)
+	case ".tar.bz2", ".tbz2":
+		fsys, err = loadTarFile(absPath, "bzip2", maxFileSize)
+	default:
+		return nil, fmt.Errorf("不支持的压缩包格式: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取压缩包 %s 失败: %w", path, err)
+	}
+
+	return &Source{FS: fsys.unwrapSingleDir(), Root: absPath}, nil
+}
+
+// OpenRevision 通过 git archive 读取 dir 在版本 rev 中的内容，不修改工作区；maxFileSize 与 OpenArchive 相同
+func OpenRevision(dir, rev string, maxFileSize int64) (*Source, error) {
+	repo, err := gitrepo.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	fsys, absDir, err := openTree(repo, dir, rev, "版本 "+rev, maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// 分支名中的 / 会让项目名称只剩最后一段
+	return &Source{FS: fsys, Root: absDir + "@" + strings.ReplaceAll(rev, "/", "-")}, nil
+}
+
+// OpenSnapshot 读取变更范围内变更后的内容：--commit 为该提交中的版本，--staged 为暂存区中的版本，
+// 而不是工作区中可能已被再次修改或删除的文件；其他范围读取工作区，返回 nil
+func OpenSnapshot(dir string, spec gitrepo.Spec, maxFileSize int64) (*Source, error) {
+	repo, err := gitrepo.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.Snapshot(spec)
+	if err != nil || tree == "" {
+		return nil, err
+	}
+	fsys, absDir, err := openTree(repo, dir, tree, spec.Describe(), maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{FS: fsys, Root: absDir}, nil
+}
+
+// openTree 导出 dir 对应的子目录在 rev 中的内容，同时返回 dir 的绝对路径；label 用于错误信息
+func openTree(repo *gitrepo.Repo, dir, rev, label string, maxFileSize int64) (*memFS, string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+	resolved := absDir
+	if r, err := filepath.EvalSymlinks(absDir); err == nil {
+		resolved = r
+	}
+	sub, err := filepath.Rel(repo.Root, resolved)
+	if err != nil || strings.HasPrefix(sub, "..") {
+		return nil, "", fmt.Errorf("%s 不在仓库 %s 中", dir, repo.Root)
+	}
+
+	data, err := repo.Archive(rev, filepath.ToSlash(sub))
+	if err != nil {
+		return nil, "", err
+	}
+	fsys, err := loadTar(bytes.NewReader(data), maxFileSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取%s失败: %w", label, err)
+	}
+	return fsys, absDir, nil
+}
@@ -0,0 +1,172 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"printcode2llm/internal/config"
+	"printcode2llm/internal/scanner"
+)
+
+// archiveFiles 压缩包中的文件，big.go 超过测试使用的 64 字节上限
+var archiveFiles = map[string]string{
+	"repo-main/small.go": "package main\n",
+	"repo-main/big.go":   "package main\n\n" + strings.Repeat("// padding\n", 20),
+}
+
+const testLimit = 64
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range archiveFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range archiveFiles {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanSource 按 max_file_size 扫描，返回载入的文件与跳过的文件
+func scanSource(t *testing.T, src *Source, maxFileSize int64) ([]string, []string) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.MaxFileSize = maxFileSize
+	var skipped []string
+	files, err := scanner.ScanFS(src.FS, src.Root, cfg, scanner.Options{
+		OnSkip: func(f scanner.SkippedFile) { skipped = append(skipped, f.RelPath) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded []string
+	for _, f := range files {
+		loaded = append(loaded, f.RelPath)
+	}
+	return loaded, skipped
+}
+
+func TestOpenArchiveMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string]func(*testing.T, string){
+		"repo.zip":    writeZip,
+		"repo.tar.gz": writeTarGz,
+	}
+	for name, write := range archives {
+		path := filepath.Join(dir, name)
+		write(t, path)
+
+		src, err := OpenArchive(path, testLimit)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// 过大的文件保留目录项与大小，读取时报错
+		info, err := fs.Stat(src.FS, "big.go")
+		if err != nil || info.Size() != int64(len(archiveFiles["repo-main/big.go"])) {
+			t.Errorf("%s: big.go stat = %v, %v", name, info, err)
+		}
+		if _, err := fs.ReadFile(src.FS, "big.go"); !errors.Is(err, errTooLarge) {
+			t.Errorf("%s: 读取过大的文件 err = %v", name, err)
+		}
+		loaded, skipped := scanSource(t, src, testLimit)
+		if strings.Join(loaded, ",") != "small.go" || strings.Join(skipped, ",") != "big.go" {
+			t.Errorf("%s: loaded=%v skipped=%v", name, loaded, skipped)
+		}
+
+		// -1 表示不限制，与扫描目录相同
+		src, err = OpenArchive(path, -1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if data, err := fs.ReadFile(src.FS, "big.go"); err != nil || string(data) != archiveFiles["repo-main/big.go"] {
+			t.Errorf("%s: 不限制时 big.go = %q, %v", name, data, err)
+		}
+		loaded, skipped = scanSource(t, src, -1)
+		if strings.Join(loaded, ",") != "big.go,small.go" || len(skipped) != 0 {
+			t.Errorf("%s: 不限制时 loaded=%v skipped=%v", name, loaded, skipped)
+		}
+	}
+}
+
+func TestOpenRevisionMaxFileSize(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有 git")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for name, content := range archiveFiles {
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	src, err := OpenRevision(dir, "HEAD", testLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, skipped := scanSource(t, src, testLimit)
+	if strings.Join(loaded, ",") != "small.go" || strings.Join(skipped, ",") != "big.go" {
+		t.Errorf("loaded=%v skipped=%v", loaded, skipped)
+	}
+
+	src, err = OpenRevision(dir, "HEAD", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, skipped = scanSource(t, src, -1)
+	if strings.Join(loaded, ",") != "big.go,small.go" || len(skipped) != 0 {
+		t.Errorf("不限制时 loaded=%v skipped=%v", loaded, skipped)
+	}
+}
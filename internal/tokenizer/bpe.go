@@ -0,0 +1,126 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// bpeTokenizer 基于 tiktoken 格式词表的字节级 BPE 分词器
+type bpeTokenizer struct {
+	name      string
+	ranks     map[string]int
+	caseAware bool
+
+	mu    sync.RWMutex
+	cache map[string]int
+}
+
+// parseTiktoken 解析 tiktoken 词表，每行为 "base64(token) rank"
+func parseTiktoken(name string, data []byte, caseAware bool) (*bpeTokenizer, error) {
+	ranks := make(map[string]int)
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := bytes.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("第 %d 行格式错误", lineNum)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行 base64 解码失败: %w", lineNum, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行序号错误: %w", lineNum, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) < 256 {
+		return nil, fmt.Errorf("词表过小 (%d 项)", len(ranks))
+	}
+
+	return &bpeTokenizer{
+		name:      name,
+		ranks:     ranks,
+		caseAware: caseAware,
+		cache:     make(map[string]int),
+	}, nil
+}
+
+func (b *bpeTokenizer) Name() string {
+	return b.name
+}
+
+func (b *bpeTokenizer) Count(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text, b.caseAware) {
+		total += b.countPiece(piece)
+	}
+	return total
+}
+
+func (b *bpeTokenizer) countPiece(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	b.mu.RLock()
+	n, ok := b.cache[piece]
+	b.mu.RUnlock()
+	if ok {
+		return n
+	}
+
+	n = b.merge([]byte(piece))
+
+	b.mu.Lock()
+	if len(b.cache) > 200000 {
+		b.cache = make(map[string]int)
+	}
+	b.cache[piece] = n
+	b.mu.Unlock()
+
+	return n
+}
+
+// merge 反复合并序号最小的相邻片段，直到无法继续合并，返回片段数
+func (b *bpeTokenizer) merge(piece []byte) int {
+	// bounds[i] 为第 i 个片段的起始位置，最后一个元素为 len(piece)
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best := -1
+		bestRank := 0
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := b.ranks[string(piece[bounds[i]:bounds[i+2]])]
+			if ok && (best < 0 || rank < bestRank) {
+				best = i
+				bestRank = rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	return len(bounds) - 1
+}
@@ -0,0 +1,161 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitPieces 按 cl100k/o200k 的预分词规则切分文本
+//
+// 原始规则使用了 Go 正则不支持的 (?!\S)，这里手工实现同等逻辑：
+//
+//	's|'t|'re|'ve|'m|'ll|'d | [^\r\n\p{L}\p{N}]?\p{L}+ | \p{N}{1,3}
+//	| ?[^\s\p{L}\p{N}]+[\r\n]* | \s*[\r\n]+ | \s+(?!\S) | \s+
+func splitPieces(text string, caseAware bool) []string {
+	var pieces []string
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		n := matchPiece(runes, i, caseAware)
+		if n <= 0 {
+			n = 1
+		}
+		pieces = append(pieces, string(runes[i:i+n]))
+		i += n
+	}
+
+	return pieces
+}
+
+func matchPiece(r []rune, i int, caseAware bool) int {
+	if n := matchContraction(r, i); n > 0 {
+		return n
+	}
+
+	if n := matchWord(r, i, caseAware); n > 0 {
+		return n
+	}
+
+	if unicode.IsNumber(r[i]) {
+		n := 0
+		for i+n < len(r) && n < 3 && unicode.IsNumber(r[i+n]) {
+			n++
+		}
+		return n
+	}
+
+	// ?[^\s\p{L}\p{N}]+[\r\n]*
+	j := i
+	if r[j] == ' ' && j+1 < len(r) && isPunct(r[j+1]) {
+		j++
+	}
+	if isPunct(r[j]) {
+		for j < len(r) && isPunct(r[j]) {
+			j++
+		}
+		for j < len(r) && (r[j] == '\r' || r[j] == '\n' || (caseAware && r[j] == '/')) {
+			j++
+		}
+		return j - i
+	}
+
+	if !unicode.IsSpace(r[i]) {
+		return 1
+	}
+
+	// \s*[\r\n]+
+	end := i
+	lastNewline := -1
+	for end < len(r) && unicode.IsSpace(r[end]) {
+		if r[end] == '\r' || r[end] == '\n' {
+			lastNewline = end
+		}
+		end++
+	}
+	if lastNewline >= 0 {
+		return lastNewline + 1 - i
+	}
+
+	// \s+(?!\S)：后面跟着非空白字符时，把最后一个空白留给下一个片段
+	if end < len(r) && end-i > 1 {
+		return end - 1 - i
+	}
+	return end - i
+}
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+func matchContraction(r []rune, i int) int {
+	if r[i] != '\'' {
+		return 0
+	}
+	for _, c := range contractions {
+		if i+1+len(c) > len(r) {
+			continue
+		}
+		ok := true
+		for k, ch := range c {
+			if unicode.ToLower(r[i+1+k]) != ch {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return 1 + len(c)
+		}
+	}
+	return 0
+}
+
+// matchWord 匹配 [^\r\n\p{L}\p{N}]?\p{L}+，caseAware 时按 o200k 规则在大小写切换处断开
+func matchWord(r []rune, i int, caseAware bool) int {
+	j := i
+	if !unicode.IsLetter(r[j]) {
+		if r[j] == '\r' || r[j] == '\n' || unicode.IsNumber(r[j]) {
+			return 0
+		}
+		if j+1 >= len(r) || !unicode.IsLetter(r[j+1]) {
+			return 0
+		}
+		j++
+	}
+
+	start := j
+	if !caseAware {
+		for j < len(r) && isWordRune(r[j]) {
+			j++
+		}
+		return j - i
+	}
+
+	// 大写字母串后接小写字母串，例如 "fooBar" 切分为 "foo" 和 "Bar"
+	for j < len(r) && isWordRune(r[j]) && !unicode.IsLower(r[j]) {
+		j++
+	}
+	for j < len(r) && isWordRune(r[j]) && !unicode.IsUpper(r[j]) {
+		j++
+	}
+	if j == start {
+		return 0
+	}
+	if j < len(r) {
+		j += matchContraction(r, j)
+	}
+	return j - i
+}
+
+func isWordRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.Is(unicode.M, ch)
+}
+
+func isPunct(ch rune) bool {
+	return !unicode.IsSpace(ch) && !unicode.IsLetter(ch) && !unicode.IsNumber(ch)
+}
+
+// isWide 判断是否是中日韩等通常一个字符对应至少一个 token 的字符
+func isWide(ch rune) bool {
+	return ch >= utf8.RuneSelf && (unicode.Is(unicode.Han, ch) ||
+		unicode.Is(unicode.Hiragana, ch) ||
+		unicode.Is(unicode.Katakana, ch) ||
+		unicode.Is(unicode.Hangul, ch))
+}
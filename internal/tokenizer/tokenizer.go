@@ -0,0 +1,136 @@
+package tokenizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"printcode2llm/configs"
+)
+
+const (
+	Heuristic  = "heuristic"
+	CL100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// Tokenizer 计算文本的 token 数
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+var (
+	loadedMu sync.Mutex
+	loaded   = make(map[string]Tokenizer)
+)
+
+// Load 按名称加载分词器，BPE 词表依次从 file、内嵌词表、用户缓存目录查找
+func Load(name, file string) (Tokenizer, error) {
+	if name == "" {
+		name = Heuristic
+	}
+
+	key := name + "|" + file
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	if tok, ok := loaded[key]; ok {
+		return tok, nil
+	}
+
+	var tok Tokenizer
+	switch name {
+	case Heuristic:
+		tok = &heuristicTokenizer{}
+	case CL100kBase, O200kBase:
+		data, source, err := findTable(name, file)
+		if err != nil {
+			return nil, err
+		}
+		bpe, err := parseTiktoken(name, data, name == O200kBase)
+		if err != nil {
+			return nil, fmt.Errorf("解析词表 %s 失败: %w", source, err)
+		}
+		tok = bpe
+	default:
+		return nil, fmt.Errorf("未知的分词器: %s (可选: %s, %s, %s)", name, Heuristic, CL100kBase, O200kBase)
+	}
+
+	loaded[key] = tok
+	return tok, nil
+}
+
+// TableDir 用户缓存目录中存放词表的位置
+func TableDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ptlm", "tokenizers")
+}
+
+func findTable(name, file string) ([]byte, string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, file, fmt.Errorf("读取词表失败: %w", err)
+		}
+		return data, file, nil
+	}
+
+	if data, err := configs.TokenizerTable(name); err == nil {
+		return data, "embedded:" + name, nil
+	}
+
+	if dir := TableDir(); dir != "" {
+		path := filepath.Join(dir, name+".tiktoken")
+		if data, err := os.ReadFile(path); err == nil {
+			return data, path, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("未找到 %s 词表，请通过 tokenizer_file 指定，或放置到 %s",
+		name, filepath.Join(TableDir(), name+".tiktoken"))
+}
+
+// heuristicTokenizer 无词表时的估算：英文约 4 字符一个 token，中日韩字符约一字一个 token
+type heuristicTokenizer struct{}
+
+func (h *heuristicTokenizer) Name() string {
+	return Heuristic
+}
+
+func (h *heuristicTokenizer) Count(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text, false) {
+		narrow := 0
+		for _, ch := range piece {
+			if isWide(ch) {
+				total++
+			} else if ch >= 0x80 {
+				total++
+			} else {
+				narrow++
+			}
+		}
+		if narrow > 0 {
+			total += (narrow + 3) / 4
+		}
+	}
+	return total
+}
+
+// Estimated 分词器给出的是否只是估算值，没有 BPE 词表时 token 数与模型的实际计数存在偏差
+func Estimated(tok Tokenizer) bool {
+	return tok.Name() == Heuristic
+}
+
+// Describe 用于头部信息展示的分词器描述
+func Describe(tok Tokenizer) string {
+	if tok.Name() == Heuristic {
+		return "估算"
+	}
+	return strings.TrimSuffix(tok.Name(), "_base")
+}
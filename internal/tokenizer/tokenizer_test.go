@@ -0,0 +1,135 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureMerges 小词表在 256 个单字节之后的合并项，序号越小越先合并
+var fixtureMerges = []string{"he", "ll", "hell", "hello", "12", " w", "or"}
+
+// writeFixtureTable 写出 tiktoken 格式的小词表：全部单字节加上 fixtureMerges
+func writeFixtureTable(t *testing.T) string {
+	t.Helper()
+	var builder strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&builder, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range fixtureMerges {
+		fmt.Fprintf(&builder, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.tiktoken")
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPECountGolden(t *testing.T) {
+	tok, err := Load(CL100kBase, writeFixtureTable(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Estimated(tok) || Describe(tok) != "cl100k" {
+		t.Errorf("提供词表时不是估算值: %v %s", Estimated(tok), Describe(tok))
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},        // 整个片段在词表中
+		{" hello", 2},       // " " + hello，按序号依次合并 he、ll、hell、hello
+		{"hello world", 5},  // hello + " w" or l d
+		{"123456", 5},       // 数字按 3 位一组: 12 3 / 4 5 6
+		{"don't", 5},        // d o n + ' t
+		{"  x", 3},          // 多余的空白单独成片: " " + " " x
+		{"中文", 6},           // 每个汉字 3 个字节，没有合并项
+		{"a\r\n\r\nb", 6},   // 换行连续成片
+		{"hello\nhello", 3}, // hello + \n + hello
+	}
+
+	for _, tt := range tests {
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text      string
+		caseAware bool
+		want      []string
+	}{
+		{"hello world", false, []string{"hello", " world"}},
+		{"HelloWorld", false, []string{"HelloWorld"}},
+		{"HelloWorld", true, []string{"Hello", "World"}},
+		{"x := 1234", false, []string{"x", " :=", " ", "123", "4"}},
+		{"it's", false, []string{"it", "'s"}},
+		{"a  b", false, []string{"a", " ", " b"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitPieces(tt.text, tt.caseAware); fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+			t.Errorf("splitPieces(%q, %v) = %q, want %q", tt.text, tt.caseAware, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicCount(t *testing.T) {
+	tok, err := Load(Heuristic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Estimated(tok) || Describe(tok) != "估算" {
+		t.Errorf("heuristic 应标为估算: %v %s", Estimated(tok), Describe(tok))
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcd efgh", 3}, // abcd + " efgh"，每片不足 4 个字符按 4 计
+		{"中文", 2},
+		{"é", 1},
+	}
+	for _, tt := range tests {
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.tiktoken")
+	if err := os.WriteFile(small, []byte("YQ== 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.tiktoken")
+	if err := os.WriteFile(broken, []byte("!!! 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, file, want string
+	}{
+		{"unknown", "", "未知的分词器"},
+		{CL100kBase, filepath.Join(dir, "missing.tiktoken"), "读取词表失败"},
+		{CL100kBase, small, "词表过小"},
+		{O200kBase, broken, "base64"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(tt.name, tt.file); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s, %s) err = %v, want %q", tt.name, tt.file, err, tt.want)
+		}
+	}
+}
@@ -19,6 +19,6 @@ func PrintBanner() {
   ║                                                ║
   ╚════════════════════════════════════════════════╝
 `
-	colorCyan.Print(banner)
-	fmt.Println()
+	colorCyan.Fprint(out, banner)
+	fmt.Fprintln(out)
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/fatih/color"
@@ -16,10 +17,28 @@ var (
 	colorBlue   = color.New(color.FgBlue, color.Bold)
 )
 
+// out 界面信息的输出位置，文档输出到标准输出时切换为标准错误
+var out io.Writer = color.Output
+
+// SetOutput 设置界面信息的输出位置
+func SetOutput(w io.Writer) {
+	out = w
+}
+
+// Writer 当前界面信息的输出位置，供交互提示等直接输出使用
+func Writer() io.Writer {
+	return out
+}
+
+// PrintBlank 输出空行
+func PrintBlank() {
+	fmt.Fprintln(out)
+}
+
 func PrintHeader(text string) {
-	fmt.Println()
+	fmt.Fprintln(out)
 	line := strings.Repeat("─", 50)
-	colorCyan.Println("┌" + line + "┐")
+	colorCyan.Fprintln(out, "┌"+line+"┐")
 
 	textLen := len([]rune(text))
 	padding := (50 - textLen) / 2
@@ -27,38 +46,38 @@ func PrintHeader(text string) {
 		padding = 0
 	}
 
-	colorCyan.Print("│")
-	fmt.Print(strings.Repeat(" ", padding))
-	colorCyan.Print(text)
-	fmt.Print(strings.Repeat(" ", 50-padding-textLen*2))
-	colorCyan.Println("│")
+	colorCyan.Fprint(out, "│")
+	fmt.Fprint(out, strings.Repeat(" ", padding))
+	colorCyan.Fprint(out, text)
+	fmt.Fprint(out, strings.Repeat(" ", 50-padding-textLen*2))
+	colorCyan.Fprintln(out, "│")
 
-	colorCyan.Println("└" + line + "┘")
-	fmt.Println()
+	colorCyan.Fprintln(out, "└"+line+"┘")
+	fmt.Fprintln(out)
 }
 
 func PrintSection(format string, args ...interface{}) {
-	colorBlue.Printf("▶ "+format+"\n", args...)
+	colorBlue.Fprintf(out, "▶ "+format+"\n", args...)
 }
 
 func PrintInfo(format string, args ...interface{}) {
-	colorCyan.Printf("  ℹ "+format+"\n", args...)
+	colorCyan.Fprintf(out, "  ℹ "+format+"\n", args...)
 }
 
 func PrintSuccess(format string, args ...interface{}) {
-	colorGreen.Printf("  ✓ "+format+"\n", args...)
+	colorGreen.Fprintf(out, "  ✓ "+format+"\n", args...)
 }
 
 func PrintWarning(format string, args ...interface{}) {
-	colorYellow.Printf("  ⚠ "+format+"\n", args...)
+	colorYellow.Fprintf(out, "  ⚠ "+format+"\n", args...)
 }
 
 func PrintError(format string, args ...interface{}) {
-	colorRed.Printf("  ✗ "+format+"\n", args...)
+	colorRed.Fprintf(out, "  ✗ "+format+"\n", args...)
 }
 
 func PrintStep(format string, args ...interface{}) {
-	colorWhite.Printf("  → "+format+"\n", args...)
+	colorWhite.Fprintf(out, "  → "+format+"\n", args...)
 }
 
 func FormatNumber(n int) string {
@@ -89,4 +108,22 @@ func FormatBytes(size int64) string {
 		exp++
 	}
 	return fmt.Sprintf("%.2f %cB", float64(size)/float64(div), "KMGTPE"[exp])
-}
\ No newline at end of file
+}
+
+// PrintDiff 按行着色输出 unified diff
+func PrintDiff(diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			colorWhite.Fprintln(out, "    "+line)
+		case strings.HasPrefix(line, "@@"):
+			colorCyan.Fprintln(out, "    "+line)
+		case strings.HasPrefix(line, "+"):
+			colorGreen.Fprintln(out, "    "+line)
+		case strings.HasPrefix(line, "-"):
+			colorRed.Fprintln(out, "    "+line)
+		default:
+			fmt.Fprintln(out, "    "+line)
+		}
+	}
+}
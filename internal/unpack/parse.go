@@ -0,0 +1,158 @@
+package unpack
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"printcode2llm/internal/generator"
+)
+
+// Chunk 从生成文档中解析出的一个文件片段
+type Chunk struct {
+	Project string
+	Title   *generator.ChunkTitle
+	Lines   []string
+	Source  string
+}
+
+// Document 一个生成文档的解析结果
+type Document struct {
+	Path      string
+	PartNum   int
+	TotalPart int
+	Chunks    []*Chunk
+}
+
+var (
+	projectHeadingPattern = regexp.MustCompile(`^# (.+?)(?: \(第 \d+ 部分\))?$`)
+	partCommentPattern    = regexp.MustCompile(`^\s*Part: (\d+) of (\d+)\s*$`)
+)
+
+// ParseFile 解析生成的 Markdown 文件
+func ParseFile(path string) (*Document, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc, err := Parse(file, path)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Parse 解析生成文档，识别 "### N. path" 标题及其后的代码块
+func Parse(r io.Reader, source string) (*Document, error) {
+	doc := &Document{Path: source}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	s.Split(scanLinesKeepCR)
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	project := ""
+	inComment := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimRight(line, "\r")
+
+		// 多部分输出时文件开头的注释中记录了分段序号
+		if strings.HasPrefix(trimmed, "<!--") {
+			inComment = true
+		}
+		if inComment {
+			if m := partCommentPattern.FindStringSubmatch(trimmed); m != nil {
+				doc.PartNum, _ = strconv.Atoi(m[1])
+				doc.TotalPart, _ = strconv.Atoi(m[2])
+			}
+			if strings.Contains(trimmed, "-->") {
+				inComment = false
+			}
+			continue
+		}
+
+		if m := projectHeadingPattern.FindStringSubmatch(trimmed); m != nil {
+			project = m[1]
+			continue
+		}
+
+		if size, _, ok := generator.ParseFenceOpen(trimmed); ok {
+			i = skipFence(lines, i+1, size)
+			continue
+		}
+
+		title, ok := generator.ParseChunkTitle(trimmed)
+		if !ok {
+			continue
+		}
+
+		// 标题之后是空行、说明行和代码块
+		j := i + 1
+		for j < len(lines) && (strings.TrimSpace(lines[j]) == "" || generator.IsChunkNote(lines[j])) {
+			j++
+		}
+		if j >= len(lines) {
+			break
+		}
+		size, _, ok := generator.ParseFenceOpen(lines[j])
+		if !ok {
+			continue
+		}
+
+		end := skipFence(lines, j+1, size)
+		if end >= len(lines) {
+			return nil, fmt.Errorf("第 %d 行的代码块没有结束", j+1)
+		}
+
+		// 开启 line_numbers 生成的文档每行带有原文行号
+		body, _ := generator.StripLineNumbers(lines[j+1 : end])
+		doc.Chunks = append(doc.Chunks, &Chunk{
+			Project: project,
+			Title:   title,
+			Lines:   append([]string{}, body...),
+			Source:  fmt.Sprintf("%s:%d", source, i+1),
+		})
+		i = end
+	}
+
+	return doc, nil
+}
+
+// skipFence 返回结束围栏所在行，找不到时返回 len(lines)
+func skipFence(lines []string, from, size int) int {
+	for k := from; k < len(lines); k++ {
+		if generator.IsFenceClose(lines[k], size) {
+			return k
+		}
+	}
+	return len(lines)
+}
+
+// scanLinesKeepCR 按 \n 分行，保留行尾的 \r，以便原样还原 CRLF 文件
+func scanLinesKeepCR(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
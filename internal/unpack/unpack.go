@@ -0,0 +1,296 @@
+package unpack
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File 拼接还原后的文件
+type File struct {
+	Project string
+	Path    string
+	Content string
+}
+
+// Assemble 将多个文档中的片段按行号拼接为完整文件，diff、骨架与节选片段会被跳过
+func Assemble(docs []*Document) ([]*File, []string, error) {
+	var warnings []string
+	warnings = append(warnings, missingParts(docs)...)
+
+	type fileKey struct {
+		project string
+		path    string
+	}
+	groups := make(map[fileKey][]*Chunk)
+	var keys []fileKey
+	projects := make(map[string]bool)
+	skeletons := 0
+	truncated := 0
+
+	for _, doc := range docs {
+		for _, chunk := range doc.Chunks {
+			if chunk.Title.Diff {
+				continue
+			}
+			if chunk.Title.Skeleton {
+				if chunk.Title.StartLine <= 1 {
+					skeletons++
+				}
+				continue
+			}
+			if chunk.Title.Truncated {
+				if chunk.Title.StartLine <= 1 {
+					truncated++
+				}
+				continue
+			}
+			key := fileKey{project: chunk.Project, path: chunk.Title.Path}
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], chunk)
+			projects[chunk.Project] = true
+		}
+	}
+
+	if skeletons > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d 个文件只有骨架，无法还原，已跳过", skeletons))
+	}
+	if truncated > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d 个文件只有节选，无法还原，已跳过", truncated))
+	}
+
+	var files []*File
+	for _, key := range keys {
+		content, err := Stitch(groups[key])
+		if err != nil {
+			return nil, warnings, fmt.Errorf("%s: %w", key.path, err)
+		}
+
+		path := key.path
+		// 多个项目时按项目名分目录，避免同名文件互相覆盖
+		if len(projects) > 1 && key.project != "" {
+			path = key.project + "/" + path
+		}
+		files = append(files, &File{Project: key.project, Path: path, Content: content})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, warnings, nil
+}
+
+// Stitch 按起始行排序并拼接同一文件的片段，检查行号是否连续
+func Stitch(chunks []*Chunk) (string, error) {
+	if len(chunks) == 1 && chunks[0].Title.StartLine == 0 {
+		return strings.Join(chunks[0].Lines, "\n"), nil
+	}
+
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i].Title.StartLine < chunks[j].Title.StartLine
+	})
+
+	var lines []string
+	next := 1
+	for _, chunk := range chunks {
+		title := chunk.Title
+		if title.StartLine == 0 {
+			return "", fmt.Errorf("同一文件出现了多个完整片段 (%s)", chunk.Source)
+		}
+		// 同一部分被重复传入时跳过
+		if title.StartLine < next && title.EndLine < next {
+			continue
+		}
+		if title.StartLine != next {
+			return "", fmt.Errorf("缺少第 %d-%d 行，请检查是否传入了全部分段", next, title.StartLine-1)
+		}
+		if got := len(chunk.Lines); got != title.EndLine-title.StartLine+1 {
+			return "", fmt.Errorf("%s 声明 %d 行，实际 %d 行", chunk.Source, title.EndLine-title.StartLine+1, got)
+		}
+		lines = append(lines, chunk.Lines...)
+		next = title.EndLine + 1
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// missingParts 根据文档头部的分段信息检查是否缺少部分
+func missingParts(docs []*Document) []string {
+	total := 0
+	seen := make(map[int]bool)
+	for _, doc := range docs {
+		if doc.TotalPart > total {
+			total = doc.TotalPart
+		}
+		if doc.PartNum > 0 {
+			seen[doc.PartNum] = true
+		}
+	}
+
+	var warnings []string
+	for i := 1; i <= total; i++ {
+		if !seen[i] {
+			warnings = append(warnings, fmt.Sprintf("缺少第 %d/%d 部分，跨部分的文件可能不完整", i, total))
+		}
+	}
+	return warnings
+}
+
+// SafeJoin 拼接要写入的目标路径，拒绝绝对路径、跳出 root 的相对路径，
+// 以及经过 root 内已存在的符号链接（目录或文件本身）指向 root 之外的路径
+func SafeJoin(root, relPath string) (string, error) {
+	target, err := joinPath(root, relPath)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSymlinks(root, target); err != nil {
+		return "", fmt.Errorf("拒绝经符号链接跳出目标目录的路径: %s", relPath)
+	}
+	return target, nil
+}
+
+// joinPath 只按路径文本检查的拼接，用于读取
+func joinPath(root, relPath string) (string, error) {
+	if relPath == "" {
+		return "", fmt.Errorf("路径为空")
+	}
+	if strings.ContainsRune(relPath, 0) {
+		return "", fmt.Errorf("路径包含非法字符: %q", relPath)
+	}
+
+	native := filepath.FromSlash(relPath)
+	if filepath.IsAbs(native) || filepath.VolumeName(native) != "" || strings.HasPrefix(relPath, "/") || strings.HasPrefix(relPath, `\`) {
+		return "", fmt.Errorf("拒绝绝对路径: %s", relPath)
+	}
+	for _, part := range strings.FieldsFunc(relPath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("拒绝跳出目标目录的路径: %s", relPath)
+		}
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(absRoot, native)
+	if !within(absRoot, target) {
+		return "", fmt.Errorf("拒绝跳出目标目录的路径: %s", relPath)
+	}
+
+	return target, nil
+}
+
+// checkSymlinks 解析 target 已存在的最深一级路径中的符号链接，结果必须仍在 root 内；
+// root 尚不存在时（例如还原到新目录）没有可以跳出的链接
+func checkSymlinks(root, target string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return nil
+		}
+		existing = parent
+	}
+	// 指向不存在位置的链接同样拒绝，写入时会在链接指向处创建文件
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if real != realRoot && !within(realRoot, real) {
+		return fmt.Errorf("%s 指向 %s", existing, real)
+	}
+	return nil
+}
+
+// within target 是否在 root 之内（不含 root 本身）
+func within(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Write 将文件写入 outDir，force 为 false 时不覆盖已存在的文件
+func Write(files []*File, outDir string, force bool) error {
+	for _, file := range files {
+		target, err := SafeJoin(outDir, file.Path)
+		if err != nil {
+			return err
+		}
+
+		if !force {
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("文件已存在: %s (使用 --force 覆盖)", target)
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// Mismatch 校验时发现的差异
+type Mismatch struct {
+	Path   string
+	Reason string
+}
+
+// Verify 将还原结果与源目录逐个比对
+func Verify(files []*File, sourceDir string) ([]Mismatch, error) {
+	var mismatches []Mismatch
+
+	for _, file := range files {
+		// 只读取，源目录中的符号链接按原样比对
+		target, err := joinPath(sourceDir, file.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(target)
+		if err != nil {
+			mismatches = append(mismatches, Mismatch{Path: file.Path, Reason: "源文件不存在"})
+			continue
+		}
+
+		if !bytes.Equal(data, []byte(file.Content)) {
+			mismatches = append(mismatches, Mismatch{Path: file.Path, Reason: firstDifference(string(data), file.Content)})
+		}
+	}
+
+	return mismatches, nil
+}
+
+func firstDifference(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) && i < len(gotLines); i++ {
+		if wantLines[i] != gotLines[i] {
+			return fmt.Sprintf("第 %d 行不同", i+1)
+		}
+	}
+	return fmt.Sprintf("行数不同 (源文件 %d 行, 还原 %d 行)", len(wantLines), len(gotLines))
+}
@@ -0,0 +1,168 @@
+package unpack
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, text string) *Document {
+	t.Helper()
+	doc, err := Parse(strings.NewReader(text), "test.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestAssembleContinuationBlocks(t *testing.T) {
+	part1 := "<!--\nPart: 1 of 2\n-->\n# demo (第 1 部分)\n\n### 1. a.go (行 1-2)\n\n```go\npackage a\n\n```\n"
+	part2 := "<!--\nPart: 2 of 2\n-->\n# demo (第 2 部分)\n\n### 1. a.go (续: 行 3-4)\n\n```go\nfunc A() {}\n\n```\n\n### 2. b.txt\n\n````\n```\ninner\n```\n````\n"
+
+	// 分段传入顺序与还原结果无关
+	files, warnings, err := Assemble([]*Document{parse(t, part2), parse(t, part1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if len(files) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	if files[0].Path != "a.go" || files[0].Content != "package a\n\nfunc A() {}\n" {
+		t.Errorf("a.go = %q", files[0].Content)
+	}
+	if files[1].Path != "b.txt" || files[1].Content != "```\ninner\n```" {
+		t.Errorf("b.txt = %q", files[1].Content)
+	}
+
+	// 缺少后一部分时提示缺失的部分
+	_, warnings, err = Assemble([]*Document{parse(t, part1)})
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "缺少第 2/2 部分") {
+		t.Errorf("只有第一部分时 err=%v warnings=%v", err, warnings)
+	}
+
+	// 缺少中间的片段时报错
+	_, _, err = Assemble([]*Document{parse(t, part2)})
+	if err == nil || !strings.Contains(err.Error(), "缺少第 1-2 行") {
+		t.Errorf("只有第二部分时 err=%v", err)
+	}
+}
+
+func TestStitchRejectsWrongLineCount(t *testing.T) {
+	doc := parse(t, "### 1. a.go (行 1-3)\n\n```go\none\ntwo\n```\n")
+	if _, err := Stitch(doc.Chunks); err == nil || !strings.Contains(err.Error(), "声明 3 行，实际 2 行") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseStripsLineNumbers(t *testing.T) {
+	doc := parse(t, "### 1. a.go (行 9-11)\n\n```go\n 9| func A() {\n10|\n11| }\n```\n\n### 2. b.txt\n\n```\nx| 不是行号\n```\n")
+	if len(doc.Chunks) != 2 {
+		t.Fatalf("chunks = %d", len(doc.Chunks))
+	}
+	if got := strings.Join(doc.Chunks[0].Lines, "\n"); got != "func A() {\n\n}" {
+		t.Errorf("带行号的片段 = %q", got)
+	}
+	if got := strings.Join(doc.Chunks[1].Lines, "\n"); got != "x| 不是行号" {
+		t.Errorf("不带行号的片段被改动: %q", got)
+	}
+}
+
+func TestAssembleSkipsDiffAndSkeleton(t *testing.T) {
+	doc := parse(t, "### 1. a.go (diff)\n\n```diff\n+x\n```\n\n### 2. b.go (骨架)\n\n```go\nfunc B()\n```\n")
+	files, warnings, err := Assemble([]*Document{doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 || len(warnings) != 1 {
+		t.Errorf("files=%+v warnings=%v", files, warnings)
+	}
+}
+
+func TestSafeJoinRejectsEscapes(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"", "../x", "a/../../x", `..\x`, "/etc/passwd", `\x`, "a\x00b"} {
+		if _, err := SafeJoin(root, rel); err == nil {
+			t.Errorf("应拒绝 %q", rel)
+		}
+	}
+	for _, rel := range []string{"a.go", "dir/b.go", "new/dir/c.go", "a..b/c.go"} {
+		target, err := SafeJoin(root, rel)
+		if err != nil {
+			t.Errorf("%q: %v", rel, err)
+			continue
+		}
+		if target != filepath.Join(root, filepath.FromSlash(rel)) {
+			t.Errorf("%q -> %s", rel, target)
+		}
+	}
+
+	// 目标目录尚不存在时也可以写入
+	if _, err := SafeJoin(filepath.Join(root, "missing"), "a/b.go"); err != nil {
+		t.Errorf("目标目录不存在时: %v", err)
+	}
+}
+
+func TestSafeJoinRejectsSymlinkEscapes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要创建符号链接的权限")
+	}
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing.go"), filepath.Join(root, "dangling.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rel := range []string{"link/x.go", "link/new/x.go", "link", "dangling.go"} {
+		if _, err := SafeJoin(root, rel); err == nil {
+			t.Errorf("应拒绝经符号链接跳出的 %q", rel)
+		}
+	}
+	// 指向 root 内部的链接可以使用
+	if _, err := SafeJoin(root, "inside/x.go"); err != nil {
+		t.Errorf("inside/x.go: %v", err)
+	}
+
+	err := Write([]*File{{Path: "link/evil.go", Content: "x"}}, root, true)
+	if err == nil {
+		t.Fatal("Write 应拒绝经符号链接写到目标目录之外")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil.go")); !os.IsNotExist(err) {
+		t.Errorf("目标目录之外出现了文件: %v", err)
+	}
+}
+
+func TestWriteAndVerify(t *testing.T) {
+	root := t.TempDir()
+	files := []*File{{Path: "a/b.go", Content: "package b\n"}}
+	if err := Write(files, root, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(files, root, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("不应覆盖已存在的文件: %v", err)
+	}
+	if err := Write(files, root, true); err != nil {
+		t.Errorf("force 时覆盖: %v", err)
+	}
+
+	mismatches, err := Verify(append(files, &File{Path: "c.go", Content: "x"}), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Path != "c.go" {
+		t.Errorf("mismatches = %+v", mismatches)
+	}
+}
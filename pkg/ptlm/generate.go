@@ -0,0 +1,149 @@
+package ptlm
+
+import (
+	"context"
+	"io/fs"
+
+	"printcode2llm/internal/compress"
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/scanner"
+)
+
+// GenerateOptions 生成选项
+type GenerateOptions struct {
+	// Config 为 nil 时使用 DefaultConfig()
+	Config *Config
+	// Jobs 大于 0 时覆盖配置中的并发数
+	Jobs int
+	// Cache 使用磁盘缓存，CacheDir 为空时位于用户缓存目录
+	Cache    bool
+	CacheDir string
+	// Progress 非空时报告处理进度
+	Progress Progress
+	// FS 非空时从这里生成目录树，应与扫描时的 ScanOptions.FS 相同
+	FS fs.FS
+	// Skipped 扫描时跳过的文件，列在文档开头
+	Skipped []SkippedFile
+	// Excluded 扫描时排除的路径，配置开启 output.report_excluded 时列在文档末尾
+	Excluded []Exclusion
+}
+
+// Result 一个项目的生成结果
+type Result struct {
+	ProjectName string
+	ProjectPath string
+	Segments    []Segment
+
+	FileCount     int
+	CodeFiles     int
+	ConfigFiles   int
+	ChangedFiles  int
+	SkeletonFiles int
+	// TruncatedFiles 超过 max_file_lines、只输出节选的文件数
+	TruncatedFiles int
+	TotalLines     int
+	TotalChars     int
+	TotalTokens    int
+	// Tokenizer 计算 token 数所用的分词器
+	Tokenizer string
+	// Warnings 生成过程中的警告，例如压缩结果校验失败
+	Warnings []string
+	// TokensEstimated 没有 BPE 词表，token 数是按字符估算的近似值
+	TokensEstimated bool
+
+	cfg      *Config
+	internal *generator.Result
+}
+
+// Segment 一个分段，即发送给模型的一条消息
+type Segment struct {
+	PartNum    int
+	TotalParts int
+	Content    string
+	CharCount  int
+	TokenCount int
+	// FileRange 分段包含的文件序号范围，例如 "1-12"
+	FileRange string
+}
+
+// Generate 按配置压缩文件内容并切分为分段；dir 用于生成目录树与项目名称
+func Generate(ctx context.Context, dir string, files []*File, opts GenerateOptions) (*Result, error) {
+	cfg := orDefault(opts.Config)
+	if err := generator.ValidateFormat(cfg.Output.Format); err != nil {
+		return nil, err
+	}
+	if opts.Jobs > 0 {
+		copied := *cfg
+		copied.Jobs = opts.Jobs
+		cfg = &copied
+	}
+
+	fc, err := openCache(opts.Cache, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	genOpts := generator.Options{Cache: fc, Context: ctx, FS: opts.FS}
+	for _, s := range opts.Skipped {
+		genOpts.Skipped = append(genOpts.Skipped, scanner.SkippedFile(s))
+	}
+	for _, e := range opts.Excluded {
+		genOpts.Excluded = append(genOpts.Excluded, scanner.Exclusion(e))
+	}
+	if opts.Progress != nil {
+		genOpts.Progress = func(done, total int) {
+			opts.Progress(StageGenerate, done, total)
+		}
+	}
+
+	infos := make([]*scanner.FileInfo, len(files))
+	for i, file := range files {
+		infos[i] = toInfo(file)
+	}
+	internal, err := generator.GenerateWithOptions(dir, infos, cfg, genOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		ProjectName:    internal.ProjectName,
+		ProjectPath:    internal.ProjectPath,
+		FileCount:      internal.FileCount,
+		CodeFiles:      internal.CodeFiles,
+		ConfigFiles:    internal.ConfigFiles,
+		ChangedFiles:   internal.ChangedFiles,
+		SkeletonFiles:  internal.SkeletonFiles,
+		TruncatedFiles: internal.TruncatedFiles,
+		TotalLines:     internal.TotalLines,
+		TotalChars:     internal.TotalChars,
+		TotalTokens:    internal.TotalTokens,
+		Tokenizer:      internal.Tokenizer,
+		Warnings:       internal.Warnings,
+		cfg:            cfg,
+		internal:       internal,
+	}
+	result.TokensEstimated = internal.TokensEstimated
+	for _, seg := range internal.Segments {
+		result.Segments = append(result.Segments, Segment{
+			PartNum:    seg.PartNum,
+			TotalParts: seg.TotalPart,
+			Content:    seg.Content,
+			CharCount:  seg.CharCount,
+			TokenCount: seg.TokenCount,
+			FileRange:  seg.FileRange,
+		})
+	}
+	return result, nil
+}
+
+// CompressOptions 压缩选项
+type CompressOptions struct {
+	// Ultra 超级压缩，去掉更多空白与换行
+	Ultra bool
+}
+
+// Compress 压缩一段代码，language 为配置 language_map 中的语言名（例如 go、python）；
+// Go 代码的压缩结果无法重新解析时返回原文与错误
+func Compress(content, language string, opts CompressOptions) (string, error) {
+	return compress.CompressChecked(content, language, opts.Ultra)
+}
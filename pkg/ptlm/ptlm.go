@@ -0,0 +1,69 @@
+// Package ptlm 把 ptlm 的扫描、压缩、生成与写入作为库提供给其他 Go 程序使用
+//
+// 典型用法：
+//
+//	cfg, _ := ptlm.LoadConfig("", dir)
+//	files, _ := ptlm.Scan(ctx, dir, ptlm.ScanOptions{Config: cfg})
+//	result, _ := ptlm.Generate(ctx, dir, files, ptlm.GenerateOptions{Config: cfg})
+//	ptlm.WriteTo(os.Stdout, []*ptlm.Result{result}, ptlm.WriteOptions{})
+//
+// 包内没有全局状态，所有设置都通过参数传入，可以在多个 goroutine 中同时使用；
+// 函数不会向终端输出任何内容。
+package ptlm
+
+import (
+	"printcode2llm/configs"
+	"printcode2llm/internal/cache"
+	"printcode2llm/internal/config"
+)
+
+// Config 与 .ptlm.yaml 对应的配置
+type Config = configs.Config
+
+// Stage 进度回调所处的阶段
+type Stage string
+
+const (
+	// StageScan 读取与检测文件
+	StageScan Stage = "scan"
+	// StageGenerate 压缩、骨架提取与 token 计数
+	StageGenerate Stage = "generate"
+)
+
+// Progress 进度回调，同一阶段内按完成顺序串行调用
+type Progress func(stage Stage, done, total int)
+
+// DefaultConfig 内置的默认配置
+func DefaultConfig() *Config {
+	if cfg, err := configs.LoadEmbedded(); err == nil {
+		return cfg
+	}
+	return config.Default()
+}
+
+// LoadConfig 加载配置：path 非空时读取该文件，否则依次查找当前目录与 dirs 中的 .ptlm.yaml，
+// 都没有时使用内置默认值
+func LoadConfig(path string, dirs ...string) (*Config, error) {
+	return config.LoadFor(path, dirs)
+}
+
+// openCache 按选项打开磁盘缓存，dir 为空时使用默认位置
+func openCache(enabled bool, dir string) (*cache.Cache, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if dir == "" {
+		var err error
+		if dir, err = cache.Dir(); err != nil {
+			return nil, err
+		}
+	}
+	return cache.Open(dir)
+}
+
+func orDefault(cfg *Config) *Config {
+	if cfg == nil {
+		return DefaultConfig()
+	}
+	return cfg
+}
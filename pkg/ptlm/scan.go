@@ -0,0 +1,173 @@
+package ptlm
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+
+	"printcode2llm/internal/redact"
+	"printcode2llm/internal/scanner"
+)
+
+// File 扫描得到的一个文本文件
+type File struct {
+	// Path 绝对路径，RelPath 相对扫描目录、以 / 分隔
+	Path     string
+	RelPath  string
+	Language string
+	Content  string
+	// IsCode 是否按代码处理（压缩、骨架），配置与文档类文件为 false
+	IsCode    bool
+	LineCount int
+	Size      int64
+	Encoding  string
+
+	// ChangeStatus 变更状态 (A/M/D/R)，Diff 为对应的 unified diff，均可为空
+	ChangeStatus string
+	Diff         string
+
+	// Redactions 生成前被遮盖的敏感信息
+	Redactions []Redaction
+}
+
+// Redaction 一处被遮盖的敏感信息
+type Redaction struct {
+	Line        int
+	Kind        string
+	Placeholder string
+}
+
+// SkippedFile 过大或无法读取、未包含内容的文件
+type SkippedFile struct {
+	RelPath string
+	Size    int64
+	Reason  string
+}
+
+// Exclusion 扫描时被排除的路径、原因与命中的规则，Rule 与 Source 可为空
+type Exclusion struct {
+	RelPath string
+	IsDir   bool
+	Reason  string
+	Rule    string
+	Source  string
+}
+
+// ScanOptions 扫描选项
+type ScanOptions struct {
+	// Config 为 nil 时使用 DefaultConfig()
+	Config *Config
+	// Filter 非空时只保留返回 true 的文件，参数为相对扫描目录、以 / 分隔的路径
+	Filter func(relPath string) bool
+	// Jobs 大于 0 时覆盖配置中的并发数
+	Jobs int
+	// Cache 使用磁盘缓存，CacheDir 为空时位于用户缓存目录
+	Cache    bool
+	CacheDir string
+	// Progress 非空时报告读取进度
+	Progress Progress
+	// FS 非空时扫描其中的内容（例如 zip.Reader），dir 只用于拼接 File.Path；
+	// 忽略规则只读取 FS 中的 .gitignore，且不使用缓存
+	FS fs.FS
+	// OnSkip 非空时按路径顺序报告跳过的文件，可以传给 GenerateOptions.Skipped
+	OnSkip func(SkippedFile)
+	// OnExclude 非空时按路径顺序报告所有被排除的路径，被 Filter 排除的不报告
+	OnExclude func(Exclusion)
+}
+
+// Scan 按忽略规则扫描目录并读取文本文件，配置开启遮盖时同时遮盖其中的敏感信息
+func Scan(ctx context.Context, dir string, opts ScanOptions) ([]*File, error) {
+	cfg := orDefault(opts.Config)
+	if opts.Jobs > 0 {
+		copied := *cfg
+		copied.Jobs = opts.Jobs
+		cfg = &copied
+	}
+
+	fc, err := openCache(opts.Cache, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	scanOpts := scanner.Options{Filter: opts.Filter, Cache: fc, Context: ctx}
+	if opts.Progress != nil {
+		scanOpts.Progress = func(done, total int) {
+			opts.Progress(StageScan, done, total)
+		}
+	}
+	if opts.OnSkip != nil {
+		scanOpts.OnSkip = func(s scanner.SkippedFile) {
+			opts.OnSkip(SkippedFile(s))
+		}
+	}
+	if opts.OnExclude != nil {
+		scanOpts.OnExclude = func(e scanner.Exclusion) {
+			opts.OnExclude(Exclusion(e))
+		}
+	}
+	var infos []*scanner.FileInfo
+	if opts.FS != nil {
+		infos, err = scanner.ScanFS(opts.FS, dir, cfg, scanOpts)
+	} else {
+		infos, err = scanner.ScanDirectoryWithOptions(dir, cfg, scanOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*redact.Report
+	if cfg.Redact.Enabled {
+		redactor, err := redact.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		reports = redactor.Files(infos)
+	}
+
+	files := make([]*File, len(infos))
+	byPath := make(map[string]*File, len(infos))
+	for i, info := range infos {
+		files[i] = fromInfo(info)
+		byPath[info.RelPath] = files[i]
+	}
+	for _, report := range reports {
+		file := byPath[report.Path]
+		for _, f := range report.Findings {
+			file.Redactions = append(file.Redactions, Redaction{Line: f.Line, Kind: f.Kind, Placeholder: f.Placeholder})
+		}
+	}
+
+	return files, nil
+}
+
+func fromInfo(info *scanner.FileInfo) *File {
+	return &File{
+		Path:         info.Path,
+		RelPath:      info.RelPath,
+		Language:     info.Language,
+		Content:      info.Content,
+		IsCode:       info.IsCode,
+		LineCount:    info.LineCount,
+		Size:         info.Size,
+		Encoding:     info.Encoding,
+		ChangeStatus: info.ChangeStatus,
+		Diff:         info.Diff,
+	}
+}
+
+// toInfo 转换为内部结构；调用方可能修改过 Content，行数与换行信息重新计算
+func toInfo(file *File) *scanner.FileInfo {
+	return &scanner.FileInfo{
+		Path:         file.Path,
+		RelPath:      file.RelPath,
+		Language:     file.Language,
+		Content:      file.Content,
+		IsCode:       file.IsCode,
+		HasNewline:   strings.ContainsAny(file.Content, "\r\n"),
+		LineCount:    scanner.CountLines(file.Content),
+		Size:         file.Size,
+		Encoding:     file.Encoding,
+		ChangeStatus: file.ChangeStatus,
+		Diff:         file.Diff,
+	}
+}
@@ -0,0 +1,78 @@
+package ptlm
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"printcode2llm/internal/generator"
+	"printcode2llm/internal/output"
+)
+
+// WriteOptions 写入选项
+type WriteOptions struct {
+	// Prefix 输出文件前缀（可以包含目录），为空时使用配置中的 output_prefix
+	Prefix string
+	// Part 只写第 N 部分（从 1 开始），0 表示全部
+	Part int
+}
+
+// Write 把各结果的分段写为文件，多个结果按顺序编号，返回写入的文件名
+func Write(ctx context.Context, results []*Result, opts WriteOptions) ([]string, error) {
+	internals, cfg, err := unwrap(results)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	copied := *cfg
+	if opts.Prefix != "" {
+		copied.Output.OutputPrefix = opts.Prefix
+	}
+	if copied.Output.OutputPrefix == output.Stdout {
+		return nil, fmt.Errorf("输出前缀 %q 表示标准输出，请使用 WriteTo", output.Stdout)
+	}
+
+	if _, err := output.WriteResults(internals, &copied, output.Options{Part: opts.Part, Quiet: true}); err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, r := range internals {
+		total += len(r.Segments)
+	}
+	ext := generator.FormatExtension(copied.Output.Format)
+	var names []string
+	for partNum := 1; partNum <= total; partNum++ {
+		if opts.Part == 0 || opts.Part == partNum {
+			names = append(names, output.PartFilename(copied.Output.OutputPrefix, ext, partNum, total))
+		}
+	}
+	return names, nil
+}
+
+// WriteTo 把各结果的分段依次写到 w，分段之间用配置中的 part_delimiter 分隔，返回写入的字节数
+func WriteTo(w io.Writer, results []*Result, opts WriteOptions) (int64, error) {
+	internals, cfg, err := unwrap(results)
+	if err != nil {
+		return 0, err
+	}
+	return output.WriteResults(internals, cfg, output.Options{Part: opts.Part, Writer: w, Quiet: true})
+}
+
+// unwrap 取出内部结果；同一批结果以第一个结果的配置写入
+func unwrap(results []*Result) ([]*generator.Result, *Config, error) {
+	if len(results) == 0 {
+		return nil, nil, fmt.Errorf("没有可写入的结果")
+	}
+	internals := make([]*generator.Result, len(results))
+	for i, r := range results {
+		if r == nil || r.internal == nil {
+			return nil, nil, fmt.Errorf("第 %d 个结果不是由 Generate 生成的", i+1)
+		}
+		internals[i] = r.internal
+	}
+	return internals, results[0].cfg, nil
+}